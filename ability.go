@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// fetchAbility returns an ability, via the s.abilities cache.
+func (s *Server) fetchAbility(ctx context.Context, name string) (abilityDetail, int, error) {
+	return fetchCached(ctx, s, s.abilities, name, "/ability/"+name)
+}
+
+type abilityPokemon struct {
+	Name     string `json:"name"`
+	IsHidden bool   `json:"is_hidden"`
+}
+
+// abilityResponse is the response of GET /ability/:name.
+type abilityResponse struct {
+	Name        string           `json:"name"`
+	Effect      string           `json:"effect,omitempty"`
+	ShortEffect string           `json:"short_effect,omitempty"`
+	Pokemon     []abilityPokemon `json:"pokemon"`
+}
+
+// abilityHandler serves GET /ability/:name?lang=en: the ability's effect in
+// lang, English by default, and the pokemon that can have it.
+func (s *Server) abilityHandler(c *gin.Context) {
+	a, status, err := s.fetchAbility(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "ability not found"))
+		return
+	}
+	effect, short := a.effect(c.DefaultQuery("lang", "en"))
+	resp := abilityResponse{Name: a.Name, Effect: effect, ShortEffect: short, Pokemon: make([]abilityPokemon, len(a.Pokemon))}
+	for i, p := range a.Pokemon {
+		resp.Pokemon[i] = abilityPokemon{Name: p.Pokemon.Name, IsHidden: p.IsHidden}
+	}
+	c.JSON(http.StatusOK, resp)
+}
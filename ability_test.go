@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAbilityEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/ability/static": `{"name":"static","effect_entries":[{"effect":"Kann paralysieren.","short_effect":"Paralyse.","language":{"name":"de"}},
+			{"effect":"May paralyze on contact.","short_effect":"Paralyzes.","language":{"name":"en"}}],
+			"pokemon":[{"is_hidden":false,"pokemon":{"name":"pikachu"}},{"is_hidden":true,"pokemon":{"name":"electrike"}}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.abilities = newTTLCache[abilityDetail](time.Minute)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ability/static", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var got abilityResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := abilityResponse{Name: "static", Effect: "May paralyze on contact.", ShortEffect: "Paralyzes.",
+		Pokemon: []abilityPokemon{{Name: "pikachu"}, {Name: "electrike", IsHidden: true}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if _, ok := s.abilities.get("static"); !ok {
+		t.Fatal("expected the ability to be cached")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ability/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
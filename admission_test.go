@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAdmissionQueueAndReject(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{metrics: m, admission: newAdmissionController(1, 1, 50*time.Millisecond, m)}
+
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(requestIDMiddleware(s), admissionMiddleware(s))
+	r.GET("/slow", func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.GET("/readyz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	// Wait for the first request to hold the only slot.
+	for len(s.admission.slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Probes bypass the cap.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the readiness probe to bypass admission, got %d", w.Code)
+	}
+
+	// Second request queues, then times out.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after queue wait, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	if got := testutil.ToFloat64(m.admissionInFlight); got != 1 {
+		t.Fatalf("expected 1 request in flight, got %v", got)
+	}
+
+	close(release)
+	wg.Wait()
+	if got := testutil.ToFloat64(m.admissionInFlight); got != 0 {
+		t.Fatalf("expected no requests in flight, got %v", got)
+	}
+
+	// Slot is free again.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 once capacity frees up, got %d", w.Code)
+	}
+}
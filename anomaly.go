@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+	"sync"
+)
+
+// ewmaDetector flags a latency signal as anomalous when its z-score against
+// an exponentially weighted mean/variance stays above threshold for trigger
+// consecutive samples, and clears it after as many normal samples. Anomalous
+// samples are kept out of the baseline so an incident can't normalize itself.
+type ewmaDetector struct {
+	alpha     float64
+	threshold float64
+	warmup    int
+	trigger   int
+
+	mu       sync.Mutex
+	n        int
+	mean     float64
+	variance float64
+	streak   int
+	degraded bool
+}
+
+// observe feeds one sample and reports whether the degraded state changed.
+func (d *ewmaDetector) observe(x float64) (changed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.n++
+	if d.n == 1 {
+		d.mean = x
+		return false
+	}
+	std := math.Sqrt(d.variance)
+	anomalous := d.n > d.warmup && std > 0 && (x-d.mean)/std > d.threshold
+
+	if !anomalous {
+		diff := x - d.mean
+		d.mean += d.alpha * diff
+		d.variance = (1 - d.alpha) * (d.variance + d.alpha*diff*diff)
+	}
+
+	if anomalous == d.degraded {
+		d.streak = 0
+		return false
+	}
+	d.streak++
+	if d.streak < d.trigger {
+		return false
+	}
+	d.streak = 0
+	d.degraded = anomalous
+	return true
+}
+
+func (d *ewmaDetector) isDegraded() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.degraded
+}
+
+// latencyMonitor runs one detector per latency signal ("request", "upstream").
+type latencyMonitor struct {
+	metrics   *metrics
+	detectors map[string]*ewmaDetector
+}
+
+func newLatencyMonitor(alpha, threshold float64, warmup, trigger int, m *metrics) *latencyMonitor {
+	if trigger < 1 {
+		trigger = 1
+	}
+	lm := &latencyMonitor{metrics: m, detectors: make(map[string]*ewmaDetector)}
+	for _, signal := range []string{"request", "upstream"} {
+		lm.detectors[signal] = &ewmaDetector{alpha: alpha, threshold: threshold, warmup: warmup, trigger: trigger}
+		m.latencyDegraded.WithLabelValues(signal).Set(0)
+	}
+	return lm
+}
+
+func (lm *latencyMonitor) observe(signal string, seconds float64) {
+	if lm == nil {
+		return
+	}
+	d := lm.detectors[signal]
+	if d == nil || !d.observe(seconds) {
+		return
+	}
+	if d.isDegraded() {
+		log.Printf("latency anomaly: %s latency degraded", signal)
+		lm.metrics.latencyDegraded.WithLabelValues(signal).Set(1)
+	} else {
+		log.Printf("latency anomaly: %s latency recovered", signal)
+		lm.metrics.latencyDegraded.WithLabelValues(signal).Set(0)
+	}
+}
+
+// degradedSignals lists the signals currently flagged, sorted.
+func (lm *latencyMonitor) degradedSignals() []string {
+	if lm == nil {
+		return nil
+	}
+	var out []string
+	for signal, d := range lm.detectors {
+		if d.isDegraded() {
+			out = append(out, signal)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
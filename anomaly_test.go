@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLatencyMonitorDegradesAndRecovers(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	lm := newLatencyMonitor(0.1, 3, 10, 3, m)
+
+	for i := 0; i < 50; i++ {
+		lm.observe("upstream", 0.1+float64(i%5)*0.001)
+	}
+	if got := lm.degradedSignals(); len(got) != 0 {
+		t.Fatalf("expected healthy baseline, got %v", got)
+	}
+	for i := 0; i < 3; i++ {
+		lm.observe("upstream", 5)
+	}
+	if got := lm.degradedSignals(); len(got) != 1 || got[0] != "upstream" {
+		t.Fatalf("expected upstream degraded, got %v", got)
+	}
+	if v := testutil.ToFloat64(m.latencyDegraded.WithLabelValues("upstream")); v != 1 {
+		t.Fatalf("expected degraded gauge 1, got %v", v)
+	}
+
+	s := &Server{httpClient: &http.Client{}, cache: newPokemonCache(0), metrics: m, anomaly: lm}
+	w := httptest.NewRecorder()
+	setupRouter(s).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if body.Status != "degraded" {
+		t.Fatalf("expected degraded status, got %q", body.Status)
+	}
+
+	for i := 0; i < 3; i++ {
+		lm.observe("upstream", 0.1)
+	}
+	if got := lm.degradedSignals(); len(got) != 0 {
+		t.Fatalf("expected recovery, got %v", got)
+	}
+}
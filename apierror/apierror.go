@@ -0,0 +1,161 @@
+// Package apierror defines the stable, machine-readable error codes returned
+// in the "error.code" field of API error responses. Codes are part of the
+// public contract: they are never renamed or reused for a different meaning.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is a stable error code.
+type Code string
+
+// Error codes returned by the API.
+const (
+	CodeBadRequest          Code = "bad_request"
+	CodeNotFound            Code = "not_found"
+	CodeUpstreamError       Code = "upstream_error"
+	CodeRateLimited         Code = "rate_limited"
+	CodeOverloaded          Code = "overloaded"
+	CodeInternal            Code = "internal_error"
+	CodeDeadlineExceeded    Code = "deadline_exceeded"
+	CodeUpstreamUnavailable Code = "upstream_unavailable"
+	CodeUpstreamRateLimited Code = "upstream_rate_limited"
+	CodeTooBusy             Code = "too_busy"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeForbidden           Code = "forbidden"
+)
+
+// Entry documents one error code.
+type Entry struct {
+	Code        Code   `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+var catalog = []Entry{
+	{CodeBadRequest, http.StatusBadRequest, "The request is malformed or a parameter is invalid."},
+	{CodeNotFound, http.StatusNotFound, "The requested resource does not exist."},
+	{CodeUpstreamError, http.StatusBadGateway, "PokeAPI failed or returned an unexpected response."},
+	{CodeRateLimited, http.StatusTooManyRequests, "The client exceeded its rate limit; see Retry-After."},
+	{CodeOverloaded, http.StatusServiceUnavailable, "The server is at capacity; see Retry-After."},
+	{CodeInternal, http.StatusInternalServerError, "An unexpected server error occurred."},
+	{CodeDeadlineExceeded, http.StatusGatewayTimeout, "The caller's request deadline expired before work could start."},
+	{CodeUpstreamUnavailable, http.StatusServiceUnavailable, "PokeAPI calls are temporarily suspended: the upstream is failing or our call quota is used up."},
+	{CodeUpstreamRateLimited, http.StatusServiceUnavailable, "PokeAPI is rate-limiting this service; retry later."},
+	{CodeTooBusy, http.StatusServiceUnavailable, "Too many PokeAPI calls are already in flight; retry later."},
+	{CodeUnauthorized, http.StatusUnauthorized, "The request lacks a valid, unrevoked API key."},
+	{CodeForbidden, http.StatusForbidden, "The API key is not allowed to perform this request."},
+}
+
+// Catalog returns every error code with its HTTP status and description.
+func Catalog() []Entry {
+	return append([]Entry(nil), catalog...)
+}
+
+// Status returns the HTTP status for c, or 500 for unknown codes.
+func (c Code) Status() int {
+	for _, e := range catalog {
+		if e.Code == c {
+			return e.Status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an API error: a stable code, its HTTP status and a human-readable
+// message.
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+
+	// Upstream carries the upstream's own error, when it reported one.
+	Upstream *UpstreamDetail
+
+	// IncidentID identifies a server fault in the logs.
+	IncidentID string
+}
+
+// UpstreamDetail is a sanitized error reported by PokeAPI: its HTTP status
+// and message. It is an error so it can be wrapped into upstream failures.
+type UpstreamDetail struct {
+	Status  int
+	Message string
+}
+
+func (d *UpstreamDetail) Error() string {
+	return d.Message
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// New returns an error with code's catalog status.
+func New(code Code, msg string) *Error {
+	return &Error{Code: code, Status: code.Status(), Message: msg}
+}
+
+// BadRequest returns a bad_request error.
+func BadRequest(msg string) *Error { return New(CodeBadRequest, msg) }
+
+// NotFound returns a not_found error.
+func NotFound(msg string) *Error { return New(CodeNotFound, msg) }
+
+// UpstreamError returns an upstream_error error.
+func UpstreamError(msg string) *Error { return New(CodeUpstreamError, msg) }
+
+// RateLimited returns a rate_limited error.
+func RateLimited(msg string) *Error { return New(CodeRateLimited, msg) }
+
+// Overloaded returns an overloaded error.
+func Overloaded(msg string) *Error { return New(CodeOverloaded, msg) }
+
+// Internal returns an internal_error error.
+func Internal(msg string) *Error { return New(CodeInternal, msg) }
+
+// DeadlineExceeded returns a deadline_exceeded error.
+func DeadlineExceeded(msg string) *Error { return New(CodeDeadlineExceeded, msg) }
+
+// UpstreamUnavailable returns an upstream_unavailable error.
+func UpstreamUnavailable(msg string) *Error { return New(CodeUpstreamUnavailable, msg) }
+
+// UpstreamRateLimited returns an upstream_rate_limited error.
+func UpstreamRateLimited(msg string) *Error { return New(CodeUpstreamRateLimited, msg) }
+
+// TooBusy returns a too_busy error.
+func TooBusy(msg string) *Error { return New(CodeTooBusy, msg) }
+
+// Unauthorized returns an unauthorized error.
+func Unauthorized(msg string) *Error { return New(CodeUnauthorized, msg) }
+
+// Forbidden returns a forbidden error.
+func Forbidden(msg string) *Error { return New(CodeForbidden, msg) }
+
+// FromUpstream maps a normalized upstream status to an API error: 404 becomes
+// not_found with notFoundMsg, 429 upstream_rate_limited, 503
+// upstream_unavailable, anything else upstream_error carrying err. A copy of
+// an Error wrapped in err is returned as is; an UpstreamDetail wrapped in err
+// is attached to the result.
+func FromUpstream(status int, err error, notFoundMsg string) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		cp := *e
+		return &cp
+	}
+	switch status {
+	case http.StatusNotFound:
+		e = NotFound(notFoundMsg)
+	case http.StatusTooManyRequests:
+		e = UpstreamRateLimited(err.Error())
+	case http.StatusServiceUnavailable:
+		e = UpstreamUnavailable(err.Error())
+	default:
+		e = UpstreamError(err.Error())
+	}
+	errors.As(err, &e.Upstream)
+	return e
+}
@@ -0,0 +1,47 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCatalogStatuses(t *testing.T) {
+	seen := map[Code]bool{}
+	for _, e := range Catalog() {
+		if seen[e.Code] {
+			t.Fatalf("duplicate code %q", e.Code)
+		}
+		seen[e.Code] = true
+		if e.Code.Status() != e.Status || e.Description == "" {
+			t.Fatalf("inconsistent entry %+v", e)
+		}
+	}
+	if Code("nope").Status() != http.StatusInternalServerError {
+		t.Fatal("unknown codes should map to 500")
+	}
+}
+
+func TestFromUpstream(t *testing.T) {
+	if e := FromUpstream(http.StatusNotFound, errors.New("x"), "pokemon not found"); e.Code != CodeNotFound || e.Message != "pokemon not found" {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	if e := FromUpstream(http.StatusBadGateway, errors.New("boom"), ""); e.Code != CodeUpstreamError || e.Status != http.StatusBadGateway || e.Message != "boom" {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	if e := FromUpstream(http.StatusServiceUnavailable, errors.New("open"), ""); e.Code != CodeUpstreamUnavailable || e.Status != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	if e := FromUpstream(http.StatusTooManyRequests, errors.New("slow down"), ""); e.Code != CodeUpstreamRateLimited || e.Status != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	busy := TooBusy("wait")
+	if e := FromUpstream(http.StatusServiceUnavailable, fmt.Errorf("bulkhead: %w", busy), ""); e.Code != CodeTooBusy || e.Message != "wait" || e == busy {
+		t.Fatalf("expected a copy of the wrapped error, got %+v", e)
+	}
+	detail := &UpstreamDetail{Status: http.StatusBadRequest, Message: "invalid id"}
+	if e := FromUpstream(http.StatusBadGateway, fmt.Errorf("upstream returned status 400: %w", detail), ""); e.Upstream != detail {
+		t.Fatalf("expected upstream detail to be attached: %+v", e)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 50
+)
+
+// autocompleteHandler serves GET /autocomplete?q=&limit= with prefix matches
+// from the in-memory name tree. Only the first call after a name index
+// refresh touches the upstream.
+func (s *Server) autocompleteHandler(c *gin.Context) {
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	if q == "" {
+		writeError(c, apierror.BadRequest("q is required"))
+		return
+	}
+	limit := defaultAutocompleteLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(c, apierror.BadRequest("limit must be a positive integer"))
+			return
+		}
+		limit = min(n, maxAutocompleteLimit)
+	}
+
+	tree, status, err := s.nameTree(c.Request.Context())
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon list not found"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"query": q, "results": tree.withPrefix(q, limit)})
+}
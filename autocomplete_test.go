@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAutocompleteEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon?limit=100000&offset=0": `{"count":4,"results":[{"name":"pikachu"},{"name":"pichu"},{"name":"raichu"},{"name":"pidgey"}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.names = newNameIndex(time.Hour)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/autocomplete?q=PI&limit=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var body struct {
+		Results []string `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if !reflect.DeepEqual(body.Results, []string{"pichu", "pidgey"}) {
+		t.Fatalf("unexpected results: %v", body.Results)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/autocomplete", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without q, got %d", w.Code)
+	}
+}
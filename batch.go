@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+const (
+	// maxBatchNames bounds how many pokemon one batch request may ask for.
+	maxBatchNames = 50
+	// batchWorkers bounds the concurrent fetches of one batch request.
+	batchWorkers = 8
+)
+
+// batchResponse is the document served by POST /pokemon/batch. Results are
+// in request order with duplicates removed; names that could not be
+// fetched are left out of Results and listed in Errors.
+type batchResponse struct {
+	Results []pokemonResponse    `json:"results"`
+	Errors  map[string]partError `json:"errors,omitempty"`
+}
+
+// batchNames validates a batch body and returns its names, trimmed and
+// deduplicated in first-seen order.
+func batchNames(names []string) ([]string, *apierror.Error) {
+	if len(names) == 0 {
+		return nil, apierror.BadRequest("body must be a non-empty JSON array of names")
+	}
+	seen := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			return nil, apierror.BadRequest("names must not be empty")
+		}
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	if len(out) > maxBatchNames {
+		return nil, apierror.BadRequest("at most 50 names per batch")
+	}
+	return out, nil
+}
+
+// batchHandler serves POST /pokemon/batch: a JSON array of names fetched
+// through the pokemon cache by at most batchWorkers goroutines. Cached names
+// cost no upstream call and concurrent misses for one name share a fetch.
+// When the client disconnects, fetches in flight are aborted and no more
+// are started.
+func (s *Server) batchHandler(c *gin.Context) {
+	var body []string
+	if err := c.ShouldBindJSON(&body); err != nil {
+		writeError(c, apierror.BadRequest("body must be a non-empty JSON array of names"))
+		return
+	}
+	names, err := batchNames(body)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	type fetched struct {
+		p      pokemonResponse
+		status int
+		err    error
+	}
+	results := make([]fetched, len(names))
+	sem := make(chan struct{}, batchWorkers)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		if !acquireSlot(ctx, sem) {
+			for j := i; j < len(names); j++ {
+				results[j] = fetched{status: http.StatusGatewayTimeout, err: ctx.Err()}
+			}
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			p, status, err := s.getPokemon(ctx, name)
+			results[i] = fetched{p: p, status: status, err: err}
+		}()
+	}
+	wg.Wait()
+	if clientGone(ctx) {
+		return // nobody left to answer
+	}
+
+	resp := batchResponse{Results: []pokemonResponse{}, Errors: map[string]partError{}}
+	for i, r := range results {
+		if r.err != nil {
+			resp.Errors[names[i]] = newPartError(r.status, r.err)
+			continue
+		}
+		resp.Results = append(resp.Results, r.p)
+	}
+	c.JSON(http.StatusOK, resp)
+}
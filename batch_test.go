@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchNames(t *testing.T) {
+	names, err := batchNames([]string{"pikachu", " raichu ", "pikachu"})
+	if err != nil || strings.Join(names, ",") != "pikachu,raichu" {
+		t.Fatalf("expected deduplicated names, got %v %v", names, err)
+	}
+	tooMany := make([]string, maxBatchNames+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("a", i+1)
+	}
+	for _, in := range [][]string{nil, {"pikachu", " "}, tooMany} {
+		if _, err := batchNames(in); err == nil {
+			t.Errorf("expected %d names %q to be rejected", len(in), in)
+		}
+	}
+}
+
+func TestBatchEndpoint(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/pokemon/pikachu":
+			w.Write([]byte(`{"name":"pikachu","height":4,"weight":60,"base_experience":112}`))
+		case "/pokemon/raichu":
+			w.Write([]byte(`{"name":"raichu","height":8,"weight":300,"base_experience":218}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pokemon/batch", strings.NewReader(`["raichu","missingno","pikachu","raichu"]`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp batchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Name != "raichu" || resp.Results[1].Name != "pikachu" {
+		t.Fatalf("expected raichu and pikachu in request order, got %+v", resp.Results)
+	}
+	if e, ok := resp.Errors["missingno"]; !ok || e.Code != "not_found" {
+		t.Fatalf("expected a not_found error for missingno, got %+v", resp.Errors)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("expected one upstream call per distinct name, got %d", n)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pokemon/batch", strings.NewReader(`{"names":["pikachu"]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a non-array body, got %d", w.Code)
+	}
+}
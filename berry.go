@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// berryDetail is the subset of the upstream berry payload we use.
+type berryDetail struct {
+	Name             string        `json:"name"`
+	GrowthTime       int           `json:"growth_time"`
+	MaxHarvest       int           `json:"max_harvest"`
+	Size             int           `json:"size"`
+	Smoothness       int           `json:"smoothness"`
+	SoilDryness      int           `json:"soil_dryness"`
+	NaturalGiftPower int           `json:"natural_gift_power"`
+	NaturalGiftType  namedResource `json:"natural_gift_type"`
+	Firmness         namedResource `json:"firmness"`
+	Item             namedResource `json:"item"`
+	Flavors          []struct {
+		Potency int           `json:"potency"`
+		Flavor  namedResource `json:"flavor"`
+	} `json:"flavors"`
+}
+
+// berryResponse is the response of GET /berry/:name. Flavors maps each
+// flavor to its potency.
+type berryResponse struct {
+	Name             string         `json:"name"`
+	Firmness         string         `json:"firmness"`
+	GrowthTime       int            `json:"growth_time"`
+	MaxHarvest       int            `json:"max_harvest"`
+	Size             int            `json:"size"`
+	Smoothness       int            `json:"smoothness"`
+	SoilDryness      int            `json:"soil_dryness"`
+	Flavors          map[string]int `json:"flavors"`
+	NaturalGiftType  string         `json:"natural_gift_type"`
+	NaturalGiftPower int            `json:"natural_gift_power"`
+	Item             string         `json:"item"`
+}
+
+// fetchBerry returns a berry, via the s.berries cache.
+func (s *Server) fetchBerry(ctx context.Context, name string) (berryDetail, int, error) {
+	return fetchCached(ctx, s, s.berries, name, "/berry/"+name)
+}
+
+// berryHandler serves GET /berry/:name.
+func (s *Server) berryHandler(c *gin.Context) {
+	b, status, err := s.fetchBerry(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "berry not found"))
+		return
+	}
+	resp := berryResponse{
+		Name: b.Name, Firmness: b.Firmness.Name, GrowthTime: b.GrowthTime, MaxHarvest: b.MaxHarvest,
+		Size: b.Size, Smoothness: b.Smoothness, SoilDryness: b.SoilDryness, Flavors: make(map[string]int, len(b.Flavors)),
+		NaturalGiftType: b.NaturalGiftType.Name, NaturalGiftPower: b.NaturalGiftPower, Item: b.Item.Name,
+	}
+	for _, f := range b.Flavors {
+		resp.Flavors[f.Flavor.Name] = f.Potency
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBerryEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/berry/cheri": `{"name":"cheri","growth_time":3,"max_harvest":5,"size":20,"smoothness":25,"soil_dryness":15,
+			"natural_gift_power":60,"natural_gift_type":{"name":"fire"},"firmness":{"name":"soft"},"item":{"name":"cheri-berry"},
+			"flavors":[{"potency":10,"flavor":{"name":"spicy"}},{"potency":0,"flavor":{"name":"dry"}}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.berries = newTTLCache[berryDetail](time.Minute)
+	r := setupRouter(s)
+
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"cheri","firmness":"soft","growth_time":3,"max_harvest":5,"size":20,"smoothness":25,"soil_dryness":15,` +
+		`"flavors":{"dry":0,"spicy":10},"natural_gift_type":"fire","natural_gift_power":60,"item":"cheri-berry"}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/berry/cheri", nil))
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Fatalf("expected 200 %s, got %d %s", want, w.Code, w.Body)
+	}
+	if errs := rs.validate(rs.schemaFor("/berry/:name", http.StatusOK), w.Body.Bytes()); len(errs) > 0 {
+		t.Fatalf("response violates its schema: %v", errs)
+	}
+	if _, ok := s.berries.get("cheri"); !ok {
+		t.Fatal("expected the berry to be cached")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/berry/bluk-ish", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+	if errs := rs.validate(rs.schemaFor("/berry/:name", http.StatusNotFound), w.Body.Bytes()); len(errs) > 0 {
+		t.Fatalf("expected the unified error format: %v", errs)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states, as exported by the upstream_circuit_state gauge.
+const (
+	breakerClosed   = 0
+	breakerOpen     = 1
+	breakerHalfOpen = 2
+)
+
+// errCircuitOpen is returned for upstream calls refused by an open breaker.
+var errCircuitOpen = errors.New("upstream circuit breaker is open")
+
+// circuitBreaker fails upstream calls fast once PokeAPI looks unhealthy.
+// Outcomes are tracked over a sliding window of recent calls; when the error
+// rate reaches maxErrorRate the breaker opens for openFor, then lets a single
+// probe through (half-open). A successful probe closes it, a failed one opens
+// it again.
+type circuitBreaker struct {
+	maxErrorRate float64
+	minRequests  int
+	openFor      time.Duration
+	metrics      *metrics
+
+	mu       sync.Mutex
+	state    int
+	window   []bool // true = error
+	next     int
+	filled   int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker returns nil (no breaker) when maxErrorRate is not positive.
+func newCircuitBreaker(maxErrorRate float64, minRequests int, openFor time.Duration, m *metrics) *circuitBreaker {
+	if maxErrorRate <= 0 {
+		return nil
+	}
+	if minRequests <= 0 {
+		minRequests = 1
+	}
+	return &circuitBreaker{
+		maxErrorRate: maxErrorRate,
+		minRequests:  minRequests,
+		openFor:      openFor,
+		metrics:      m,
+		window:       make([]bool, minRequests),
+	}
+}
+
+// allow reports whether an upstream call may proceed.
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.openFor {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record feeds the outcome of an allowed call into the breaker.
+func (b *circuitBreaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.open()
+			return
+		}
+		b.next, b.filled = 0, 0
+		b.setState(breakerClosed)
+		log.Printf("circuit breaker: closed after successful probe")
+		return
+	case breakerOpen:
+		return
+	}
+	b.window[b.next] = failed
+	b.next = (b.next + 1) % len(b.window)
+	if b.filled < len(b.window) {
+		b.filled++
+	}
+	if b.filled < b.minRequests {
+		return
+	}
+	errs := 0
+	for _, e := range b.window[:b.filled] {
+		if e {
+			errs++
+		}
+	}
+	if rate := float64(errs) / float64(b.filled); rate >= b.maxErrorRate {
+		log.Printf("circuit breaker: opened at error rate %.2f over %d requests", rate, b.filled)
+		b.open()
+	}
+}
+
+// release gives back the half-open probe slot of an allowed call that was
+// not made after all, or whose outcome says nothing about the upstream, so
+// the next call can probe.
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.probing = false
+	}
+}
+
+// open trips the breaker; b.mu must be held.
+func (b *circuitBreaker) open() {
+	b.openedAt = time.Now()
+	b.setState(breakerOpen)
+}
+
+// setState updates the state and its gauge; b.mu must be held.
+func (b *circuitBreaker) setState(state int) {
+	b.state = state
+	b.metrics.upstreamCircuitState.Set(float64(state))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	b := newCircuitBreaker(0.5, 4, 20*time.Millisecond, m)
+	for _, failed := range []bool{false, true, false, true} {
+		if !b.allow() {
+			t.Fatal("expected closed breaker to allow calls")
+		}
+		b.record(failed)
+	}
+	if b.allow() {
+		t.Fatal("expected breaker to open at 50% errors")
+	}
+	if v := testutil.ToFloat64(m.upstreamCircuitState); v != breakerOpen {
+		t.Fatalf("expected open state gauge, got %v", v)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("expected a half-open probe")
+	}
+	if b.allow() {
+		t.Fatal("expected only one probe in flight")
+	}
+	b.record(true)
+	if b.allow() {
+		t.Fatal("expected failed probe to reopen the breaker")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("expected a second probe")
+	}
+	b.record(false)
+	if !b.allow() || testutil.ToFloat64(m.upstreamCircuitState) != breakerClosed {
+		t.Fatal("expected successful probe to close the breaker")
+	}
+	if newCircuitBreaker(0, 1, time.Second, m) != nil {
+		t.Fatal("expected disabled breaker for zero error rate")
+	}
+}
+
+// openBreaker returns a breaker that is open and lets the next call probe.
+func openBreaker(m *metrics) *circuitBreaker {
+	b := newCircuitBreaker(0.5, 1, 0, m)
+	b.allow()
+	b.record(true)
+	return b
+}
+
+func TestQuotaRefusalReleasesProbe(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{httpClient: &http.Client{}, metrics: m, breaker: openBreaker(m), quota: newUpstreamQuota(1, 0, 1, m)}
+	s.quota.allow()
+
+	var out pokemonResponse
+	if status, err := s.fetchUpstream(context.Background(), "/pokemon/pikachu", &out); status != http.StatusServiceUnavailable || err != errQuotaExhausted {
+		t.Fatalf("expected a quota refusal, got %d %v", status, err)
+	}
+	if !s.breaker.allow() {
+		t.Fatal("expected the refused call to give back the probe slot")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// routeBudget is the performance budget of one route. Zero fields are not
+// checked.
+type routeBudget struct {
+	Route       string
+	MaxDuration time.Duration
+	MaxRequest  int64 // request body bytes, per Content-Length or as read
+	MaxResponse int   // response body bytes
+}
+
+// parseRouteBudgets parses a comma-separated list of route=options items,
+// where options are ;-separated duration= (a Go duration), request_bytes= and
+// response_bytes=, e.g. "/pokemon/:name=duration=200ms;response_bytes=4096".
+// Invalid items are logged and skipped.
+func parseRouteBudgets(v string) map[string]routeBudget {
+	budgets := map[string]routeBudget{}
+items:
+	for _, item := range splitList(v) {
+		route, opts, ok := strings.Cut(item, "=")
+		if !ok || route == "" {
+			log.Printf("route budget: ignoring invalid entry %q", item)
+			continue
+		}
+		b := routeBudget{Route: route}
+		for _, opt := range strings.Split(opts, ";") {
+			key, val, _ := strings.Cut(strings.TrimSpace(opt), "=")
+			var err error
+			switch key {
+			case "duration":
+				b.MaxDuration, err = time.ParseDuration(val)
+			case "request_bytes":
+				b.MaxRequest, err = strconv.ParseInt(val, 10, 64)
+			case "response_bytes":
+				b.MaxResponse, err = strconv.Atoi(val)
+			default:
+				err = fmt.Errorf("unknown budget %q", key)
+			}
+			if err != nil || b.MaxDuration < 0 || b.MaxRequest < 0 || b.MaxResponse < 0 {
+				log.Printf("route budget: ignoring invalid entry %q", item)
+				continue items
+			}
+		}
+		budgets[route] = b
+	}
+	return budgets
+}
+
+// countingBody counts the request body bytes read through it.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// budgetViolation records that a request exceeded one budget of its route.
+func (s *Server) budgetViolation(c *gin.Context, budget string, limit, actual any) {
+	rid, _ := c.Get("request_id")
+	log.Printf("budget violation: rid=%v route=%s budget=%s limit=%v actual=%v enforced=%t",
+		rid, c.FullPath(), budget, limit, actual, s.enforceBudgets)
+	s.metrics.budgetViolationsTotal.WithLabelValues(c.FullPath(), budget).Inc()
+}
+
+// middleware: check requests against their route's budget. Violations are
+// logged and counted in route_budget_violations_total; with enforcement on
+// (meant for staging) they also fail the request: an oversized request body
+// with 400 before the handler runs, a slow or oversized response with 500.
+// Responses are buffered only when enforcing.
+func routeBudgetMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, ok := s.budgets[c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+		var body *countingBody
+		if b.MaxRequest > 0 {
+			if c.Request.ContentLength > b.MaxRequest {
+				s.budgetViolation(c, "request_bytes", b.MaxRequest, c.Request.ContentLength)
+				if s.enforceBudgets {
+					writeError(c, apierror.BadRequest("request body exceeds the route's budget"))
+					c.Abort()
+					return
+				}
+			}
+			// bodies without a Content-Length (chunked) are measured as
+			// they are read, and cut off when enforcing
+			body = &countingBody{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+			if s.enforceBudgets {
+				c.Request.Body = http.MaxBytesReader(c.Writer, body, b.MaxRequest)
+			}
+		}
+
+		start := time.Now()
+		orig := c.Writer
+		var bw *bufferedWriter
+		if s.enforceBudgets {
+			bw = newBufferedWriter(orig)
+			c.Writer = bw
+		}
+		c.Next()
+		c.Writer = orig
+		elapsed := time.Since(start)
+
+		if body != nil && c.Request.ContentLength < 0 && body.n > b.MaxRequest {
+			s.budgetViolation(c, "request_bytes", b.MaxRequest, body.n)
+		}
+		var failed []string
+		if b.MaxDuration > 0 && elapsed > b.MaxDuration {
+			s.budgetViolation(c, "duration", b.MaxDuration, elapsed.Round(time.Microsecond))
+			failed = append(failed, "duration")
+		}
+		size := orig.Size()
+		if bw != nil {
+			size = bw.Size()
+		}
+		if b.MaxResponse > 0 && size > b.MaxResponse {
+			s.budgetViolation(c, "response_bytes", b.MaxResponse, size)
+			failed = append(failed, "response_bytes")
+		}
+		if bw == nil {
+			return
+		}
+		if len(failed) == 0 {
+			bw.flushTo(bw.bytes())
+			return
+		}
+		bw.discardBodyHeaders()
+		writeError(c, apierror.Internal("response exceeded the route's "+strings.Join(failed, " and ")+" budget"))
+	}
+}
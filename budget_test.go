@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseRouteBudgets(t *testing.T) {
+	got := parseRouteBudgets("/pokemon/:name=duration=200ms;response_bytes=4096, /pokemon/batch=request_bytes=1024, /bad=speed=1, /neg=duration=-1s")
+	want := map[string]routeBudget{
+		"/pokemon/:name": {Route: "/pokemon/:name", MaxDuration: 200 * time.Millisecond, MaxResponse: 4096},
+		"/pokemon/batch": {Route: "/pokemon/batch", MaxRequest: 1024},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d budgets, got %+v", len(want), got)
+	}
+	for route, b := range want {
+		if got[route] != b {
+			t.Errorf("%s: expected %+v, got %+v", route, b, got[route])
+		}
+	}
+}
+
+func TestRouteBudgetMiddleware(t *testing.T) {
+	s := newTestServer("")
+	s.budgets = parseRouteBudgets("/slow=duration=1ms,/big=response_bytes=8,/upload=request_bytes=4")
+	r := gin.New()
+	r.Use(routeBudgetMiddleware(s))
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(5 * time.Millisecond)
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/big", func(c *gin.Context) { c.String(http.StatusOK, "0123456789") })
+	r.POST("/upload", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	serveChunked := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/upload", io.NopCloser(strings.NewReader(body)))
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// observing only: responses pass through, violations are counted
+	if w := serve(http.MethodGet, "/slow", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "/big", ""); w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("expected the response unchanged, got %d %q", w.Code, w.Body)
+	}
+	if w := serve(http.MethodPost, "/upload", "12345"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", w.Code)
+	}
+	for route, budget := range map[string]string{"/slow": "duration", "/big": "response_bytes", "/upload": "request_bytes"} {
+		if got := testutil.ToFloat64(s.metrics.budgetViolationsTotal.WithLabelValues(route, budget)); got != 1 {
+			t.Errorf("%s: expected 1 %s violation, got %v", route, budget, got)
+		}
+	}
+	if w := serveChunked("12345"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", w.Code)
+	}
+	if got := testutil.ToFloat64(s.metrics.budgetViolationsTotal.WithLabelValues("/upload", "request_bytes")); got != 2 {
+		t.Fatalf("expected a chunked body over budget to be counted, got %v", got)
+	}
+
+	// enforcing: violations fail the request
+	s.enforceBudgets = true
+	for _, tc := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/slow", "", http.StatusInternalServerError},
+		{http.MethodGet, "/big", "", http.StatusInternalServerError},
+		{http.MethodPost, "/upload", "12345", http.StatusBadRequest},
+		{http.MethodPost, "/upload", "1234", http.StatusNoContent},
+	} {
+		if w := serve(tc.method, tc.path, tc.body); w.Code != tc.want {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.want, w.Code)
+		}
+	}
+	if w := serveChunked("12345"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a chunked body over budget to be cut off, got %d", w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bufferedWriter captures a handler's status and body so middleware can
+// inspect or rewrite the response before anything reaches the client.
+// Headers are not buffered: they go straight to the underlying writer's map.
+// With a positive limit, bytes beyond it are dropped and overflow is set.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	status   int
+	limit    int
+	overflow bool
+	attempts int // total bytes the handler tried to write
+}
+
+func newBufferedWriter(w gin.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.attempts += len(b)
+	if w.limit > 0 && w.buf.Len()+len(b) > w.limit {
+		w.overflow = true
+		return len(b), nil
+	}
+	return w.buf.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) { return w.Write([]byte(s)) }
+func (w *bufferedWriter) WriteHeaderNow()                   {}
+func (w *bufferedWriter) Status() int                       { return w.status }
+func (w *bufferedWriter) Size() int                         { return w.buf.Len() }
+func (w *bufferedWriter) Written() bool                     { return false }
+func (w *bufferedWriter) Flush()                            {}
+func (w *bufferedWriter) bytes() []byte                     { return w.buf.Bytes() }
+
+// discardBodyHeaders drops the headers that describe the buffered body, for
+// when it is replaced by an error: the error must not be sized, downloaded
+// or cached as the original.
+func (w *bufferedWriter) discardBodyHeaders() {
+	h := w.ResponseWriter.Header()
+	for _, name := range []string{"Content-Length", "Content-Disposition", "Cache-Control", "Expires", "ETag"} {
+		h.Del(name)
+	}
+}
+
+// flushTo writes the buffered status and body (or a replacement body) to the
+// underlying writer.
+func (w *bufferedWriter) flushTo(body []byte) {
+	h := w.ResponseWriter.Header()
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ci_education/apierror"
+)
+
+// errUpstreamBusy is returned for upstream calls that found every bulkhead
+// slot taken for longer than the allowed wait.
+var errUpstreamBusy = fmt.Errorf("upstream bulkhead: %w", apierror.TooBusy("too many concurrent upstream calls, retry later"))
+
+// upstreamBulkhead caps simultaneous in-flight PokeAPI calls so a traffic
+// spike cannot exhaust sockets. A call waits up to maxWait for a slot.
+type upstreamBulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+	metrics *metrics
+}
+
+// newUpstreamBulkhead returns nil (no cap) when maxInFlight is not positive.
+func newUpstreamBulkhead(maxInFlight int, maxWait time.Duration, m *metrics) *upstreamBulkhead {
+	if maxInFlight <= 0 {
+		return nil
+	}
+	return &upstreamBulkhead{slots: make(chan struct{}, maxInFlight), maxWait: maxWait, metrics: m}
+}
+
+// acquire obtains a slot, waiting at most maxWait or until ctx is done. It
+// returns false if the call should be refused.
+func (b *upstreamBulkhead) acquire(ctx context.Context) bool {
+	if b == nil {
+		return true
+	}
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		timer := time.NewTimer(b.maxWait)
+		defer timer.Stop()
+		select {
+		case b.slots <- struct{}{}:
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+	b.metrics.upstreamInFlight.Set(float64(len(b.slots)))
+	return true
+}
+
+func (b *upstreamBulkhead) release() {
+	if b == nil {
+		return
+	}
+	<-b.slots
+	b.metrics.upstreamInFlight.Set(float64(len(b.slots)))
+}
+
+// saturation returns the fraction of slots in use, 0 without a cap.
+func (b *upstreamBulkhead) saturation() float64 {
+	if b == nil {
+		return 0
+	}
+	return float64(len(b.slots)) / float64(cap(b.slots))
+}
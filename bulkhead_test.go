@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"ci_education/apierror"
+)
+
+func TestUpstreamBulkhead(t *testing.T) {
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-unblock
+		fmt.Fprint(w, `{"name":"pikachu"}`)
+	}))
+	defer ts.Close()
+
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{httpClient: ts.Client(), baseURL: ts.URL, metrics: m,
+		bulkhead: newUpstreamBulkhead(1, 20*time.Millisecond, m)}
+
+	done := make(chan error)
+	go func() {
+		var out pokemonResponse
+		_, err := s.fetchUpstream(context.Background(), "/pokemon/pikachu", &out)
+		done <- err
+	}()
+	<-entered
+
+	var out pokemonResponse
+	status, err := s.fetchUpstream(context.Background(), "/pokemon/pikachu", &out)
+	if status != http.StatusServiceUnavailable || err != errUpstreamBusy {
+		t.Fatalf("expected a busy refusal, got %d %v", status, err)
+	}
+	if e := apierror.FromUpstream(status, err, ""); e.Code != apierror.CodeTooBusy {
+		t.Fatalf("expected too_busy, got %+v", e)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	go func() { <-entered }()
+	if _, err := s.fetchUpstream(context.Background(), "/pokemon/pikachu", &out); err != nil {
+		t.Fatalf("expected the released slot to be reusable, got %v", err)
+	}
+}
+
+func TestBulkheadRefusalReleasesProbe(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{httpClient: &http.Client{}, metrics: m, breaker: openBreaker(m),
+		bulkhead: newUpstreamBulkhead(1, time.Millisecond, m)}
+	s.bulkhead.acquire(context.Background())
+
+	var out pokemonResponse
+	if status, err := s.fetchUpstream(context.Background(), "/pokemon/pikachu", &out); status != http.StatusServiceUnavailable || err != errUpstreamBusy {
+		t.Fatalf("expected a busy refusal, got %d %v", status, err)
+	}
+	if !s.breaker.allow() {
+		t.Fatal("expected the refused call to give back the probe slot")
+	}
+}
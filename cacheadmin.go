@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// adminCache is what the admin cache endpoints need from a cache.
+type adminCache interface {
+	delete(key string) bool
+	flush() int
+}
+
+// adminCaches returns every cache of s. The admin flush and evict endpoints
+// iterate it, so a cache missing here would survive a flush;
+// TestAdminCachesCoverServer fails for any cache of Server not listed.
+func (s *Server) adminCaches() []adminCache {
+	caches := []adminCache{
+		s.cache, s.details, s.types, s.species, s.lists, s.abilities, s.moves,
+		s.items, s.berries, s.encounters, s.chains, s.generations, s.sprites,
+	}
+	if s.proxy != nil {
+		caches = append(caches, s.proxy.cache)
+	}
+	return caches
+}
+
+// adminEvictCacheHandler drops one key from every cache so the next request
+// for it refetches from upstream. The key is normalized like pokemon names;
+// an entry stored under the name as given is dropped too.
+func (s *Server) adminEvictCacheHandler(c *gin.Context) {
+	name := c.Param("name")
+	keys := normalizeNames([]string{name})
+	if len(keys) == 0 {
+		writeError(c, apierror.BadRequest("name is required"))
+		return
+	}
+	if name != keys[0] {
+		keys = append(keys, name)
+	}
+	evicted := false
+	for _, cache := range s.adminCaches() {
+		for _, key := range keys {
+			if cache.delete(key) {
+				evicted = true
+			}
+		}
+	}
+	if !evicted {
+		writeError(c, apierror.NotFound("pokemon is not cached"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"evicted": keys[0]})
+}
+
+// adminFlushCacheHandler empties every cache.
+func (s *Server) adminFlushCacheHandler(c *gin.Context) {
+	n := 0
+	for _, cache := range s.adminCaches() {
+		n += cache.flush()
+	}
+	c.JSON(http.StatusOK, gin.H{"flushed": n})
+}
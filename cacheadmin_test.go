@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestAdminCacheInvalidation(t *testing.T) {
+	s := &Server{cache: newLRUCache[pokemonCacheEntry](time.Minute, 10), details: newTTLCache[pokemonDetail](time.Minute), metrics: newMetrics(prometheus.NewRegistry())}
+	s.cache.set("pikachu", pokemonCacheEntry{pokemon: pokemonResponse{Name: "pikachu"}})
+	s.cache.set("eevee", pokemonCacheEntry{pokemon: pokemonResponse{Name: "eevee"}})
+	s.details.set("eevee", pokemonDetail{})
+	s.types = newTTLCache[typeDetail](time.Minute)
+	s.types.set("fire", typeDetail{})
+	r := setupRouter(withAdminKey(t, s))
+
+	do := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodDelete, path, nil)))
+		return w.Code
+	}
+
+	if code := do("/admin/cache/Pikachu"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if _, ok := s.cache.get("pikachu"); ok {
+		t.Fatal("expected pikachu to be evicted")
+	}
+	if code := do("/admin/cache/pikachu"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for uncached name, got %d", code)
+	}
+	if code := do("/admin/cache"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if s.cache.len() != 0 || s.details.len() != 0 || s.types.len() != 0 {
+		t.Fatalf("expected empty caches, got %d, %d and %d", s.cache.len(), s.details.len(), s.types.len())
+	}
+}
+
+// TestAdminCachesCoverServer fails when a cache of Server, directly or one
+// level down (sprites, proxy), is missing from adminCaches.
+func TestAdminCachesCoverServer(t *testing.T) {
+	t.Setenv("PROXY_PREFIXES", "/berry")
+	s := newServerFromEnv()
+	registered := map[uintptr]bool{}
+	for _, c := range s.adminCaches() {
+		registered[reflect.ValueOf(c).Pointer()] = true
+	}
+	cacheType := reflect.TypeOf((*adminCache)(nil)).Elem()
+	isCache := func(f reflect.StructField) bool {
+		return f.Type.Kind() == reflect.Pointer && strings.HasPrefix(f.Type.Elem().Name(), "ttlCache[")
+	}
+
+	v := reflect.ValueOf(s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		if f.Type.Kind() != reflect.Pointer || fv.IsNil() {
+			continue
+		}
+		if isCache(f) || f.Type.Implements(cacheType) {
+			if !registered[fv.Pointer()] {
+				t.Errorf("Server.%s is not in adminCaches", f.Name)
+			}
+			continue
+		}
+		if f.Type.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		inner := fv.Elem()
+		for j := 0; j < inner.NumField(); j++ {
+			g, gv := inner.Type().Field(j), inner.Field(j)
+			if isCache(g) && !gv.IsNil() && !registered[gv.Pointer()] {
+				t.Errorf("Server.%s.%s is not in adminCaches", f.Name, g.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachePolicy controls how a route's responses are cached. A zero TTL uses
+// the cache's own TTL; a zero NegativeTTL disables caching of upstream 404s.
+// Within StaleWhileRevalidate after expiry an entry is still served while it
+// is refreshed in the background. With AllowBypass, a request carrying
+// "Cache-Control: no-cache" skips the cache lookup (the fresh result is still
+// stored).
+type cachePolicy struct {
+	Route                string
+	TTL                  time.Duration
+	NegativeTTL          time.Duration
+	StaleWhileRevalidate time.Duration
+	AllowBypass          bool
+}
+
+func (p cachePolicy) matches(route string) bool {
+	if prefix, ok := strings.CutSuffix(p.Route, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	return route == p.Route
+}
+
+// parseCachePolicies parses a comma-separated list of route=options items,
+// where options are ;-separated ttl=, negative_ttl=, swr= durations and the
+// bypass flag, e.g. "/pokemon/:name=ttl=10m;negative_ttl=1m;swr=30s;bypass".
+// Options not given fall back to defaults. Invalid items are logged and
+// skipped.
+func parseCachePolicies(v string, defaults cachePolicy) []cachePolicy {
+	var policies []cachePolicy
+items:
+	for _, item := range splitList(v) {
+		route, opts, ok := strings.Cut(item, "=")
+		if !ok || route == "" {
+			log.Printf("cache policy: ignoring invalid entry %q", item)
+			continue
+		}
+		p := defaults
+		p.Route = route
+		for _, opt := range strings.Split(opts, ";") {
+			key, val, _ := strings.Cut(strings.TrimSpace(opt), "=")
+			if key == "bypass" {
+				p.AllowBypass = true
+				continue
+			}
+			d, err := time.ParseDuration(val)
+			if err != nil || d < 0 {
+				log.Printf("cache policy: ignoring invalid entry %q", item)
+				continue items
+			}
+			switch key {
+			case "ttl":
+				p.TTL = d
+			case "negative_ttl":
+				p.NegativeTTL = d
+			case "swr":
+				p.StaleWhileRevalidate = d
+			default:
+				log.Printf("cache policy: ignoring invalid entry %q", item)
+				continue items
+			}
+		}
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// cachePolicyFor returns the first policy matching route, or the default.
+func (s *Server) cachePolicyFor(route string) cachePolicy {
+	for _, p := range s.cachePolicies {
+		if p.matches(route) {
+			return p
+		}
+	}
+	p := s.defaultCachePolicy
+	p.Route = route
+	return p
+}
+
+// cacheBypassed reports whether the request asks to skip the cache and the
+// policy allows it.
+func cacheBypassed(c *gin.Context, p cachePolicy) bool {
+	return p.AllowBypass && strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseCachePolicies(t *testing.T) {
+	defaults := cachePolicy{TTL: time.Minute, NegativeTTL: 10 * time.Second}
+	got := parseCachePolicies("/pokemon/:name=ttl=10m;swr=30s;bypass,/bad=ttl=oops,/x=nope=1s,/export/*=negative_ttl=0s", defaults)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 policies, got %+v", got)
+	}
+	want := cachePolicy{Route: "/pokemon/:name", TTL: 10 * time.Minute, NegativeTTL: 10 * time.Second, StaleWhileRevalidate: 30 * time.Second, AllowBypass: true}
+	if got[0] != want {
+		t.Fatalf("unexpected policy %+v", got[0])
+	}
+	if !got[1].matches("/export/pokedex.csv") || got[1].NegativeTTL != 0 || got[1].TTL != time.Minute {
+		t.Fatalf("unexpected policy %+v", got[1])
+	}
+}
+
+func TestCachePolicyStaleWhileRevalidateAndBypass(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		fmt.Fprintf(w, `{"name":"pikachu","height":%d}`, n)
+	}))
+	defer ts.Close()
+
+	s := &Server{httpClient: ts.Client(), cache: newPokemonCache(time.Minute), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL,
+		cachePolicies: []cachePolicy{{Route: pokemonRoute, TTL: 20 * time.Millisecond, StaleWhileRevalidate: time.Minute, AllowBypass: true}}}
+	r := setupRouter(s)
+	get := func(header string) string {
+		req := httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil)
+		if header != "" {
+			req.Header.Set("Cache-Control", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	get("")
+	time.Sleep(30 * time.Millisecond)
+	if body := get(""); !strings.Contains(body, `"height":1`) {
+		t.Fatalf("expected the stale entry to be served, got %s", body)
+	}
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("expected a background refresh, got %d upstream calls", n)
+	}
+	if body := get("no-cache"); !strings.Contains(body, `"height":3`) {
+		t.Fatalf("expected bypass to refetch, got %s", body)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+const (
+	// peerExportTimeout bounds reading a peer's whole cache export.
+	peerExportTimeout = 2 * time.Minute
+	// maxCacheImportJobs is how many import jobs are kept for status queries.
+	maxCacheImportJobs = 20
+	// maxRecordBytes bounds one line of a cache export.
+	maxRecordBytes = 1 << 20
+	// exportFlushEvery is how many records are written between flushes.
+	exportFlushEvery = 100
+)
+
+// newPeerClient returns the client used to reach other instances. Peers are
+// internal, so it must not be the egress-restricted upstream client. With a
+// key, requests carry it as X-API-Key, as the peers' admin routes require.
+func newPeerClient(key string) *http.Client {
+	c := &http.Client{Timeout: peerExportTimeout}
+	if key != "" {
+		c.Transport = peerKeyTransport{key: key, base: http.DefaultTransport}
+	}
+	return c
+}
+
+// peerKeyTransport authenticates requests to peers with an API key.
+type peerKeyTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+func (t peerKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-API-Key", t.key)
+	return t.base.RoundTrip(req)
+}
+
+// adminCacheExportHandler serves GET /admin/cache/export: the pokemon cache as
+// newline-delimited snapshot records, one entry per line with its original
+// timestamps, so a peer can restore it with the same expiry. X-Entry-Count
+// announces how many records follow.
+func (s *Server) adminCacheExportHandler(c *gin.Context) {
+	records := snapshotRecords(s.cache)
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("X-Entry-Count", strconv.Itoa(len(records)))
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+	for i, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return // client went away
+		}
+		if (i+1)%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+	log.Printf("cache export: %d entries, %d bytes", len(records), c.Writer.Size())
+}
+
+// decodeRecords reads newline-delimited snapshot records from r, calling fn
+// with each record and the size of its line.
+func decodeRecords(r io.Reader, fn func(rec snapshotRecord, size int)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxRecordBytes)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec snapshotRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("cache export: %w", err)
+		}
+		fn(rec, len(line)+1)
+	}
+	return sc.Err()
+}
+
+// openPeerExport requests a peer's cache export from baseURL. The announced
+// entry count is -1 when the peer did not send one.
+func openPeerExport(ctx context.Context, client *http.Client, baseURL string) (io.ReadCloser, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/admin/cache/export", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("peer export answered %d", resp.StatusCode)
+	}
+	total, err := strconv.Atoi(resp.Header.Get("X-Entry-Count"))
+	if err != nil {
+		total = -1
+	}
+	return resp.Body, total, nil
+}
+
+// fetchPeerRecords reads a peer's whole cache export from baseURL.
+func fetchPeerRecords(ctx context.Context, client *http.Client, baseURL string) ([]snapshotRecord, error) {
+	body, _, err := openPeerExport(ctx, client, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var records []snapshotRecord
+	err = decodeRecords(body, func(rec snapshotRecord, _ int) {
+		records = append(records, rec)
+	})
+	return records, err
+}
+
+// cacheImportJob is one cache import, shared between the importer and
+// status readers.
+type cacheImportJob struct {
+	mu         sync.Mutex
+	id         string
+	source     string
+	total      int // announced entries, -1 if unknown
+	entries    int
+	restored   int
+	bytes      int64
+	startedAt  time.Time
+	finishedAt time.Time
+	err        string
+}
+
+// cacheImportStatus is the JSON view of a cache import. Skipped entries had
+// expired (or were malformed) by the time they arrived.
+type cacheImportStatus struct {
+	ID         string     `json:"id"`
+	Source     string     `json:"source"`
+	State      string     `json:"state"`
+	Total      *int       `json:"total,omitempty"`
+	Entries    int        `json:"entries"`
+	Restored   int        `json:"restored"`
+	Skipped    int        `json:"skipped"`
+	Bytes      int64      `json:"bytes"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func newCacheImportJob(source string) *cacheImportJob {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &cacheImportJob{id: hex.EncodeToString(b), source: source, total: -1, startedAt: time.Now()}
+}
+
+func (j *cacheImportJob) setTotal(n int) {
+	j.mu.Lock()
+	j.total = n
+	j.mu.Unlock()
+}
+
+func (j *cacheImportJob) record(restored bool, size int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries++
+	j.bytes += int64(size)
+	if restored {
+		j.restored++
+	}
+}
+
+func (j *cacheImportJob) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finishedAt = time.Now()
+	if err != nil {
+		j.err = err.Error()
+	}
+}
+
+func (j *cacheImportJob) status() cacheImportStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := cacheImportStatus{
+		ID: j.id, Source: j.source, State: "running", Entries: j.entries, Restored: j.restored,
+		Skipped: j.entries - j.restored, Bytes: j.bytes, StartedAt: j.startedAt, Error: j.err,
+	}
+	if j.total >= 0 {
+		total := j.total
+		st.Total = &total
+	}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		st.FinishedAt = &finished
+		st.State = "done"
+		if j.err != "" {
+			st.State = "failed"
+		}
+	}
+	return st
+}
+
+// importRecords restores every record read from r into the pokemon cache
+// with its original expiry, recording progress in job.
+func (s *Server) importRecords(r io.Reader, job *cacheImportJob) error {
+	now := time.Now()
+	return decodeRecords(r, func(rec snapshotRecord, size int) {
+		job.record(restoreRecord(s.cache, rec, 0, now), size)
+	})
+}
+
+// runPeerImport imports a peer's cache export in the background.
+func (s *Server) runPeerImport(job *cacheImportJob, peerURL string) {
+	ctx, cancel := context.WithTimeout(context.Background(), peerExportTimeout)
+	defer cancel()
+	body, total, err := openPeerExport(ctx, s.peerClient, peerURL)
+	if err == nil {
+		job.setTotal(total)
+		err = s.importRecords(body, job)
+		body.Close()
+	}
+	job.finish(err)
+	st := job.status()
+	log.Printf("cache import %s from %s: %s, %d of %d entries restored, %d bytes", job.id, peerURL, st.State, st.Restored, st.Entries, st.Bytes)
+}
+
+// adminCacheImportHandler serves POST /admin/cache/import. An NDJSON body
+// (Content-Type application/x-ndjson, as served by /admin/cache/export) is
+// imported inline and answered with the final counts; {"peer_url": ...}
+// pulls that peer's export in the background and answers 202, with progress
+// at GET /admin/cache/import/:id.
+func (s *Server) adminCacheImportHandler(c *gin.Context) {
+	if strings.HasPrefix(c.ContentType(), "application/x-ndjson") {
+		job := newCacheImportJob("body")
+		err := s.importRecords(c.Request.Body, job)
+		job.finish(err)
+		s.cacheImports.add(job.id, job, maxCacheImportJobs)
+		if err != nil {
+			writeError(c, apierror.BadRequest(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, job.status())
+		return
+	}
+
+	var req struct {
+		PeerURL string `json:"peer_url"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.PeerURL == "" {
+		writeError(c, apierror.BadRequest("body must be an NDJSON cache export or a JSON object with peer_url"))
+		return
+	}
+	job := newCacheImportJob(req.PeerURL)
+	s.cacheImports.add(job.id, job, maxCacheImportJobs)
+	go s.runPeerImport(job, req.PeerURL)
+	c.Header("Location", "/admin/cache/import/"+job.id)
+	c.JSON(http.StatusAccepted, job.status())
+}
+
+// adminCacheImportStatusHandler serves GET /admin/cache/import/:id.
+func (s *Server) adminCacheImportStatusHandler(c *gin.Context) {
+	job, ok := s.cacheImports.get(c.Param("id"))
+	if !ok {
+		writeError(c, apierror.NotFound("cache import job not found"))
+		return
+	}
+	c.JSON(http.StatusOK, job.status())
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheExportImport(t *testing.T) {
+	live := withAdminKey(t, newTestServer(""))
+	live.cache.set("pikachu", pokemonCacheEntry{pokemon: pokemonResponse{Name: "pikachu", Height: 4}})
+	live.cache.set("missingno", pokemonCacheEntry{notFound: true})
+	peer := httptest.NewServer(setupRouter(live))
+	defer peer.Close()
+
+	resp, err := newPeerClient(testAdminKey).Get(peer.URL + "/admin/cache/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Entry-Count") != "2" || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected export headers %v", resp.Header)
+	}
+
+	s := withAdminKey(t, newTestServer(""))
+	s.peerClient = newPeerClient(testAdminKey)
+	r := setupRouter(s)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/cache/import", strings.NewReader(`{"peer_url":"`+peer.URL+`"}`)))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodPost, "/admin/cache/import", strings.NewReader(`{"peer_url":"`+peer.URL+`"}`))))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body)
+	}
+	var st cacheImportStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); st.State == "running" && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/cache/import/"+st.ID, nil)))
+		if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil {
+			t.Fatalf("failed to parse status: %v", err)
+		}
+	}
+	if st.State != "done" || st.Total == nil || *st.Total != 2 || st.Restored != 2 || st.Bytes == 0 {
+		t.Fatalf("unexpected import status %+v", st)
+	}
+
+	live.cache.mu.RLock()
+	want := live.cache.data["pikachu"].expiresAt
+	live.cache.mu.RUnlock()
+	s.cache.mu.RLock()
+	got := s.cache.data["pikachu"].expiresAt
+	s.cache.mu.RUnlock()
+	if !got.Equal(want) {
+		t.Fatalf("expected the expiry to be preserved: %v != %v", got, want)
+	}
+	if v, ok := s.cache.get("missingno"); !ok || !v.notFound {
+		t.Fatal("expected the negative entry to be imported")
+	}
+}
+
+func TestCacheImportBody(t *testing.T) {
+	s := withAdminKey(t, newTestServer(""))
+	r := setupRouter(s)
+	exp := time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	body := `{"key":"pikachu","pokemon":{"name":"pikachu"},"stored_at":"` + time.Now().Format(time.RFC3339Nano) + `","expires_at":"` + exp + `"}
+{"key":"raichu","pokemon":{"name":"raichu"},"expires_at":"2000-01-01T00:00:00Z"}
+`
+	req := asAdmin(httptest.NewRequest(http.MethodPost, "/admin/cache/import", strings.NewReader(body)))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var st cacheImportStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if st.Entries != 2 || st.Restored != 1 || st.Skipped != 1 || st.Bytes != int64(len(body)) {
+		t.Fatalf("unexpected import status %+v", st)
+	}
+
+	req = asAdmin(httptest.NewRequest(http.MethodPost, "/admin/cache/import", strings.NewReader("not json\n")))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a malformed export, got %d", w.Code)
+	}
+}
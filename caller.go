@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Caller labels used when the header is absent or not allowlisted. Keeping
+// unknown values out of labels bounds metric cardinality.
+const (
+	callerNone  = "none"
+	callerOther = "other"
+)
+
+// newCallerAllowlist returns the set of caller names accepted as labels.
+func newCallerAllowlist(names []string) map[string]bool {
+	allowed := make(map[string]bool, len(names))
+	for _, n := range names {
+		allowed[strings.ToLower(n)] = true
+	}
+	return allowed
+}
+
+// middleware: tag the request with the calling service from X-Caller (or
+// X-Service), mapped through s.callers so only allowlisted names reach
+// metrics and logs.
+func callerMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.GetHeader("X-Caller")
+		if name == "" {
+			name = c.GetHeader("X-Service")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		caller := callerOther
+		switch {
+		case name == "":
+			caller = callerNone
+		case s.callers[name]:
+			caller = name
+		}
+		c.Set("caller", caller)
+		c.Next()
+	}
+}
+
+// callerLabel returns the request's caller tag.
+func callerLabel(c *gin.Context) string {
+	if v := c.GetString("caller"); v != "" {
+		return v
+	}
+	return callerNone
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCallerMetricsLabel(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{cache: newPokemonCache(0), metrics: m, callers: newCallerAllowlist([]string{"team-battle"})}
+	r := setupRouter(s)
+	for _, h := range []struct{ key, value string }{
+		{"X-Caller", "Team-Battle"},
+		{"X-Service", "team-battle"},
+		{"X-Caller", "random-" + t.Name()},
+		{"", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		if h.key != "" {
+			req.Header.Set(h.key, h.value)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for caller, want := range map[string]float64{"team-battle": 2, callerOther: 1, callerNone: 1} {
+		if v := testutil.ToFloat64(m.requestsTotal.WithLabelValues("/health", "GET", "200", caller)); v != want {
+			t.Fatalf("expected %v requests for caller %q, got %v", want, caller, v)
+		}
+	}
+}
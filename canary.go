@@ -0,0 +1,125 @@
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+)
+
+const (
+	upstreamPrimary = "primary"
+	upstreamCanary  = "canary"
+)
+
+// canaryRouter splits upstream traffic between the primary and a canary base
+// URL. Canary outcomes are tracked over a sliding window of recent calls; once
+// the error rate crosses maxErrorRate all traffic is rolled back to primary
+// for the rest of the process lifetime.
+type canaryRouter struct {
+	primaryURL   string
+	canaryURL    string
+	percent      int
+	maxErrorRate float64
+	minRequests  int
+	metrics      *metrics
+
+	mu         sync.Mutex
+	window     []bool // true = error
+	next       int
+	filled     int
+	rolledBack bool
+}
+
+// newCanaryRouter returns nil (no canary) when canaryURL is empty or percent
+// is not positive.
+func newCanaryRouter(primaryURL, canaryURL string, percent int, maxErrorRate float64, minRequests int, m *metrics) *canaryRouter {
+	if canaryURL == "" || percent <= 0 {
+		return nil
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	if minRequests <= 0 {
+		minRequests = 1
+	}
+	return &canaryRouter{
+		primaryURL:   primaryURL,
+		canaryURL:    canaryURL,
+		percent:      percent,
+		maxErrorRate: maxErrorRate,
+		minRequests:  minRequests,
+		metrics:      m,
+		window:       make([]bool, minRequests),
+	}
+}
+
+// pick returns the base URL and upstream label for the next call.
+func (c *canaryRouter) pick() (string, string) {
+	c.mu.Lock()
+	rolledBack := c.rolledBack
+	c.mu.Unlock()
+	if rolledBack || rand.IntN(100) >= c.percent {
+		return c.primaryURL, upstreamPrimary
+	}
+	return c.canaryURL, upstreamCanary
+}
+
+// record feeds a canary outcome into the window and triggers rollback when
+// the error rate is too high.
+func (c *canaryRouter) record(upstream string, failed bool) {
+	if upstream != upstreamCanary {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rolledBack {
+		return
+	}
+	c.window[c.next] = failed
+	c.next = (c.next + 1) % len(c.window)
+	if c.filled < len(c.window) {
+		c.filled++
+	}
+	if c.filled < c.minRequests {
+		return
+	}
+	errs := 0
+	for _, e := range c.window[:c.filled] {
+		if e {
+			errs++
+		}
+	}
+	rate := float64(errs) / float64(c.filled)
+	if rate > c.maxErrorRate {
+		c.rolledBack = true
+		c.metrics.canaryRolledBack.Set(1)
+		log.Printf("canary rollback: error rate %.2f exceeded %.2f over %d requests", rate, c.maxErrorRate, c.filled)
+	}
+}
+
+// upstreamBaseURL chooses where the next upstream call goes.
+func (s *Server) upstreamBaseURL() (string, string) {
+	if s.canary != nil {
+		return s.canary.pick()
+	}
+	return s.baseURL, upstreamPrimary
+}
+
+// recordUpstreamOutcome updates per-upstream metrics, canary health, the
+// circuit breaker and the latency anomaly detector.
+// Not-found is a valid answer and does not count as an error.
+func (s *Server) recordUpstreamOutcome(upstream string, status int, seconds float64) {
+	failed := status != http.StatusOK && status != http.StatusNotFound
+	result := "ok"
+	if failed {
+		result = "error"
+	}
+	s.metrics.upstreamRequestsTotal.WithLabelValues(upstream, result).Inc()
+	s.metrics.upstreamDurationSec.WithLabelValues(upstream).Observe(seconds)
+	s.anomaly.observe("upstream", seconds)
+	if s.canary != nil {
+		s.canary.record(upstream, failed)
+	}
+	s.breaker.record(failed)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCanaryRollback(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"pikachu"}`))
+	}))
+	defer primary.Close()
+	canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer canary.Close()
+
+	reg := prometheus.NewRegistry()
+	m := newMetrics(reg)
+	s := &Server{
+		httpClient: &http.Client{},
+		cache:      newPokemonCache(0),
+		metrics:    m,
+		baseURL:    primary.URL,
+		canary:     newCanaryRouter(primary.URL, canary.URL, 100, 0.5, 2, m),
+	}
+	r := setupRouter(s)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil))
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("expected canary failure 502, got %d", w.Code)
+		}
+	}
+	if v := testutil.ToFloat64(m.canaryRolledBack); v != 1 {
+		t.Fatalf("expected canary rolled back, gauge=%v", v)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected primary after rollback, got %d", w.Code)
+	}
+	if v := testutil.ToFloat64(m.upstreamRequestsTotal.WithLabelValues(upstreamPrimary, "ok")); v != 1 {
+		t.Fatalf("expected one primary call, got %v", v)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+
+	"ci_education/apierror"
+)
+
+// clientIdleTTL is how long an unused per-client bucket is kept. A bucket idle
+// this long has refilled completely, so dropping it loses nothing.
+const clientIdleTTL = 10 * time.Minute
+
+type clientBucket struct {
+	lim      *rate.Limiter
+	lastSeen time.Time
+}
+
+// clientRateLimiter enforces one token bucket per client IP.
+type clientRateLimiter struct {
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+// newClientRateLimiter returns nil (no limiting) when rate or burst is not
+// positive.
+func newClientRateLimiter(r float64, burst int) *clientRateLimiter {
+	if r <= 0 || burst <= 0 {
+		return nil
+	}
+	return &clientRateLimiter{rate: r, burst: burst, buckets: map[string]*clientBucket{}}
+}
+
+// limiterFor returns ip's bucket, creating it on first use. Idle buckets are
+// swept at most once per clientIdleTTL.
+func (cl *clientRateLimiter) limiterFor(ip string, now time.Time) *rate.Limiter {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if now.Sub(cl.lastSweep) >= clientIdleTTL {
+		for k, b := range cl.buckets {
+			if now.Sub(b.lastSeen) >= clientIdleTTL {
+				delete(cl.buckets, k)
+			}
+		}
+		cl.lastSweep = now
+	}
+	b, ok := cl.buckets[ip]
+	if !ok {
+		b = &clientBucket{lim: rate.NewLimiter(rate.Limit(cl.rate), cl.burst)}
+		cl.buckets[ip] = b
+	}
+	b.lastSeen = now
+	return b.lim
+}
+
+// setTrustedProxies makes c.ClientIP() honor X-Forwarded-For only when the
+// direct peer is one of proxies (IPs or CIDRs); otherwise the peer address is
+// the client. With no proxies, forwarded headers are ignored entirely.
+func setTrustedProxies(r *gin.Engine, proxies []string) {
+	r.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		log.Printf("trusted proxies: %v; trusting none", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+}
+
+// middleware: per-client-IP rate limiting with X-RateLimit-* headers
+func clientRateLimitMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.clientRateLimit == nil {
+			c.Next()
+			return
+		}
+		cl := s.clientRateLimit
+		now := time.Now()
+		lim := cl.limiterFor(c.ClientIP(), now)
+		allowed := lim.AllowN(now, 1)
+		h := c.Writer.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(cl.burst))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, lim.TokensAt(now)))))
+		if !allowed {
+			s.metrics.rateLimitTotal.WithLabelValues("client_ip", "rejected").Inc()
+			wait := time.Duration(float64(time.Second) / cl.rate)
+			h.Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(c, apierror.RateLimited("client rate limit exceeded"))
+			c.Abort()
+			return
+		}
+		s.metrics.rateLimitTotal.WithLabelValues("client_ip", "allowed").Inc()
+		c.Next()
+	}
+}
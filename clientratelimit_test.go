@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestClientRateLimit(t *testing.T) {
+	s := &Server{
+		httpClient:      &http.Client{},
+		cache:           newPokemonCache(0),
+		metrics:         newMetrics(prometheus.NewRegistry()),
+		clientRateLimit: newClientRateLimiter(0.001, 1),
+		trustedProxies:  []string{"10.0.0.0/8"},
+	}
+	r := setupRouter(s)
+
+	do := func(remote, xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		req.RemoteAddr = remote + ":1234"
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := do("192.0.2.1", ""); got != http.StatusOK {
+		t.Fatalf("first request: expected 200, got %d", got)
+	}
+	if got := do("192.0.2.1", ""); got != http.StatusTooManyRequests {
+		t.Fatalf("second request: expected 429, got %d", got)
+	}
+	// An untrusted peer cannot escape its bucket by forging the header.
+	if got := do("192.0.2.1", "198.51.100.7"); got != http.StatusTooManyRequests {
+		t.Fatalf("forged X-Forwarded-For: expected 429, got %d", got)
+	}
+	// Behind a trusted proxy each forwarded client has its own bucket.
+	if got := do("10.1.1.1", "198.51.100.7"); got != http.StatusOK {
+		t.Fatalf("proxied client: expected 200, got %d", got)
+	}
+	if got := do("10.1.1.1", "198.51.100.8, 10.2.2.2"); got != http.StatusOK {
+		t.Fatalf("second proxied client: expected 200, got %d", got)
+	}
+	if got := do("10.1.1.1", "198.51.100.7"); got != http.StatusTooManyRequests {
+		t.Fatalf("repeated proxied client: expected 429, got %d", got)
+	}
+}
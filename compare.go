@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// statComparison puts one attribute of two pokemon side by side. Difference
+// is B minus A; Leader names the pokemon with the larger value and is empty
+// on a tie.
+type statComparison struct {
+	A          int    `json:"a"`
+	B          int    `json:"b"`
+	Difference int    `json:"difference"`
+	Leader     string `json:"leader,omitempty"`
+}
+
+// compareSummary counts how many attributes each pokemon leads. Leader is
+// the pokemon leading more of them, empty when both lead as many.
+type compareSummary struct {
+	Leads  map[string]int `json:"leads"`
+	Leader string         `json:"leader,omitempty"`
+	Ties   int            `json:"ties"`
+}
+
+// compareResponse is the document served by /pokemon/compare.
+type compareResponse struct {
+	A       pokemonResponse           `json:"a"`
+	B       pokemonResponse           `json:"b"`
+	Diff    map[string]statComparison `json:"diff"`
+	Summary compareSummary            `json:"summary"`
+}
+
+// comparePokemon diffs the height, weight and base experience of a and b.
+func comparePokemon(a, b pokemonResponse) compareResponse {
+	resp := compareResponse{
+		A: a, B: b, Diff: map[string]statComparison{},
+		Summary: compareSummary{Leads: map[string]int{a.Name: 0, b.Name: 0}},
+	}
+	add := func(attr string, va, vb int) {
+		st := statComparison{A: va, B: vb, Difference: vb - va}
+		switch {
+		case va > vb:
+			st.Leader = a.Name
+		case vb > va:
+			st.Leader = b.Name
+		default:
+			resp.Summary.Ties++
+		}
+		if st.Leader != "" {
+			resp.Summary.Leads[st.Leader]++
+		}
+		resp.Diff[attr] = st
+	}
+	add("height", a.Height, b.Height)
+	add("weight", a.Weight, b.Weight)
+	add("base_experience", a.BaseExperience, b.BaseExperience)
+
+	switch la, lb := resp.Summary.Leads[a.Name], resp.Summary.Leads[b.Name]; {
+	case la > lb:
+		resp.Summary.Leader = a.Name
+	case lb > la:
+		resp.Summary.Leader = b.Name
+	}
+	return resp
+}
+
+// compareHandler serves GET /pokemon/compare?a=&b=. Both pokemon are fetched
+// concurrently through the pokemon cache; either failing fails the request.
+func (s *Server) compareHandler(c *gin.Context) {
+	nameA, nameB := c.Query("a"), c.Query("b")
+	if nameA == "" || nameB == "" {
+		writeError(c, apierror.BadRequest("query parameters a and b are required"))
+		return
+	}
+	ctx := c.Request.Context()
+
+	var (
+		wg      sync.WaitGroup
+		b       pokemonResponse
+		bStatus int
+		bErr    error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b, bStatus, bErr = s.getPokemon(ctx, nameB)
+	}()
+	a, aStatus, aErr := s.getPokemon(ctx, nameA)
+	wg.Wait()
+
+	if aErr != nil {
+		writeError(c, apierror.FromUpstream(aStatus, aErr, "pokemon "+nameA+" not found"))
+		return
+	}
+	if bErr != nil {
+		writeError(c, apierror.FromUpstream(bStatus, bErr, "pokemon "+nameB+" not found"))
+		return
+	}
+	c.JSON(http.StatusOK, comparePokemon(a, b))
+}
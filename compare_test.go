@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestComparePokemon(t *testing.T) {
+	got := comparePokemon(
+		pokemonResponse{Name: "pikachu", Height: 4, Weight: 60, BaseExperience: 112},
+		pokemonResponse{Name: "raichu", Height: 8, Weight: 300, BaseExperience: 112},
+	)
+	if d := got.Diff["weight"]; d != (statComparison{A: 60, B: 300, Difference: 240, Leader: "raichu"}) {
+		t.Fatalf("unexpected weight diff %+v", d)
+	}
+	if d := got.Diff["base_experience"]; d.Leader != "" || d.Difference != 0 {
+		t.Fatalf("expected a tie on base experience, got %+v", d)
+	}
+	if got.Summary.Leader != "raichu" || got.Summary.Leads["raichu"] != 2 || got.Summary.Ties != 1 {
+		t.Fatalf("unexpected summary %+v", got.Summary)
+	}
+}
+
+func TestCompareEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu": `{"name":"pikachu","height":4,"weight":60,"base_experience":112}`,
+		"/pokemon/raichu":  `{"name":"raichu","height":8,"weight":300,"base_experience":218}`,
+	})
+	s := newTestServer(ts.URL)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/compare?a=pikachu&b=raichu", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp compareResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.A.Name != "pikachu" || resp.B.Name != "raichu" || resp.Diff["height"].Difference != 4 || resp.Summary.Leader != "raichu" {
+		t.Fatalf("unexpected comparison %+v", resp)
+	}
+	if _, ok := s.cache.get("raichu"); !ok {
+		t.Fatal("expected compared pokemon to be cached")
+	}
+
+	for query, want := range map[string]int{
+		"?a=pikachu":             http.StatusBadRequest,
+		"?a=pikachu&b=missingno": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/compare"+query, nil))
+		if w.Code != want {
+			t.Fatalf("%s: expected status %d, got %d", query, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// defaultDexSize is the number of pokemon in the national dex.
+const defaultDexSize = 1025
+
+// dailyID deterministically maps a UTC date to a national dex id in [1, n].
+func dailyID(seed, date string, n int) int {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	h.Write([]byte{0})
+	h.Write([]byte(date))
+	return int(h.Sum64()%uint64(n)) + 1
+}
+
+// dailyPokemonHandler serves GET /pokemon/daily: one pokemon per UTC day,
+// chosen by hashing the date (and DAILY_POKEMON_SEED) over national dex ids
+// 1..DAILY_POKEMON_MAX_ID. The range is fixed, so the pick does not depend
+// on the upstream list. The response is cacheable until the next UTC
+// midnight.
+func (s *Server) dailyPokemonHandler(c *gin.Context) {
+	now := time.Now().UTC()
+	date := now.Format(time.DateOnly)
+
+	n := s.dexSize
+	if n <= 0 {
+		n = defaultDexSize
+	}
+	id := strconv.Itoa(dailyID(s.dailySeed, date, n))
+
+	p, status, err := s.getPokemon(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+		return
+	}
+
+	rollover := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	maxAge := int(rollover.Sub(now).Seconds())
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	c.Header("Expires", rollover.Format(http.TimeFormat))
+	c.JSON(http.StatusOK, gin.H{"date": date, "pokemon": p})
+}
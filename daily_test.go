@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDailyIDDeterministic(t *testing.T) {
+	a := dailyID("", "2026-10-14", 1000)
+	if a != dailyID("", "2026-10-14", 1000) {
+		t.Fatal("same date must pick the same id")
+	}
+	differs := false
+	for d := 15; d < 25; d++ {
+		id := dailyID("", "2026-10-"+strconv.Itoa(d), 1000)
+		if id < 1 || id > 1000 {
+			t.Fatalf("id %d is outside 1..1000", id)
+		}
+		if id != a {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Fatal("expected rotation across days")
+	}
+}
+
+func TestDailyPokemon(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/1": `{"name":"bulbasaur","height":7,"weight":69,"base_experience":64}`,
+	})
+	s := newTestServer(ts.URL)
+	s.dexSize = 1
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/daily", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Date    string          `json:"date"`
+		Pokemon pokemonResponse `json:"pokemon"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if body.Pokemon.Name != "bulbasaur" || body.Date != time.Now().UTC().Format(time.DateOnly) {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+	cc := w.Header().Get("Cache-Control")
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(cc, "public, max-age="))
+	if err != nil || maxAge <= 0 || maxAge > 86400 {
+		t.Fatalf("unexpected Cache-Control %q", cc)
+	}
+}
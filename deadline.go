@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// grpcTimeoutUnits maps grpc-timeout unit suffixes to durations.
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseGRPCTimeout parses a grpc-timeout value: up to 8 digits followed by a
+// unit (H, M, S, m, u or n), e.g. "250m".
+func parseGRPCTimeout(v string) (time.Duration, error) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, errors.New("malformed timeout")
+	}
+	unit, ok := grpcTimeoutUnits[v[len(v)-1]]
+	if !ok {
+		return 0, errors.New("unknown timeout unit")
+	}
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, errors.New("malformed timeout")
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// requestDeadline returns the deadline the caller asked for, if any.
+// X-Request-Deadline carries an absolute RFC 3339 time and wins over the
+// relative Grpc-Timeout.
+func requestDeadline(c *gin.Context, now time.Time) (time.Time, bool, error) {
+	if v := c.GetHeader("X-Request-Deadline"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, false, errors.New("X-Request-Deadline must be an RFC 3339 time")
+		}
+		return t, true, nil
+	}
+	if v := c.GetHeader("Grpc-Timeout"); v != "" {
+		d, err := parseGRPCTimeout(v)
+		if err != nil {
+			return time.Time{}, false, errors.New("Grpc-Timeout: " + err.Error())
+		}
+		return now.Add(d), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// middleware: bound the request context by the caller's deadline, capped at
+// s.maxDeadline, so upstream calls and retries stop when the caller gives up.
+// Requests without a deadline header are left alone.
+func deadlineMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		deadline, ok, err := requestDeadline(c, now)
+		if err != nil {
+			writeError(c, apierror.BadRequest(err.Error()))
+			c.Abort()
+			return
+		}
+		if !ok {
+			c.Next()
+			return
+		}
+		if s.maxDeadline > 0 && deadline.After(now.Add(s.maxDeadline)) {
+			deadline = now.Add(s.maxDeadline)
+		}
+		if !deadline.After(now) {
+			writeError(c, apierror.DeadlineExceeded("request deadline already passed"))
+			c.Abort()
+			return
+		}
+		ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
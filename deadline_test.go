@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseGRPCTimeout(t *testing.T) {
+	if d, err := parseGRPCTimeout("250m"); err != nil || d != 250*time.Millisecond {
+		t.Fatalf("expected 250ms, got %v %v", d, err)
+	}
+	for _, v := range []string{"", "5", "5x", "m", "123456789S"} {
+		if _, err := parseGRPCTimeout(v); err == nil {
+			t.Fatalf("expected error for %q", v)
+		}
+	}
+}
+
+func TestDeadlineMiddleware(t *testing.T) {
+	s := &Server{maxDeadline: time.Second}
+	r := gin.New()
+	r.Use(deadlineMiddleware(s))
+	var remaining time.Duration
+	var hasDeadline bool
+	r.GET("/x", func(c *gin.Context) {
+		var deadline time.Time
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+		c.Status(http.StatusNoContent)
+	})
+
+	do := func(header, value string) int {
+		req := httptest.NewRequest(http.MethodGet, "/x", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do("", ""); code != http.StatusNoContent || hasDeadline {
+		t.Fatalf("expected no deadline without header, got %d %v", code, hasDeadline)
+	}
+	if code := do("Grpc-Timeout", "200m"); code != http.StatusNoContent || !hasDeadline || remaining > 200*time.Millisecond {
+		t.Fatalf("expected ~200ms deadline, got %d %v", code, remaining)
+	}
+	if code := do("X-Request-Deadline", time.Now().Add(time.Hour).Format(time.RFC3339Nano)); code != http.StatusNoContent || remaining > time.Second {
+		t.Fatalf("expected deadline capped at 1s, got %d %v", code, remaining)
+	}
+	if code := do("X-Request-Deadline", time.Now().Add(-time.Second).Format(time.RFC3339Nano)); code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504 for expired deadline, got %d", code)
+	}
+	if code := do("X-Request-Deadline", "soon"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed deadline, got %d", code)
+	}
+}
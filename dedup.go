@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// maxDedupBodyBytes bounds the response kept for replay; larger responses
+// are streamed to their own client only and not shared.
+const maxDedupBodyBytes = 1 << 20
+
+// dedupKeyHeaders are the request headers that, with the client IP and the
+// URL, make two GETs identical. The credential and impersonation headers
+// keep requests made as different principals apart.
+var dedupKeyHeaders = []string{"Authorization", "X-API-Key", "X-Impersonate", "X-Caller", "Accept", "Accept-Encoding", "Accept-Language", "Range"}
+
+// dedupEntry is one request being served, or served within the window.
+type dedupEntry struct {
+	done       chan struct{} // closed once the response below is set
+	replayable bool
+	status     int
+	header     http.Header
+	body       []byte
+	finishedAt time.Time
+}
+
+// requestDeduper shares one response between identical GET requests from
+// the same client that arrive while the first is in flight or within window
+// after it finished.
+type requestDeduper struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*dedupEntry
+	lastSweep time.Time
+}
+
+// newRequestDeduper returns nil (no deduplication) when window is not
+// positive.
+func newRequestDeduper(window time.Duration) *requestDeduper {
+	if window <= 0 {
+		return nil
+	}
+	return &requestDeduper{window: window, entries: map[string]*dedupEntry{}}
+}
+
+// dedupKey identifies a request by client, URL and the headers that can
+// change its response.
+func dedupKey(c *gin.Context) string {
+	h := sha256.New()
+	h.Write([]byte(c.ClientIP() + "\n" + c.Request.URL.RequestURI()))
+	for _, name := range dedupKeyHeaders {
+		h.Write([]byte("\n" + strings.Join(c.Request.Header.Values(name), ",")))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// join returns the live entry for key and false, or registers a new entry
+// the caller must complete and true. Entries past the window are swept at
+// most once per window.
+func (d *requestDeduper) join(key string, now time.Time) (*dedupEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastSweep) >= d.window {
+		for k, e := range d.entries {
+			if d.expired(e, now) {
+				delete(d.entries, k)
+			}
+		}
+		d.lastSweep = now
+	}
+	if e, ok := d.entries[key]; ok && !d.expired(e, now) {
+		return e, false
+	}
+	e := &dedupEntry{done: make(chan struct{})}
+	d.entries[key] = e
+	return e, true
+}
+
+// expired reports whether a finished entry is past the window. Callers hold
+// d.mu.
+func (d *requestDeduper) expired(e *dedupEntry, now time.Time) bool {
+	return !e.finishedAt.IsZero() && now.Sub(e.finishedAt) >= d.window
+}
+
+// finish publishes the leader's response; completed is false when its
+// handler panicked. Server errors are handed to requests already waiting but
+// not kept, so a retry does the work again.
+func (d *requestDeduper) finish(key string, e *dedupEntry, w *captureWriter, completed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e.replayable = completed && !w.overflow
+	e.status, e.header, e.body = w.Status(), w.Header().Clone(), w.buf.Bytes()
+	e.finishedAt = time.Now()
+	if (!e.replayable || e.status >= http.StatusInternalServerError) && d.entries[key] == e {
+		delete(d.entries, key)
+	}
+	close(e.done)
+}
+
+// captureWriter passes a response through to the client while keeping a copy
+// of its body, up to maxDedupBodyBytes.
+type captureWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	if !w.overflow {
+		if w.buf.Len()+n > maxDedupBodyBytes {
+			w.overflow = true
+			w.buf = bytes.Buffer{}
+		} else {
+			w.buf.Write(b[:n])
+		}
+	}
+	return n, err
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) { return w.Write([]byte(s)) }
+
+func dedupExempt(path string) bool {
+	switch path {
+	case "", "/health", "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	return strings.HasPrefix(path, "/admin/")
+}
+
+// middleware: identical GETs from one client within the dedup window are
+// answered with the first one's response, without running the handler
+// again. Followers wait for a leader still in flight. Replays carry
+// X-Deduplicated and are counted in requests_deduplicated_total.
+func dedupMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.dedup == nil || c.Request.Method != http.MethodGet || dedupExempt(c.FullPath()) {
+			c.Next()
+			return
+		}
+		key := dedupKey(c)
+		e, leader := s.dedup.join(key, time.Now())
+		if leader {
+			w := &captureWriter{ResponseWriter: c.Writer}
+			c.Writer = w
+			completed := false
+			defer func() {
+				c.Writer = w.ResponseWriter
+				s.dedup.finish(key, e, w, completed)
+			}()
+			c.Next()
+			completed = true
+			return
+		}
+
+		source := "recent"
+		select {
+		case <-e.done:
+		default:
+			source = "in_flight"
+			select {
+			case <-e.done:
+			case <-c.Request.Context().Done():
+				writeError(c, apierror.DeadlineExceeded("request ended while waiting for an identical one"))
+				c.Abort()
+				return
+			}
+		}
+		if !e.replayable {
+			c.Next()
+			return
+		}
+		s.metrics.dedupedRequestsTotal.WithLabelValues(c.FullPath(), source).Inc()
+		h := c.Writer.Header()
+		for name, values := range e.header {
+			if name != "X-Request-Id" {
+				h[name] = values
+			}
+		}
+		h.Set("X-Deduplicated", "true")
+		c.Writer.WriteHeader(e.status)
+		_, _ = c.Writer.Write(e.body)
+		c.Abort()
+	}
+}
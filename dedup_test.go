@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDedupSharesIdenticalRequests(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"pikachu","height":4,"weight":60,"base_experience":112}`))
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	s.cache = nil // every handler run reaches the upstream
+	s.dedup = newRequestDeduper(time.Minute)
+	r := setupRouter(s)
+
+	get := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 3)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = get("10.0.0.1")
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // let the followers join the leader
+	close(release)
+	wg.Wait()
+
+	deduped := 0
+	for _, w := range results {
+		if w.Code != http.StatusOK || w.Body.String() == "" {
+			t.Fatalf("expected every request to get the pokemon, got %d %q", w.Code, w.Body)
+		}
+		if w.Header().Get("X-Deduplicated") == "true" {
+			deduped++
+		}
+	}
+	if deduped != 2 || calls.Load() != 1 {
+		t.Fatalf("expected 2 of 3 requests deduplicated with 1 upstream call, got %d and %d calls", deduped, calls.Load())
+	}
+	if w := get("10.0.0.1"); w.Header().Get("X-Deduplicated") != "true" || calls.Load() != 1 {
+		t.Fatal("expected a repeat within the window to be served from the result buffer")
+	}
+	if got := testutil.ToFloat64(s.metrics.dedupedRequestsTotal.WithLabelValues("/pokemon/:name", "in_flight")); got != 2 {
+		t.Fatalf("expected 2 in-flight dedups counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(s.metrics.dedupedRequestsTotal.WithLabelValues("/pokemon/:name", "recent")); got != 1 {
+		t.Fatalf("expected 1 recent dedup counted, got %v", got)
+	}
+
+	if w := get("10.0.0.2"); w.Header().Get("X-Deduplicated") != "" || calls.Load() != 2 {
+		t.Fatal("expected another client's request to be served on its own")
+	}
+}
+
+func TestDedupKeepsImpersonationApart(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"name":"pikachu"}`))
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	s.cache = nil
+	s.dedup = newRequestDeduper(time.Minute)
+	r := setupRouter(s)
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 2)
+	for i, as := range []string{"", "ash"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil)
+			if as != "" {
+				req.Header.Set("X-Impersonate", as)
+			}
+			results[i] = httptest.NewRecorder()
+			r.ServeHTTP(results[i], req)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, w := range results {
+		if w.Header().Get("X-Deduplicated") != "" {
+			t.Fatal("expected an impersonated request not to share another principal's response")
+		}
+	}
+}
+
+func TestDedupWindowAndServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"pikachu"}`))
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	s.cache = nil
+	s.retry.MaxAttempts = 1
+	s.dedup = newRequestDeduper(50 * time.Millisecond)
+	r := setupRouter(s)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	get("/pokemon/pikachu")
+	if w := get("/pokemon/pikachu"); w.Header().Get("X-Deduplicated") != "true" {
+		t.Fatal("expected a repeat within the window to be deduplicated")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if w := get("/pokemon/pikachu"); w.Header().Get("X-Deduplicated") != "" || calls.Load() != 2 {
+		t.Fatalf("expected a repeat after the window to run again, got %d calls", calls.Load())
+	}
+
+	failing.Store(true)
+	for i := 0; i < 2; i++ {
+		if w := get("/pokemon/raichu"); w.Code < 500 || w.Header().Get("X-Deduplicated") != "" {
+			t.Fatalf("expected each failing request to be retried for real, got %d", w.Code)
+		}
+	}
+	if calls.Load() != 4 {
+		t.Fatalf("expected the retry to reach the upstream again, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// maxFieldDiffs caps how many differing fields are kept per sample.
+const maxFieldDiffs = 20
+
+// fieldDiff is a single differing JSON field between primary and shadow.
+type fieldDiff struct {
+	Field   string `json:"field"`
+	Primary any    `json:"primary"`
+	Shadow  any    `json:"shadow"`
+}
+
+// diffSample is a recorded mismatch for one mirrored request.
+type diffSample struct {
+	Path   string      `json:"path"`
+	At     time.Time   `json:"at"`
+	Fields []fieldDiff `json:"fields"`
+}
+
+// shadowDiffer compares primary and shadow JSON bodies, ignoring configured
+// fields, and keeps mismatch counts plus the most recent sampled diffs.
+type shadowDiffer struct {
+	ignore     map[string]bool
+	maxSamples int
+	metrics    *metrics
+
+	mu          sync.Mutex
+	compared    int
+	mismatched  int
+	fieldCounts map[string]int
+	samples     []diffSample
+}
+
+// newShadowDiffer builds a differ. Ignored fields match either a full dotted
+// path ("sprites.front_default") or a bare key name at any depth ("order").
+func newShadowDiffer(ignore []string, maxSamples int, m *metrics) *shadowDiffer {
+	if maxSamples <= 0 {
+		maxSamples = 1
+	}
+	d := &shadowDiffer{ignore: make(map[string]bool), maxSamples: maxSamples, metrics: m, fieldCounts: make(map[string]int)}
+	for _, f := range ignore {
+		d.ignore[f] = true
+	}
+	return d
+}
+
+func (d *shadowDiffer) compare(path string, primary, shadow []byte) {
+	var a, b any
+	if err := json.Unmarshal(primary, &a); err != nil {
+		d.metrics.shadowDiffsTotal.WithLabelValues("invalid").Inc()
+		return
+	}
+	if err := json.Unmarshal(shadow, &b); err != nil {
+		d.metrics.shadowDiffsTotal.WithLabelValues("invalid").Inc()
+		return
+	}
+	var diffs []fieldDiff
+	d.diffValues("", a, b, &diffs)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.compared++
+	if len(diffs) == 0 {
+		d.metrics.shadowDiffsTotal.WithLabelValues("match").Inc()
+		return
+	}
+	d.mismatched++
+	d.metrics.shadowDiffsTotal.WithLabelValues("mismatch").Inc()
+	for _, f := range diffs {
+		d.fieldCounts[f.Field]++
+	}
+	if len(d.samples) == d.maxSamples {
+		d.samples = d.samples[1:]
+	}
+	d.samples = append(d.samples, diffSample{Path: path, At: time.Now().UTC(), Fields: diffs})
+}
+
+func (d *shadowDiffer) ignored(field string) bool {
+	if d.ignore[field] {
+		return true
+	}
+	key := field
+	if i := strings.LastIndexByte(field, '.'); i >= 0 {
+		key = field[i+1:]
+	}
+	if i := strings.IndexByte(key, '['); i >= 0 {
+		key = key[:i]
+	}
+	return d.ignore[key]
+}
+
+func (d *shadowDiffer) diffValues(field string, a, b any, out *[]fieldDiff) {
+	if len(*out) >= maxFieldDiffs || (field != "" && d.ignored(field)) {
+		return
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, dup := av[k]; !dup {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if field != "" {
+				child = field + "." + k
+			}
+			d.diffValues(child, av[k], bv[k], out)
+		}
+		return
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			break
+		}
+		for i := range av {
+			d.diffValues(fmt.Sprintf("%s[%d]", field, i), av[i], bv[i], out)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*out = append(*out, fieldDiff{Field: field, Primary: a, Shadow: b})
+	}
+}
+
+// adminDiffsHandler reports shadow diff counts and recent samples.
+func (s *Server) adminDiffsHandler(c *gin.Context) {
+	if s.shadow == nil || s.shadow.differ == nil {
+		writeError(c, apierror.NotFound("shadow diffing is not enabled"))
+		return
+	}
+	d := s.shadow.differ
+	d.mu.Lock()
+	fields := make(map[string]int, len(d.fieldCounts))
+	for k, v := range d.fieldCounts {
+		fields[k] = v
+	}
+	samples := append([]diffSample(nil), d.samples...)
+	compared, mismatched := d.compared, d.mismatched
+	d.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"compared":   compared,
+		"mismatched": mismatched,
+		"fields":     fields,
+		"samples":    samples,
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestShadowDifferIgnoresConfiguredFields(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	d := newShadowDiffer([]string{"order", "sprites.back"}, 5, m)
+
+	d.compare("/pokemon/pikachu",
+		[]byte(`{"name":"pikachu","order":1,"sprites":{"back":"a","front":"x"},"types":["electric"]}`),
+		[]byte(`{"name":"pikachu","order":2,"sprites":{"back":"b","front":"y"},"types":["electric","fairy"]}`))
+	d.compare("/pokemon/ditto", []byte(`{"name":"ditto","order":1}`), []byte(`{"name":"ditto","order":9}`))
+
+	if d.compared != 2 || d.mismatched != 1 {
+		t.Fatalf("expected 2 compared / 1 mismatched, got %d / %d", d.compared, d.mismatched)
+	}
+	got := d.samples[0].Fields
+	if len(got) != 2 || got[0].Field != "sprites.front" || got[1].Field != "types" {
+		t.Fatalf("unexpected diff fields: %+v", got)
+	}
+}
+
+func TestAdminDiffs(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := newMetrics(reg)
+	shadow := newShadowMirror(http.DefaultClient, "http://shadow.invalid", 100, 0, m)
+	shadow.differ = newShadowDiffer(nil, 5, m)
+	shadow.differ.compare("/pokemon/pikachu", []byte(`{"height":4}`), []byte(`{"height":5}`))
+
+	s := &Server{httpClient: &http.Client{}, cache: newPokemonCache(0), metrics: m, shadow: shadow}
+	r := setupRouter(withAdminKey(t, s))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/diffs", nil)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var body struct {
+		Mismatched int            `json:"mismatched"`
+		Fields     map[string]int `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if body.Mismatched != 1 || body.Fields["height"] != 1 {
+		t.Fatalf("unexpected report: %+v", body)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// statusClientClosedRequest is the status recorded in metrics for requests
+// the client abandoned before the response was written (nginx's 499); it is
+// never sent.
+const statusClientClosedRequest = 499
+
+// clientGone reports whether ctx was canceled because the client went away,
+// as opposed to running out of its deadline.
+func clientGone(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// acquireSlot takes a slot of sem for one unit of fan-out work, unless ctx is
+// done first; fan-outs stop starting work once their client is gone.
+func acquireSlot(ctx context.Context, sem chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// errClientGone is returned for work skipped because its client went away.
+var errClientGone = errors.New("client closed the request")
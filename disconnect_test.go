@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFetchUpstreamSkipsGoneClient(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls.Add(1) }))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	s.metrics = newMetrics(prometheus.NewRegistry())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out any
+	if _, err := s.fetchUpstream(ctx, "/pokemon/pikachu", &out); err != errClientGone {
+		t.Fatalf("expected errClientGone, got %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Fatal("expected no upstream call for a gone client")
+	}
+	if v := testutil.ToFloat64(s.metrics.extCallsTotal.WithLabelValues("pokeapi", "canceled")); v != 1 {
+		t.Fatalf("expected 1 canceled call, got %v", v)
+	}
+	if v := testutil.ToFloat64(s.metrics.upstreamRequestsTotal.WithLabelValues(upstreamPrimary, "error")); v != 0 {
+		t.Fatalf("expected the canceled call not to count as an upstream error, got %v", v)
+	}
+}
+
+func TestGoneClientReleasesProbe(t *testing.T) {
+	entered := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	s.breaker = openBreaker(s.metrics)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-entered
+		cancel()
+	}()
+	var out any
+	if _, err := s.fetchUpstream(ctx, "/pokemon/pikachu", &out); err != errClientGone {
+		t.Fatalf("expected errClientGone, got %v", err)
+	}
+	if !s.breaker.allow() {
+		t.Fatal("expected the abandoned probe to give back its slot")
+	}
+}
+
+func TestBatchStopsWhenClientDisconnects(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{}, maxBatchNames)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-r.Context().Done() // never answers; only the client giving up ends it
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	s.metrics = newMetrics(prometheus.NewRegistry())
+	r := setupRouter(s)
+
+	names := make([]string, 30)
+	for i := range names {
+		names[i] = `"p` + strings.Repeat("x", i+1) + `"`
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/pokemon/batch", strings.NewReader("["+strings.Join(names, ",")+"]")).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("batch kept running after the client disconnected")
+	}
+	if n := calls.Load(); n > batchWorkers {
+		t.Fatalf("expected at most %d upstream calls, got %d", batchWorkers, n)
+	}
+	if v := testutil.ToFloat64(s.metrics.requestsAbortedTotal.WithLabelValues("/pokemon/batch")); v != 1 {
+		t.Fatalf("expected 1 aborted request, got %v", v)
+	}
+	if v := testutil.ToFloat64(s.metrics.requestsTotal.WithLabelValues("/pokemon/batch", http.MethodPost, "499", "none")); v != 1 {
+		t.Fatalf("expected the request recorded as 499, got %v", v)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// playgroundHTML is a self-contained "try it" console. It calls the server's
+// own endpoints from the browser, so requests pass through the regular
+// middleware chain.
+//
+//go:embed web/playground.html
+var playgroundHTML []byte
+
+func playgroundHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", playgroundHTML)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDriftEntries bounds the distinct (kind, field, reason) entries kept in
+// the drift report.
+const maxDriftEntries = 1000
+
+// Drift reasons.
+const (
+	driftUnknown  = "unknown"       // top-level field not in the kind's baseline
+	driftMissing  = "missing"       // field we decode is absent
+	driftMismatch = "type_mismatch" // field we decode has another type; left zero
+)
+
+// errDriftShape reports a payload whose top-level type does not match at all.
+var errDriftShape = errors.New("payload does not have the expected shape")
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// driftEvent is one difference between an upstream payload and the struct it
+// is decoded into. Array indices in Field are collapsed to "[]" and map keys
+// to "*".
+type driftEvent struct {
+	Field  string
+	Reason string
+}
+
+// decodeTolerant decodes body into out like json.Unmarshal, except that
+// fields whose JSON type does not match out are dropped (and left zero)
+// instead of failing the decode. It returns those fields, the fields of out
+// absent from body, and the top-level keys of body. Only a body that is not
+// JSON, or whose top-level type is wrong, is an error.
+func decodeTolerant(body []byte, out any) (events []driftEvent, keys []string, err error) {
+	var raw any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, nil, err
+	}
+	if m, ok := raw.(map[string]any); ok {
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	t := reflect.TypeOf(out).Elem()
+	clean, ok := checkDrift(raw, t, "", &events)
+	if !ok {
+		return events, keys, errDriftShape
+	}
+	for _, e := range events {
+		if e.Reason == driftMismatch {
+			// re-encode without the offending fields
+			if body, err = json.Marshal(clean); err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+	}
+	return events, keys, json.Unmarshal(body, out)
+}
+
+// checkDrift compares a decoded JSON value with the Go type it will be
+// decoded into, appending differences to events. It returns v with
+// mismatching fields and elements removed, and false when v itself does not
+// match t.
+func checkDrift(v any, t reflect.Type, path string, events *[]driftEvent) (any, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if v == nil || t.Kind() == reflect.Interface ||
+		reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return v, true
+	}
+	mismatch := func() (any, bool) {
+		field := path
+		if field == "" {
+			field = "$"
+		}
+		*events = append(*events, driftEvent{Field: field, Reason: driftMismatch})
+		return nil, false
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				checkDrift(m, f.Type, path, events) // promoted fields
+				continue
+			}
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fv, present := m[name]
+			if !present {
+				*events = append(*events, driftEvent{Field: joinDriftPath(path, name), Reason: driftMissing})
+				continue
+			}
+			if _, ok := checkDrift(fv, f.Type, joinDriftPath(path, name), events); !ok {
+				delete(m, name)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		a, ok := v.([]any)
+		if !ok {
+			return mismatch()
+		}
+		for i, item := range a {
+			if _, ok := checkDrift(item, t.Elem(), path+"[]", events); !ok {
+				a[i] = nil
+			}
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		for k, item := range m {
+			if _, ok := checkDrift(item, t.Elem(), joinDriftPath(path, "*"), events); !ok {
+				delete(m, k)
+			}
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			return mismatch()
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return mismatch()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := v.(float64); !ok || f != float64(int64(f)) {
+			return mismatch()
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(float64); !ok {
+			return mismatch()
+		}
+	}
+	return v, true
+}
+
+func joinDriftPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// upstreamKind names the kind of payload an upstream path returns:
+// "/pokemon/pikachu" is "pokemon", "/pokemon?limit=20" is "pokemon-list".
+func upstreamKind(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) == 1 {
+		return parts[0] + "-list"
+	}
+	return parts[0]
+}
+
+// driftEntry aggregates one kind of drift for the admin report.
+type driftEntry struct {
+	Kind      string    `json:"kind"`
+	Field     string    `json:"field"`
+	Reason    string    `json:"reason"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Example   string    `json:"example"` // last upstream path it was seen on
+}
+
+// driftMonitor records how upstream payloads drift from the structs we
+// decode them into. Unknown fields are judged against a per-kind baseline:
+// the top-level keys of the first payload of that kind.
+type driftMonitor struct {
+	mu        sync.Mutex
+	baselines map[string]map[string]bool
+	entries   map[[3]string]*driftEntry
+	m         *metrics
+}
+
+func newDriftMonitor(m *metrics) *driftMonitor {
+	return &driftMonitor{baselines: map[string]map[string]bool{}, entries: map[[3]string]*driftEntry{}, m: m}
+}
+
+// decode decodes an upstream body tolerantly (see decodeTolerant) and records
+// any drift. A nil monitor decodes without recording.
+func (d *driftMonitor) decode(path string, body []byte, out any) error {
+	events, keys, err := decodeTolerant(body, out)
+	if d == nil || (err != nil && !errors.Is(err, errDriftShape)) {
+		return err
+	}
+	d.record(path, events, keys)
+	return err
+}
+
+func (d *driftMonitor) record(path string, events []driftEvent, keys []string) {
+	kind := upstreamKind(path)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if base, ok := d.baselines[kind]; !ok {
+		base = make(map[string]bool, len(keys))
+		for _, k := range keys {
+			base[k] = true
+		}
+		d.baselines[kind] = base
+	} else {
+		for _, k := range keys {
+			if !base[k] {
+				events = append(events, driftEvent{Field: k, Reason: driftUnknown})
+			}
+		}
+	}
+	now := time.Now()
+	for _, e := range events {
+		key := [3]string{kind, e.Field, e.Reason}
+		entry, ok := d.entries[key]
+		if !ok {
+			if len(d.entries) >= maxDriftEntries {
+				continue
+			}
+			log.Printf("upstream drift: kind=%s field=%s reason=%s path=%s", kind, e.Field, e.Reason, path)
+			entry = &driftEntry{Kind: kind, Field: e.Field, Reason: e.Reason, FirstSeen: now}
+			d.entries[key] = entry
+		}
+		entry.Count++
+		entry.LastSeen = now
+		entry.Example = path
+		d.m.upstreamDriftTotal.WithLabelValues(kind, e.Reason).Inc()
+	}
+}
+
+// report returns the drift entries by kind and field.
+func (d *driftMonitor) report() []driftEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]driftEntry, 0, len(d.entries))
+	for _, e := range d.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Field != out[j].Field {
+			return out[i].Field < out[j].Field
+		}
+		return out[i].Reason < out[j].Reason
+	})
+	return out
+}
+
+// adminDriftHandler serves GET /admin/upstream/drift: every field that has
+// drifted from what we decode, per upstream kind.
+func (s *Server) adminDriftHandler(c *gin.Context) {
+	entries := []driftEntry{}
+	if s.drift != nil {
+		entries = s.drift.report()
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
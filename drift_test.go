@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecodeTolerant(t *testing.T) {
+	var p pokemonDetail
+	events, _, err := decodeTolerant([]byte(`{"id":25,"name":"pikachu","height":"four","weight":60,
+		"types":[{"slot":1,"type":{"name":"electric"}},"fire"],"stats":[],"abilities":[],"species":{"name":"pikachu"}}`), &p)
+	if err != nil {
+		t.Fatalf("expected drift to be tolerated, got %v", err)
+	}
+	if p.Name != "pikachu" || p.Weight != 60 || p.Height != 0 || len(p.Types) != 2 || p.Types[0].Type.Name != "electric" {
+		t.Fatalf("unexpected decode %+v", p)
+	}
+	got := map[driftEvent]bool{}
+	for _, e := range events {
+		got[e] = true
+	}
+	for _, want := range []driftEvent{
+		{Field: "height", Reason: driftMismatch},
+		{Field: "types[]", Reason: driftMismatch},
+		{Field: "base_experience", Reason: driftMissing},
+	} {
+		if !got[want] {
+			t.Errorf("expected %+v in %+v", want, events)
+		}
+	}
+
+	if _, _, err := decodeTolerant([]byte(`[1,2]`), &p); err != errDriftShape {
+		t.Fatalf("expected a shape error for an array, got %v", err)
+	}
+	if _, _, err := decodeTolerant([]byte(`{"name":`), &p); err == nil {
+		t.Fatal("expected invalid JSON to fail")
+	}
+}
+
+func TestUpstreamKind(t *testing.T) {
+	for path, want := range map[string]string{
+		"/pokemon/pikachu":           "pokemon",
+		"/pokemon?limit=20&offset=0": "pokemon-list",
+		"/evolution-chain/10":        "evolution-chain",
+	} {
+		if got := upstreamKind(path); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestUpstreamDriftDegradesGracefully(t *testing.T) {
+	// the fields of pokemonDetail other than name, height, weight and base_experience
+	const rest = `"id":25,"species":{"name":"pikachu","url":""},"abilities":[],"types":[],"stats":[],` +
+		`"sprites":{"front_default":"","other":{"official-artwork":{"front_default":""}}}`
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu":   `{"name":"pikachu","height":4,"weight":60,"base_experience":112,` + rest + `}`,
+		"/pokemon/raichu":    `{"name":"raichu","height":8,"weight":"300","base_experience":218,"cries":{},` + rest + `}`,
+		"/pokemon/missingno": `["not","a","pokemon"]`,
+	})
+	s := newTestServer(ts.URL)
+	s.metrics = newMetrics(prometheus.NewRegistry())
+	s.drift = newDriftMonitor(s.metrics)
+	r := setupRouter(withAdminKey(t, s))
+
+	// pikachu comes first and sets the baseline
+	for _, path := range []string{"/pokemon/pikachu", "/pokemon/raichu"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected a wrongly shaped payload to fail, got %d", w.Code)
+	}
+
+	if got := testutil.ToFloat64(s.metrics.upstreamDriftTotal.WithLabelValues("pokemon", driftMismatch)); got != 2 {
+		t.Fatalf("expected 2 type mismatches, got %v", got)
+	}
+	if got := testutil.ToFloat64(s.metrics.upstreamDriftTotal.WithLabelValues("pokemon", driftUnknown)); got != 1 {
+		t.Fatalf("expected 1 unknown field, got %v", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/upstream/drift", nil)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	entries := s.drift.report()
+	if len(entries) != 3 || entries[0].Field != "$" || entries[1].Field != "cries" || entries[2].Field != "weight" || entries[2].Example != "/pokemon/raichu" {
+		t.Fatalf("unexpected drift report %+v", entries)
+	}
+}
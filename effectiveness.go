@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// maxDefenderTypes is the number of types a pokemon can have.
+const maxDefenderTypes = 2
+
+type effectivenessEntry struct {
+	Type       string  `json:"type"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// effectivenessResponse is the response of GET /effectiveness.
+type effectivenessResponse struct {
+	Attacker      string               `json:"attacker"`
+	Defender      []string             `json:"defender"`
+	Multiplier    float64              `json:"multiplier"`
+	Effectiveness string               `json:"effectiveness"`
+	Breakdown     []effectivenessEntry `json:"breakdown"`
+}
+
+// damageFrom returns the multiplier of an attacking type against the
+// defending type t: 2, 0.5, 0 or 1.
+func (t typeDetail) damageFrom(attacker string) float64 {
+	has := func(rs []namedResource) bool {
+		return slices.ContainsFunc(rs, func(r namedResource) bool { return r.Name == attacker })
+	}
+	switch {
+	case has(t.DamageRelations.NoDamageFrom):
+		return 0
+	case has(t.DamageRelations.DoubleDamageFrom):
+		return 2
+	case has(t.DamageRelations.HalfDamageFrom):
+		return 0.5
+	}
+	return 1
+}
+
+// effectivenessLabel names a combined multiplier the way the games do.
+func effectivenessLabel(m float64) string {
+	switch {
+	case m == 0:
+		return "no_effect"
+	case m < 1:
+		return "not_very_effective"
+	case m > 1:
+		return "super_effective"
+	}
+	return "normal"
+}
+
+// effectivenessHandler serves GET /effectiveness?attacker=fire&defender=grass,steel:
+// the damage multiplier of a move of the attacking type against a pokemon of
+// the defending types, computed from the cached type chart. Multipliers of
+// dual types combine (2 x 2 = 4, 2 x 0.5 = 1).
+func (s *Server) effectivenessHandler(c *gin.Context) {
+	attacker := strings.ToLower(strings.TrimSpace(c.Query("attacker")))
+	defender := splitList(strings.ToLower(c.Query("defender")))
+	if attacker == "" || len(defender) == 0 {
+		writeError(c, apierror.BadRequest("attacker and defender are required"))
+		return
+	}
+	if len(defender) > maxDefenderTypes {
+		writeError(c, apierror.BadRequest("a defender has at most 2 types"))
+		return
+	}
+	if len(defender) == 2 && defender[0] == defender[1] {
+		writeError(c, apierror.BadRequest("defender types must differ"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	// the attacker's own row is not needed for the math, but an unknown
+	// attacking type must not pass as neutral
+	if _, status, err := s.fetchType(ctx, attacker); err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "type not found: "+attacker))
+		return
+	}
+	resp := effectivenessResponse{Attacker: attacker, Defender: defender, Multiplier: 1, Breakdown: make([]effectivenessEntry, len(defender))}
+	for i, name := range defender {
+		t, status, err := s.fetchType(ctx, name)
+		if err != nil {
+			writeError(c, apierror.FromUpstream(status, err, "type not found: "+name))
+			return
+		}
+		m := t.damageFrom(attacker)
+		resp.Breakdown[i] = effectivenessEntry{Type: name, Multiplier: m}
+		resp.Multiplier *= m
+	}
+	resp.Effectiveness = effectivenessLabel(resp.Multiplier)
+	c.JSON(http.StatusOK, resp)
+}
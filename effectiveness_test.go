@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEffectivenessEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/type/fire": `{"name":"fire","damage_relations":{"double_damage_to":[{"name":"grass"},{"name":"steel"}]}}`,
+		"/type/grass": `{"name":"grass","damage_relations":{"double_damage_from":[{"name":"fire"}],
+			"half_damage_from":[{"name":"water"}]}}`,
+		"/type/steel": `{"name":"steel","damage_relations":{"double_damage_from":[{"name":"fire"}],
+			"no_damage_from":[{"name":"poison"}]}}`,
+		"/type/water":  `{"name":"water","damage_relations":{"half_damage_from":[{"name":"fire"}]}}`,
+		"/type/poison": `{"name":"poison","damage_relations":{}}`,
+	})
+	s := newTestServer(ts.URL)
+	s.types = newTTLCache[typeDetail](time.Minute)
+	r := setupRouter(s)
+
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ query, want string }{
+		{"attacker=fire&defender=grass,steel", `{"attacker":"fire","defender":["grass","steel"],"multiplier":4,"effectiveness":"super_effective",` +
+			`"breakdown":[{"type":"grass","multiplier":2},{"type":"steel","multiplier":2}]}`},
+		{"attacker=Fire&defender=water", `{"attacker":"fire","defender":["water"],"multiplier":0.5,"effectiveness":"not_very_effective",` +
+			`"breakdown":[{"type":"water","multiplier":0.5}]}`},
+		{"attacker=water&defender=grass,steel", `{"attacker":"water","defender":["grass","steel"],"multiplier":0.5,"effectiveness":"not_very_effective",` +
+			`"breakdown":[{"type":"grass","multiplier":0.5},{"type":"steel","multiplier":1}]}`},
+		{"attacker=poison&defender=steel,grass", `{"attacker":"poison","defender":["steel","grass"],"multiplier":0,"effectiveness":"no_effect",` +
+			`"breakdown":[{"type":"steel","multiplier":0},{"type":"grass","multiplier":1}]}`},
+		{"attacker=fire&defender=poison", `{"attacker":"fire","defender":["poison"],"multiplier":1,"effectiveness":"normal",` +
+			`"breakdown":[{"type":"poison","multiplier":1}]}`},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/effectiveness?"+tc.query, nil))
+		if w.Code != http.StatusOK || w.Body.String() != tc.want {
+			t.Fatalf("%s: expected 200 %s, got %d %s", tc.query, tc.want, w.Code, w.Body)
+		}
+		if errs := rs.validate(rs.schemaFor("/effectiveness", http.StatusOK), w.Body.Bytes()); len(errs) > 0 {
+			t.Fatalf("%s: response violates its schema: %v", tc.query, errs)
+		}
+	}
+
+	for query, want := range map[string]int{
+		"defender=grass": http.StatusBadRequest,
+		"attacker=fire":  http.StatusBadRequest,
+		"attacker=fire&defender=grass,steel,water": http.StatusBadRequest,
+		"attacker=fire&defender=grass,grass":       http.StatusBadRequest,
+		"attacker=fire&defender=shadow":            http.StatusNotFound,
+		"attacker=shadow&defender=grass":           http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/effectiveness?"+query, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", query, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// egressPolicy restricts where the upstream client may connect. Hosts, when
+// listed, must match exactly; resolved addresses in private, loopback,
+// link-local and other non-public ranges are refused unless allowPrivate is
+// set or they fall within an explicitly allowed network. Addresses are checked
+// at dial time, so a name that later resolves somewhere else is still caught.
+type egressPolicy struct {
+	hosts        map[string]bool // empty = any host
+	allowPrivate bool
+	allowedNets  []*net.IPNet
+}
+
+func newEgressPolicy(hosts []string, allowPrivate bool, cidrs []string) (*egressPolicy, error) {
+	p := &egressPolicy{hosts: make(map[string]bool), allowPrivate: allowPrivate}
+	for _, h := range hosts {
+		p.hosts[strings.ToLower(h)] = true
+	}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("egress: invalid network %q: %w", cidr, err)
+		}
+		p.allowedNets = append(p.allowedNets, n)
+	}
+	return p, nil
+}
+
+// checkURL validates the scheme and host of an outbound URL. Literal IP hosts
+// are checked against the address rules as well.
+func (p *egressPolicy) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("egress: scheme %q not allowed", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(p.hosts) > 0 && !p.hosts[host] {
+		return fmt.Errorf("egress: host %q not allowed", host)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+	return nil
+}
+
+// checkIP refuses non-public addresses unless explicitly allowed.
+func (p *egressPolicy) checkIP(ip net.IP) error {
+	for _, n := range p.allowedNets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	if p.allowPrivate {
+		return nil
+	}
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("egress: address %s is not a public address", ip)
+	}
+	return nil
+}
+
+// dialControl runs after name resolution, right before connecting.
+func (p *egressPolicy) dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errors.New("egress: unresolved address " + address)
+	}
+	return p.checkIP(ip)
+}
+
+// apply installs the policy on client: every connection is vetted at dial
+// time and every redirect target is validated before it is followed. Hop
+// limits are left to the redirect policy.
+func (p *egressPolicy) apply(client *http.Client) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: p.dialControl}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	client.Transport = t
+	client.CheckRedirect = func(req *http.Request, _ []*http.Request) error {
+		return p.checkURL(req.URL)
+	}
+}
+
+// checkBaseURLs validates configured upstream base URLs at startup; empty
+// values (disabled features) are skipped.
+func (p *egressPolicy) checkBaseURLs(raws ...string) error {
+	for _, raw := range raws {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("egress: invalid base URL %q: %w", raw, err)
+		}
+		if err := p.checkURL(u); err != nil {
+			return fmt.Errorf("base URL %s: %w", raw, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEgressPolicyAddresses(t *testing.T) {
+	p, err := newEgressPolicy(nil, false, []string{"10.1.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"127.0.0.1", "10.0.0.1", "192.168.1.1", "169.254.169.254", "::1", "fd00::1"} {
+		if p.checkIP(net.ParseIP(ip)) == nil {
+			t.Fatalf("expected %s to be refused", ip)
+		}
+	}
+	for _, ip := range []string{"104.21.0.1", "10.1.2.3"} {
+		if err := p.checkIP(net.ParseIP(ip)); err != nil {
+			t.Fatalf("expected %s to be allowed: %v", ip, err)
+		}
+	}
+	if _, err := newEgressPolicy(nil, false, []string{"nope"}); err == nil {
+		t.Fatal("expected invalid CIDR error")
+	}
+}
+
+func TestEgressPolicyBaseURLs(t *testing.T) {
+	p, _ := newEgressPolicy([]string{"pokeapi.co"}, false, nil)
+	if err := p.checkBaseURLs("https://pokeapi.co/api/v2", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, raw := range []string{"https://evil.example/api", "file:///etc/passwd"} {
+		if p.checkBaseURLs(raw) == nil {
+			t.Fatalf("expected %s to be refused", raw)
+		}
+	}
+}
+
+func TestEgressPolicyBlocksLoopbackAndRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer target.Close()
+
+	client := &http.Client{}
+	p, _ := newEgressPolicy(nil, false, nil)
+	p.apply(client)
+	if _, err := client.Get(target.URL); err == nil {
+		t.Fatal("expected loopback dial to be refused")
+	}
+
+	// allow the redirecting server itself but not the host it redirects to
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://internal.example/", http.StatusFound)
+	}))
+	defer redirector.Close()
+	client = &http.Client{}
+	p, _ = newEgressPolicy([]string{"127.0.0.1"}, true, nil)
+	p.apply(client)
+	if _, err := client.Get(redirector.URL); err == nil {
+		t.Fatal("expected redirect to a non-allowed host to be refused")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// encodedBody is a cached response body with its pre-compressed variant, so
+// the compression is paid once per cache fill rather than on every request.
+type encodedBody struct {
+	ContentType string
+	Body        []byte
+	Gzip        []byte // nil when gzip does not make Body noticeably smaller
+}
+
+func newEncodedBody(contentType string, body []byte) encodedBody {
+	return encodedBody{ContentType: contentType, Body: body, Gzip: gzipVariant(body)}
+}
+
+// gzipVariant returns body compressed with gzip, or nil when that saves less
+// than a tenth of its size (already compressed images, tiny bodies).
+func gzipVariant(body []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	_, _ = zw.Write(body)
+	if err := zw.Close(); err != nil || buf.Len() > len(body)-len(body)/10 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip: listed
+// with a non-zero quality, or covered by a non-zero "*" without being
+// refused by name.
+func acceptsGzip(header string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		switch coding {
+		case "gzip", "x-gzip":
+			return q > 0
+		case "*":
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
+
+// write answers with b, gzip-encoded when the client accepts it and a
+// variant exists. The response varies on Accept-Encoding either way.
+func (b encodedBody) write(c *gin.Context, status int) {
+	c.Writer.Header().Add("Vary", "Accept-Encoding")
+	if b.Gzip != nil && acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(status, b.ContentType, b.Gzip)
+		return
+	}
+	c.Data(status, b.ContentType, b.Body)
+}
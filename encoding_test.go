@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                     false,
+		"gzip":                 true,
+		"br, gzip;q=0.5":       true,
+		"gzip;q=0":             false,
+		"*":                    true,
+		"*;q=0.1, gzip;q=0":    false,
+		"identity":             false,
+		"deflate, br;q=1.0":    false,
+		"GZIP;q=0.8, identity": true,
+	} {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("%q: expected %v, got %v", header, want, got)
+		}
+	}
+}
+
+func TestEncodedBodyVariants(t *testing.T) {
+	text := []byte(strings.Repeat(`{"name":"spicy"},`, 100))
+	if b := newEncodedBody("application/json", text); b.Gzip == nil || len(b.Gzip) >= len(text) {
+		t.Fatal("expected a gzip variant for compressible text")
+	}
+	if b := newEncodedBody("image/png", fakePNG); b.Gzip != nil {
+		t.Fatal("expected no gzip variant when compression does not pay off")
+	}
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestProxyServesPrecompressedVariant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	payload := `{"name":"spicy","flavors":[` + strings.Repeat(`{"potency":10,"berry":{"url":"https://pokeapi.co/api/v2/berry/1/"}},`, 50) + `{}]}`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.WriteString(w, payload)
+	}))
+	defer upstream.Close()
+	s := &Server{
+		httpClient: upstream.Client(),
+		baseURL:    upstream.URL,
+		cache:      newPokemonCache(0),
+		metrics:    newMetrics(prometheus.NewRegistry()),
+		proxy:      newReverseProxy([]string{"berry-flavor"}, "https://facade.example", newTTLCache[encodedBody](time.Minute)),
+	}
+	r := setupRouter(s)
+
+	get := func(acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/berry-flavor/spicy", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	want := strings.ReplaceAll(payload, "https://pokeapi.co/api/v2", "https://facade.example")
+	w := get("gzip, br")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected a gzip response varying on Accept-Encoding, got %d %v", w.Code, w.Header())
+	}
+	if got := gunzip(t, w.Body.Bytes()); got != want {
+		t.Fatalf("unexpected decompressed body %s", got)
+	}
+	w = get("")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != want || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected the identity body, got %v %s", w.Header(), w.Body)
+	}
+	if calls != 1 {
+		t.Fatalf("expected both encodings to come from one cache entry, got %d upstream calls", calls)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// encounterArea is one element of the upstream /pokemon/:name/encounters
+// payload: a location area and how the pokemon can be met there, per game
+// version.
+type encounterArea struct {
+	LocationArea   namedResource `json:"location_area"`
+	VersionDetails []struct {
+		Version          namedResource `json:"version"`
+		MaxChance        int           `json:"max_chance"`
+		EncounterDetails []struct {
+			MinLevel        int             `json:"min_level"`
+			MaxLevel        int             `json:"max_level"`
+			Chance          int             `json:"chance"`
+			Method          namedResource   `json:"method"`
+			ConditionValues []namedResource `json:"condition_values"`
+		} `json:"encounter_details"`
+	} `json:"version_details"`
+}
+
+type encounterMethod struct {
+	Method     string   `json:"method"`
+	MinLevel   int      `json:"min_level"`
+	MaxLevel   int      `json:"max_level"`
+	Chance     int      `json:"chance"`
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+type encounterVersion struct {
+	Version   string            `json:"version"`
+	MaxChance int               `json:"max_chance"`
+	Methods   []encounterMethod `json:"methods"`
+}
+
+type encounterLocation struct {
+	LocationArea string             `json:"location_area"`
+	Versions     []encounterVersion `json:"versions"`
+}
+
+// encountersResponse is the response of GET /pokemon/:name/encounters.
+type encountersResponse struct {
+	Name      string              `json:"name"`
+	Locations []encounterLocation `json:"locations"`
+}
+
+// fetchEncounters returns where a pokemon can be encountered, via the
+// s.encounters cache.
+func (s *Server) fetchEncounters(ctx context.Context, name string) ([]encounterArea, int, error) {
+	return fetchCached(ctx, s, s.encounters, name, "/pokemon/"+name+"/encounters")
+}
+
+// encountersHandler serves GET /pokemon/:name/encounters?version=red: the
+// location areas where the pokemon can be found in the wild, with the
+// methods, levels and chances per game version. With version, only that
+// game's encounters are listed. A pokemon that cannot be met in the wild has
+// no locations.
+func (s *Server) encountersHandler(c *gin.Context) {
+	name := c.Param("name")
+	areas, status, err := s.fetchEncounters(c.Request.Context(), name)
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+		return
+	}
+	version := c.Query("version")
+	resp := encountersResponse{Name: name, Locations: []encounterLocation{}}
+	for _, a := range areas {
+		loc := encounterLocation{LocationArea: a.LocationArea.Name}
+		for _, vd := range a.VersionDetails {
+			if version != "" && vd.Version.Name != version {
+				continue
+			}
+			v := encounterVersion{Version: vd.Version.Name, MaxChance: vd.MaxChance, Methods: make([]encounterMethod, len(vd.EncounterDetails))}
+			for i, ed := range vd.EncounterDetails {
+				m := encounterMethod{Method: ed.Method.Name, MinLevel: ed.MinLevel, MaxLevel: ed.MaxLevel, Chance: ed.Chance}
+				for _, cv := range ed.ConditionValues {
+					m.Conditions = append(m.Conditions, cv.Name)
+				}
+				v.Methods[i] = m
+			}
+			loc.Versions = append(loc.Versions, v)
+		}
+		if len(loc.Versions) > 0 {
+			resp.Locations = append(resp.Locations, loc)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
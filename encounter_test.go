@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEncountersEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu/encounters": `[
+			{"location_area":{"name":"viridian-forest-area"},"version_details":[
+				{"version":{"name":"red"},"max_chance":5,"encounter_details":[
+					{"min_level":3,"max_level":5,"chance":5,"method":{"name":"walk"},"condition_values":[]}]},
+				{"version":{"name":"yellow"},"max_chance":10,"encounter_details":[
+					{"min_level":3,"max_level":4,"chance":10,"method":{"name":"walk"},"condition_values":[{"name":"time-day"}]}]}]},
+			{"location_area":{"name":"power-plant-area"},"version_details":[
+				{"version":{"name":"yellow"},"max_chance":25,"encounter_details":[
+					{"min_level":20,"max_level":24,"chance":25,"method":{"name":"walk"},"condition_values":[]}]}]}]`,
+		"/pokemon/mew/encounters": `[]`,
+	})
+	s := newTestServer(ts.URL)
+	s.encounters = newTTLCache[[]encounterArea](time.Minute)
+	r := setupRouter(s)
+
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ path, want string }{
+		{"/pokemon/pikachu/encounters?version=red", `{"name":"pikachu","locations":[{"location_area":"viridian-forest-area",` +
+			`"versions":[{"version":"red","max_chance":5,"methods":[{"method":"walk","min_level":3,"max_level":5,"chance":5}]}]}]}`},
+		{"/pokemon/pikachu/encounters?version=yellow", `{"name":"pikachu","locations":[{"location_area":"viridian-forest-area",` +
+			`"versions":[{"version":"yellow","max_chance":10,"methods":[{"method":"walk","min_level":3,"max_level":4,"chance":10,"conditions":["time-day"]}]}]},` +
+			`{"location_area":"power-plant-area","versions":[{"version":"yellow","max_chance":25,"methods":[{"method":"walk","min_level":20,"max_level":24,"chance":25}]}]}]}`},
+		{"/pokemon/mew/encounters", `{"name":"mew","locations":[]}`},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != tc.want {
+			t.Fatalf("%s: expected 200 %s, got %d %s", tc.path, tc.want, w.Code, w.Body)
+		}
+		if errs := rs.validate(rs.schemaFor("/pokemon/:name/encounters", http.StatusOK), w.Body.Bytes()); len(errs) > 0 {
+			t.Fatalf("%s: response violates its schema: %v", tc.path, errs)
+		}
+	}
+	if v, ok := s.encounters.get("pikachu"); !ok || len(v) != 2 {
+		t.Fatalf("expected the encounters to be cached, got %v %v", v, ok)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno/encounters", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+	"ci_education/storage"
+)
+
+const (
+	// maxErasureJobs is how many erasure jobs are kept for status queries.
+	maxErasureJobs = 100
+	// erasureTimeout bounds one erasure, webhook excluded.
+	erasureTimeout = time.Minute
+)
+
+// erasureJob is one DELETE /me/data request, shared between the worker and
+// status readers.
+type erasureJob struct {
+	mu         sync.Mutex
+	id         string
+	owner      string
+	startedAt  time.Time
+	finishedAt time.Time
+	err        string
+	erased     *storage.Erasure
+}
+
+// erasureJobStatus is the JSON view of an erasure job, also the body POSTed
+// to the completion webhook.
+type erasureJobStatus struct {
+	ID         string           `json:"id"`
+	Owner      string           `json:"owner"`
+	State      string           `json:"state"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Erased     *storage.Erasure `json:"erased,omitempty"`
+}
+
+func newErasureJob(owner string) *erasureJob {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &erasureJob{id: hex.EncodeToString(b), owner: owner, startedAt: time.Now()}
+}
+
+func (j *erasureJob) finish(e storage.Erasure, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finishedAt = time.Now()
+	if err != nil {
+		j.err = "erasure failed"
+		return
+	}
+	j.erased = &e
+}
+
+func (j *erasureJob) status() erasureJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := erasureJobStatus{ID: j.id, Owner: j.owner, State: "running", StartedAt: j.startedAt, Error: j.err, Erased: j.erased}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		st.FinishedAt = &finished
+		st.State = "done"
+		if j.err != "" {
+			st.State = "failed"
+		}
+	}
+	return st
+}
+
+// erasureWebhook POSTs finished erasure jobs to an operator-configured URL.
+type erasureWebhook struct {
+	client *http.Client
+	url    string
+}
+
+// newErasureWebhook returns nil (no notifications) when url is empty.
+func newErasureWebhook(url string, timeout time.Duration) *erasureWebhook {
+	if url == "" {
+		return nil
+	}
+	return &erasureWebhook{client: &http.Client{Timeout: timeout}, url: url}
+}
+
+func (w *erasureWebhook) notify(st erasureJobStatus) error {
+	if w == nil {
+		return nil
+	}
+	body, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runErasure erases the job owner's data, drops their export jobs so
+// finished exports can no longer be downloaded, writes the audit record and
+// notifies the completion webhook.
+func (s *Server) runErasure(job *erasureJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), erasureTimeout)
+	defer cancel()
+	e, err := s.store.EraseOwner(ctx, job.owner)
+	var exports int
+	if err == nil {
+		exports = s.takeouts.removeIf(func(t *takeoutJob) bool { return t.owner == job.owner })
+	}
+	job.finish(e, err)
+	if err != nil {
+		log.Printf("audit: erasure job=%s owner=%s failed: %v", job.id, job.owner, err)
+	} else {
+		log.Printf("audit: erasure job=%s owner=%s done teams=%d favorites=%d usage_days=%d exports=%d",
+			job.id, job.owner, e.Teams, e.Favorites, e.UsageDays, exports)
+	}
+	if err := s.erasureWebhook.notify(job.status()); err != nil {
+		log.Printf("erasure: job %s: notifying webhook: %v", job.id, err)
+	}
+}
+
+// meDeleteDataHandler serves DELETE /me/data: it starts erasing everything
+// stored for the caller's owner and answers 202 with the job, to poll at
+// GET /me/data/erasures/:id. API keys survive so the caller can poll.
+func (s *Server) meDeleteDataHandler(c *gin.Context) {
+	p := principal(c)
+	job := newErasureJob(p.Owner)
+	rid, _ := c.Get("request_id")
+	log.Printf("audit: erasure job=%s owner=%s requested rid=%v impersonator=%q", job.id, p.Owner, rid, impersonator(c))
+	s.erasures.add(job.id, job, maxErasureJobs)
+	go s.runErasure(job)
+	c.Header("Location", "/me/data/erasures/"+job.id)
+	c.JSON(http.StatusAccepted, job.status())
+}
+
+// meErasureStatusHandler serves GET /me/data/erasures/:id for the caller's
+// own jobs.
+func (s *Server) meErasureStatusHandler(c *gin.Context) {
+	job, ok := s.erasures.get(c.Param("id"))
+	if !ok || job.owner != principal(c).Owner {
+		writeError(c, apierror.NotFound("erasure job not found"))
+		return
+	}
+	c.JSON(http.StatusOK, job.status())
+}
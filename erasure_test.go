@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ci_education/storage"
+)
+
+func TestMeDeleteData(t *testing.T) {
+	notified := make(chan erasureJobStatus, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var st erasureJobStatus
+		json.NewDecoder(r.Body).Decode(&st)
+		notified <- st
+	}))
+	defer hook.Close()
+
+	s, r := newTeamsTestServer(t)
+	s.erasureWebhook = newErasureWebhook(hook.URL, time.Second)
+	ctx := context.Background()
+	s.store.CreateTeam(ctx, storage.Team{Owner: "ash", Name: "kanto", Members: []string{"pikachu"}})
+	s.store.CreateTeam(ctx, storage.Team{Owner: "gary", Name: "rival", Members: []string{"eevee"}})
+	s.store.AddFavorite(ctx, "ash", "mew")
+	ashExport, garyExport := newTakeoutJob("ash"), newTakeoutJob("gary")
+	ashExport.finish(takeout{Owner: "ash"}, nil)
+	garyExport.finish(takeout{Owner: "gary"}, nil)
+	s.takeouts.add(ashExport.id, ashExport, maxTakeoutJobs)
+	s.takeouts.add(garyExport.id, garyExport, maxTakeoutJobs)
+
+	w := doTeams(r, http.MethodDelete, "/me/data", "ash-key", "")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	var st erasureJobStatus
+	json.Unmarshal(w.Body.Bytes(), &st)
+
+	select {
+	case got := <-notified:
+		if got.ID != st.ID || got.State != "done" || got.Erased == nil || *got.Erased != (storage.Erasure{Teams: 1, Favorites: 1}) {
+			t.Fatalf("unexpected webhook payload %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the completion webhook to be called")
+	}
+
+	path := "/me/data/erasures/" + st.ID
+	if w := doTeams(r, http.MethodGet, path, "gary-key", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected other owners to get 404, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodGet, path, "ash-key", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if teams, _ := s.store.ListTeams(ctx, "ash", true); len(teams) != 0 {
+		t.Fatalf("expected ash's teams to be erased, got %+v", teams)
+	}
+	if teams, _ := s.store.ListTeams(ctx, "gary", false); len(teams) != 1 {
+		t.Fatalf("expected gary's teams to be kept, got %+v", teams)
+	}
+	if w := doTeams(r, http.MethodGet, "/me/export/"+ashExport.id, "ash-key", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected ash's finished export to be purged, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodGet, "/me/export/"+garyExport.id, "gary-key", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected gary's export to be kept, got %d", w.Code)
+	}
+}
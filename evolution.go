@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// chainLink is one node of an upstream evolution chain: a species, how it is
+// reached from its parent, and what it evolves into.
+type chainLink struct {
+	Species          namedResource `json:"species"`
+	EvolutionDetails []struct {
+		MinLevel *int          `json:"min_level"`
+		Trigger  namedResource `json:"trigger"`
+		Item     namedResource `json:"item"`
+	} `json:"evolution_details"`
+	EvolvesTo []chainLink `json:"evolves_to"`
+}
+
+// evolutionChain is the subset of the upstream evolution-chain payload we use.
+type evolutionChain struct {
+	ID    int       `json:"id"`
+	Chain chainLink `json:"chain"`
+}
+
+// evolutionChainPath turns a species' evolution_chain URL into an upstream
+// path, e.g. ".../api/v2/evolution-chain/10/" into "/evolution-chain/10".
+func evolutionChainPath(url string) (string, bool) {
+	i := strings.LastIndex(url, "/evolution-chain/")
+	if i < 0 {
+		return "", false
+	}
+	id := strings.Trim(url[i+len("/evolution-chain/"):], "/")
+	if id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return "/evolution-chain/" + id, true
+}
+
+// fetchEvolutionChain returns the chain a species links to, via the s.chains
+// cache keyed by upstream path.
+func (s *Server) fetchEvolutionChain(ctx context.Context, sp speciesDetail) (evolutionChain, int, error) {
+	path, ok := evolutionChainPath(sp.EvolutionChain.URL)
+	if !ok {
+		return evolutionChain{}, http.StatusNotFound, errors.New("species has no evolution chain")
+	}
+	return fetchCached(ctx, s, s.chains, path, path)
+}
+
+// evolutionStage is one species of a flattened chain. Stage counts from 1
+// for the base species; the remaining fields describe how it is reached from
+// EvolvesFrom and are empty for the base species.
+type evolutionStage struct {
+	Name        string `json:"name"`
+	Stage       int    `json:"stage"`
+	EvolvesFrom string `json:"evolves_from,omitempty"`
+	Trigger     string `json:"trigger,omitempty"`
+	MinLevel    *int   `json:"min_level,omitempty"`
+	Item        string `json:"item,omitempty"`
+}
+
+// flattenChain lists the chain's species depth first, so every stage comes
+// after the one it evolves from.
+func flattenChain(root chainLink) []evolutionStage {
+	var out []evolutionStage
+	var walk func(l chainLink, from string, stage int)
+	walk = func(l chainLink, from string, stage int) {
+		st := evolutionStage{Name: l.Species.Name, Stage: stage, EvolvesFrom: from}
+		if len(l.EvolutionDetails) > 0 {
+			d := l.EvolutionDetails[0]
+			st.Trigger, st.MinLevel, st.Item = d.Trigger.Name, d.MinLevel, d.Item.Name
+		}
+		out = append(out, st)
+		for _, next := range l.EvolvesTo {
+			walk(next, l.Species.Name, stage+1)
+		}
+	}
+	walk(root, "", 1)
+	return out
+}
+
+// evolutionResponse is the document served by /pokemon/:name/evolution. When
+// the species or its chain cannot be fetched, Stages is empty and the
+// failure is listed in Errors.
+type evolutionResponse struct {
+	Name    string               `json:"name"`
+	Species string               `json:"species"`
+	ChainID int                  `json:"chain_id,omitempty"`
+	Stages  []evolutionStage     `json:"stages"`
+	Partial bool                 `json:"partial"`
+	Errors  map[string]partError `json:"errors,omitempty"`
+}
+
+// evolutionHandler serves GET /pokemon/:name/evolution: pokemon, species and
+// evolution chain are fetched in turn, each through its cache, under the
+// request's context. The pokemon is required; species and chain failures
+// yield a partial document.
+func (s *Server) evolutionHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	p, status, err := s.fetchPokemonDetail(ctx, c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+		return
+	}
+	resp := evolutionResponse{Name: p.Name, Species: p.Species.Name, Stages: []evolutionStage{}, Errors: map[string]partError{}}
+	if resp.Species == "" {
+		resp.Species = p.Name
+	}
+
+	sp, status, err := s.fetchSpecies(ctx, resp.Species)
+	if err != nil {
+		resp.Errors["species"] = newPartError(status, err)
+	} else if chain, status, err := s.fetchEvolutionChain(ctx, sp); err != nil {
+		resp.Errors["evolution_chain"] = newPartError(status, err)
+	} else {
+		resp.ChainID = chain.ID
+		resp.Stages = flattenChain(chain.Chain)
+	}
+
+	resp.Partial = len(resp.Errors) > 0
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvolutionEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/eevee":         `{"id":133,"name":"eevee","species":{"name":"eevee"}}`,
+		"/pokemon/ditto":         `{"id":132,"name":"ditto","species":{"name":"ditto"}}`,
+		"/pokemon-species/eevee": `{"name":"eevee","evolution_chain":{"url":"https://pokeapi.co/api/v2/evolution-chain/67/"}}`,
+		"/pokemon-species/ditto": `{"name":"ditto","evolution_chain":{"url":"https://pokeapi.co/api/v2/evolution-chain/66/"}}`,
+		"/evolution-chain/67": `{"id":67,"chain":{"species":{"name":"eevee"},"evolution_details":[],"evolves_to":[
+			{"species":{"name":"vaporeon"},"evolution_details":[{"min_level":null,"trigger":{"name":"use-item"},"item":{"name":"water-stone"}}],"evolves_to":[]},
+			{"species":{"name":"umbreon"},"evolution_details":[{"min_level":null,"trigger":{"name":"level-up"},"item":null}],"evolves_to":[]}]}}`,
+	})
+	s := newTestServer(ts.URL)
+	s.chains = newTTLCache[evolutionChain](time.Minute)
+	r := setupRouter(s)
+
+	get := func(path string) evolutionResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body)
+		}
+		var resp evolutionResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("/pokemon/eevee/evolution")
+	want := []evolutionStage{
+		{Name: "eevee", Stage: 1},
+		{Name: "vaporeon", Stage: 2, EvolvesFrom: "eevee", Trigger: "use-item", Item: "water-stone"},
+		{Name: "umbreon", Stage: 2, EvolvesFrom: "eevee", Trigger: "level-up"},
+	}
+	if resp.Partial || resp.ChainID != 67 || !reflect.DeepEqual(resp.Stages, want) {
+		t.Fatalf("unexpected evolution %+v", resp)
+	}
+	if _, ok := s.chains.get("/evolution-chain/67"); !ok {
+		t.Fatal("expected the chain to be cached")
+	}
+
+	resp = get("/pokemon/ditto/evolution")
+	if !resp.Partial || len(resp.Stages) != 0 || resp.Errors["evolution_chain"].Code != "not_found" {
+		t.Fatalf("expected a partial document, got %+v", resp)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno/evolution", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestEvolutionChainPath(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://pokeapi.co/api/v2/evolution-chain/1/": "/evolution-chain/1",
+		"https://pokeapi.co/api/v2/evolution-chain/1":  "/evolution-chain/1",
+		"https://pokeapi.co/api/v2/pokemon/1/":         "",
+		"":                                             "",
+	} {
+		if got, _ := evolutionChainPath(url); got != want {
+			t.Errorf("evolutionChainPath(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// Expansions of /pokemon/:name, selected by ?expand=.
+const (
+	expandTypes     = "types"
+	expandAbilities = "abilities"
+	expandStats     = "stats"
+)
+
+var expandFields = []string{expandTypes, expandAbilities, expandStats}
+
+// expandedAbility is one ability slot of an expanded pokemon.
+type expandedAbility struct {
+	Name     string `json:"name"`
+	Slot     int    `json:"slot"`
+	IsHidden bool   `json:"is_hidden"`
+}
+
+// expandedPokemon is the /pokemon/:name response with ?expand=: the slim
+// pokemon (localized when a language is requested) plus the requested parts
+// of the upstream payload. Parts not requested are omitted.
+type expandedPokemon struct {
+	localizedPokemon
+	Types     []string          `json:"types,omitempty"`
+	Abilities []expandedAbility `json:"abilities,omitempty"`
+	Stats     map[string]int    `json:"stats,omitempty"`
+}
+
+// newPokemonCacheEntry keeps both the slim response and the payload it was
+// derived from.
+func newPokemonCacheEntry(d pokemonDetail) pokemonCacheEntry {
+	return pokemonCacheEntry{
+		pokemon: pokemonResponse{Name: d.Name, Height: d.Height, Weight: d.Weight, BaseExperience: d.BaseExperience},
+		detail:  &d,
+	}
+}
+
+// parseExpand parses a comma-separated ?expand= value into a set.
+func parseExpand(raw string) (map[string]bool, error) {
+	set := map[string]bool{}
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !slices.Contains(expandFields, f) {
+			return nil, fmt.Errorf("unknown expand field %q (want %s)", f, strings.Join(expandFields, ", "))
+		}
+		set[f] = true
+	}
+	return set, nil
+}
+
+// writeExpandedPokemon answers with e expanded by the fields in expand.
+// Entries cached without their payload (restored from an older snapshot)
+// fall back to the pokemon detail cache.
+func (s *Server) writeExpandedPokemon(c *gin.Context, name string, e pokemonCacheEntry, expand map[string]bool) {
+	d := e.detail
+	if d == nil {
+		fetched, status, err := s.fetchPokemonDetail(c.Request.Context(), name)
+		if err != nil {
+			writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+			return
+		}
+		d = &fetched
+	}
+
+	out := expandedPokemon{localizedPokemon: localizedPokemon{pokemonResponse: e.pokemon}}
+	if langs := requestedLanguages(c); len(langs) > 0 {
+		out.localizedPokemon = s.localizePokemon(c.Request.Context(), e.pokemon, langs)
+		if out.DisplayLanguage != "" {
+			c.Header("Content-Language", out.DisplayLanguage)
+		}
+	}
+	if expand[expandTypes] {
+		out.Types = d.typeNames()
+	}
+	if expand[expandAbilities] {
+		out.Abilities = make([]expandedAbility, len(d.Abilities))
+		for i, a := range d.Abilities {
+			out.Abilities[i] = expandedAbility{Name: a.Ability.Name, Slot: a.Slot, IsHidden: a.IsHidden}
+		}
+	}
+	if expand[expandStats] {
+		out.Stats = make(map[string]int, len(d.Stats))
+		for _, st := range d.Stats {
+			out.Stats[st.Stat.Name] = st.BaseStat
+		}
+	}
+	c.JSON(http.StatusOK, out)
+}
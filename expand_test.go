@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPokemonExpand(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pokemon/pikachu" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":25,"name":"pikachu","height":4,"weight":60,"base_experience":112,
+			"types":[{"slot":1,"type":{"name":"electric","url":""}}],
+			"abilities":[{"ability":{"name":"static","url":""},"is_hidden":false,"slot":1},
+				{"ability":{"name":"lightning-rod","url":""},"is_hidden":true,"slot":3}],
+			"stats":[{"base_stat":35,"stat":{"name":"hp","url":""}},{"base_stat":90,"stat":{"name":"speed","url":""}}]}`))
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	r := setupRouter(s)
+
+	get := func(target string) (*httptest.ResponseRecorder, map[string]any) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var body map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body
+	}
+
+	w, body := get("/pokemon/pikachu")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	for _, k := range []string{"types", "abilities", "stats", "display_name"} {
+		if _, ok := body[k]; ok {
+			t.Fatalf("default response should stay slim, got %s", w.Body)
+		}
+	}
+
+	w, body = get("/pokemon/pikachu?expand=types,stats")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if body["name"] != "pikachu" || !reflect.DeepEqual(body["types"], []any{"electric"}) ||
+		!reflect.DeepEqual(body["stats"], map[string]any{"hp": 35.0, "speed": 90.0}) {
+		t.Fatalf("unexpected expanded body %s", w.Body)
+	}
+	if _, ok := body["abilities"]; ok {
+		t.Fatalf("abilities were not requested: %s", w.Body)
+	}
+
+	w, body = get("/pokemon/pikachu?expand=abilities")
+	abilities, _ := body["abilities"].([]any)
+	if w.Code != http.StatusOK || len(abilities) != 2 ||
+		!reflect.DeepEqual(abilities[1], map[string]any{"name": "lightning-rod", "slot": 3.0, "is_hidden": true}) {
+		t.Fatalf("unexpected abilities in %s", w.Body)
+	}
+	if calls != 1 {
+		t.Fatalf("expected every expansion to be served from one cached fetch, got %d upstream calls", calls)
+	}
+
+	if w, _ := get("/pokemon/pikachu?expand=moves"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown expansion, got %d", w.Code)
+	}
+}
+
+func TestSnapshotKeepsPokemonPayload(t *testing.T) {
+	src := newPokemonCache(time.Hour)
+	src.set("pikachu", newPokemonCacheEntry(pokemonDetail{ID: 25, Name: "pikachu", Height: 4}))
+	dst := newPokemonCache(time.Hour)
+	for _, rec := range snapshotRecords(src) {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back snapshotRecord
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatal(err)
+		}
+		restoreRecord(dst, back, 0, time.Now())
+	}
+	v, ok := dst.get("pikachu")
+	if !ok || v.pokemon.Height != 4 || v.detail == nil || v.detail.ID != 25 {
+		t.Fatalf("expected the payload to survive a snapshot, got %+v", v)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// pokedexExporter streams the pokedex as CSV, fetching pokemon details with a
+// bounded worker pool while preserving pokedex order.
+type pokedexExporter struct {
+	workers int
+	maxRows int
+}
+
+func newPokedexExporter(workers, maxRows int) *pokedexExporter {
+	if workers <= 0 {
+		workers = 1
+	}
+	if maxRows <= 0 {
+		maxRows = 1
+	}
+	return &pokedexExporter{workers: workers, maxRows: maxRows}
+}
+
+type detailResult struct {
+	detail pokemonDetail
+	err    error
+}
+
+// exportPokedexHandler serves GET /export/pokedex.csv?offset=&limit=.
+//
+// At most maxRows rows are written per response. X-Total-Count carries the
+// pokedex size and X-Next-Offset, when present, the offset to request next.
+// If a detail fetch fails mid-stream the response ends early; clients resume
+// by requesting offset plus the number of data rows they received.
+func (s *Server) exportPokedexHandler(c *gin.Context) {
+	ex := s.exporter
+	if ex == nil {
+		ex = newPokedexExporter(4, 1000)
+	}
+	offset, err1 := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, err2 := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(ex.maxRows)))
+	if err1 != nil || err2 != nil || offset < 0 || limit <= 0 {
+		writeError(c, apierror.BadRequest("offset and limit must be non-negative integers"))
+		return
+	}
+	limit = min(limit, ex.maxRows)
+
+	all, status, err := s.pokedexNames(c.Request.Context())
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon list not found"))
+		return
+	}
+	var names []namedResource
+	if offset < len(all) {
+		names = all[offset:min(offset+limit, len(all))]
+	}
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/csv; charset=utf-8")
+	h.Set("Content-Disposition", `attachment; filename="pokedex.csv"`)
+	h.Set("X-Total-Count", strconv.Itoa(len(all)))
+	if next := offset + len(names); next < len(all) {
+		h.Set("X-Next-Offset", strconv.Itoa(next))
+	}
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(append([]string{"id", "name", "types"}, statNames...))
+
+	ex.eachDetail(c.Request.Context(), s, names, func(i int, res detailResult) bool {
+		if res.err != nil {
+			log.Printf("export: stopping at offset %d (%s): %v", offset+i, names[i].Name, res.err)
+			return false
+		}
+		d := res.detail
+		row := []string{strconv.Itoa(d.ID), d.Name, strings.Join(d.typeNames(), "|")}
+		for _, st := range statNames {
+			row = append(row, strconv.Itoa(d.baseStat(st)))
+		}
+		_ = w.Write(row)
+		if i%50 == 49 {
+			w.Flush()
+		}
+		return true
+	})
+	w.Flush()
+}
+
+// eachDetail fetches details for names with the worker pool, through the
+// s.details cache, and calls fn with each result in order, stopping when fn
+// returns false. At most 2*workers results are fetched ahead of fn, so
+// memory stays bounded.
+func (ex *pokedexExporter) eachDetail(ctx context.Context, s *Server, names []namedResource, fn func(int, detailResult) bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]chan detailResult, len(names))
+	for i := range results {
+		results[i] = make(chan detailResult, 1)
+	}
+	window := make(chan struct{}, 2*ex.workers)
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range names {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < ex.workers; w++ {
+		go func() {
+			for i := range jobs {
+				d, _, err := s.fetchPokemonDetail(ctx, names[i].Name)
+				results[i] <- detailResult{detail: d, err: err}
+			}
+		}()
+	}
+
+	for i := range names {
+		var res detailResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return
+		}
+		<-window
+		if !fn(i, res) {
+			return
+		}
+	}
+}
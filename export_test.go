@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExportPokedexCSV(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon?limit=100000&offset=0": `{"count":3,"results":[{"name":"bulbasaur"},{"name":"ivysaur"},{"name":"venusaur"}]}`,
+		"/pokemon/bulbasaur":             `{"id":1,"name":"bulbasaur","types":[{"slot":1,"type":{"name":"grass"}},{"slot":2,"type":{"name":"poison"}}],"stats":[{"base_stat":45,"stat":{"name":"hp"}},{"base_stat":45,"stat":{"name":"speed"}}]}`,
+		"/pokemon/ivysaur":               `{"id":2,"name":"ivysaur","types":[{"slot":1,"type":{"name":"grass"}}],"stats":[{"base_stat":60,"stat":{"name":"hp"}}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.names = newNameIndex(0)
+	s.exporter = newPokedexExporter(2, 2)
+	s.details = newTTLCache[pokemonDetail](time.Minute)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export/pokedex.csv", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-Next-Offset"); got != "2" {
+		t.Fatalf("expected next offset 2, got %q", got)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected header + 2 rows, got %d", len(rows))
+	}
+	if rows[1][1] != "bulbasaur" || rows[1][2] != "grass|poison" || rows[1][3] != "45" || rows[1][8] != "45" {
+		t.Fatalf("unexpected first row: %v", rows[1])
+	}
+
+	if d, ok := s.details.get("ivysaur"); !ok || d.ID != 2 {
+		t.Fatalf("expected the export to fill the detail cache, got %+v %v", d, ok)
+	}
+
+	// venusaur has no detail upstream: resuming at offset 2 yields only the header.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export/pokedex.csv?offset=2", nil))
+	rows, _ = csv.NewReader(w.Body).ReadAll()
+	if len(rows) != 1 || w.Header().Get("X-Next-Offset") != "" {
+		t.Fatalf("expected truncated final page, got %d rows", len(rows))
+	}
+}
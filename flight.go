@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sharedFetchTimeout bounds a coalesced pokemon fetch, which outlives the
+// request that started it.
+const sharedFetchTimeout = 30 * time.Second
+
+// flightWaiters gives each coalesced pokemon fetch a context of its own,
+// detached from the caller that started it and canceled once every caller
+// waiting on it has gone: one disconnect does not fail the others, and a
+// fetch nobody waits for stops spending upstream budget. The zero value is
+// ready to use.
+type flightWaiters struct {
+	mu sync.Mutex
+	m  map[string]*flight
+}
+
+type flight struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// join registers a waiter on name's fetch, creating its context from ctx's
+// values when none is in progress. Call leave once done waiting.
+func (fw *flightWaiters) join(ctx context.Context, name string) (f *flight, leave func()) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.m == nil {
+		fw.m = map[string]*flight{}
+	}
+	f = fw.m[name]
+	if f == nil {
+		fctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedFetchTimeout)
+		f = &flight{ctx: fctx, cancel: cancel}
+		fw.m[name] = f
+	}
+	f.waiters++
+	return f, func() {
+		fw.mu.Lock()
+		defer fw.mu.Unlock()
+		if f.waiters--; f.waiters == 0 {
+			fw.forget(name, f)
+		}
+	}
+}
+
+// done releases name's fetch context once the fetch has finished.
+func (fw *flightWaiters) done(name string, f *flight) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.forget(name, f)
+}
+
+// forget cancels f and unregisters it; fw.mu must be held.
+func (fw *flightWaiters) forget(name string, f *flight) {
+	f.cancel()
+	if fw.m[name] == f {
+		delete(fw.m, name)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// generationDetail is the subset of the upstream generation payload we use.
+type generationDetail struct {
+	ID             int             `json:"id"`
+	Name           string          `json:"name"`
+	MainRegion     namedResource   `json:"main_region"`
+	PokemonSpecies []namedResource `json:"pokemon_species"`
+	Types          []namedResource `json:"types"`
+}
+
+type generationSpecies struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// generationResponse is the response of GET /generation/:id/pokemon.
+type generationResponse struct {
+	ID      int                 `json:"id"`
+	Name    string              `json:"name"`
+	Region  string              `json:"region"`
+	Count   int                 `json:"count"`
+	Species []generationSpecies `json:"species"`
+}
+
+// resourceID returns the numeric id at the end of a PokeAPI resource URL
+// such as https://pokeapi.co/api/v2/pokemon-species/25/, or 0.
+func resourceID(url string) int {
+	url = strings.TrimSuffix(url, "/")
+	id, err := strconv.Atoi(url[strings.LastIndex(url, "/")+1:])
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// fetchGeneration returns a generation, via the s.generations cache. A
+// generation's species never change, so the cache keeps it for
+// GENERATION_CACHE_TTL_SEC.
+func (s *Server) fetchGeneration(ctx context.Context, id string) (generationDetail, int, error) {
+	return fetchCached(ctx, s, s.generations, id, "/generation/"+id)
+}
+
+// generationPokemonHandler serves GET /generation/:id/pokemon: the species
+// introduced in a generation, by number or name (1 or generation-i), in
+// pokedex order.
+func (s *Server) generationPokemonHandler(c *gin.Context) {
+	id := strings.ToLower(c.Param("id"))
+	g, status, err := s.fetchGeneration(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "generation not found"))
+		return
+	}
+	species := make([]generationSpecies, len(g.PokemonSpecies))
+	for i, sp := range g.PokemonSpecies {
+		species[i] = generationSpecies{ID: resourceID(sp.URL), Name: sp.Name}
+	}
+	sort.Slice(species, func(i, j int) bool { return species[i].ID < species[j].ID })
+	c.JSON(http.StatusOK, generationResponse{ID: g.ID, Name: g.Name, Region: g.MainRegion.Name, Count: len(species), Species: species})
+}
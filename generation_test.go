@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResourceID(t *testing.T) {
+	for url, want := range map[string]int{
+		"https://pokeapi.co/api/v2/pokemon-species/25/": 25,
+		"https://pokeapi.co/api/v2/pokemon-species/151": 151,
+		"https://pokeapi.co/api/v2/pokemon-species/":    0,
+		"": 0,
+	} {
+		if got := resourceID(url); got != want {
+			t.Errorf("resourceID(%q) = %d, want %d", url, got, want)
+		}
+	}
+}
+
+func TestGenerationPokemonEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/generation/1": `{"id":1,"name":"generation-i","main_region":{"name":"kanto"},"pokemon_species":[
+			{"name":"ivysaur","url":"https://pokeapi.co/api/v2/pokemon-species/2/"},
+			{"name":"bulbasaur","url":"https://pokeapi.co/api/v2/pokemon-species/1/"},
+			{"name":"mew","url":"https://pokeapi.co/api/v2/pokemon-species/151/"}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.generations = newTTLCache[generationDetail](time.Minute)
+	r := setupRouter(s)
+
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":1,"name":"generation-i","region":"kanto","count":3,"species":[{"id":1,"name":"bulbasaur"},{"id":2,"name":"ivysaur"},{"id":151,"name":"mew"}]}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/generation/1/pokemon", nil))
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Fatalf("expected 200 %s, got %d %s", want, w.Code, w.Body)
+	}
+	if errs := rs.validate(rs.schemaFor("/generation/:id/pokemon", http.StatusOK), w.Body.Bytes()); len(errs) > 0 {
+		t.Fatalf("response violates its schema: %v", errs)
+	}
+	if g, ok := s.generations.get("1"); !ok || len(g.PokemonSpecies) != 3 {
+		t.Fatalf("expected the generation to be cached, got %v %v", g, ok)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/generation/42/pokemon", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
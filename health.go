@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/plugin"
+)
+
+// healthCheckTimeout bounds each registered health check.
+const healthCheckTimeout = 2 * time.Second
+
+// healthzHandler reports detailed health. Latency degradation alone still
+// answers 200 ("degraded") so the endpoint can be used for liveness; a
+// failing registered health check or store answers 503 ("unhealthy"), and a
+// background worker restarting after a panic is reported as degraded. The
+// store's schema version is reported as schema_version.
+func (s *Server) healthzHandler(c *gin.Context) {
+	degraded := s.anomaly.degradedSignals()
+	workers, crashed := s.workers.status()
+	for _, name := range crashed {
+		degraded = append(degraded, "worker:"+name)
+	}
+	status := "ok"
+	if len(degraded) > 0 {
+		status = "degraded"
+	}
+	code := http.StatusOK
+
+	checks := gin.H{}
+	for _, hc := range plugin.HealthChecks() {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		err := hc.Check(ctx)
+		cancel()
+		if err != nil {
+			checks[hc.Name] = err.Error()
+			status, code = "unhealthy", http.StatusServiceUnavailable
+			continue
+		}
+		checks[hc.Name] = "ok"
+	}
+
+	schemaVersion := 0
+	if s.store != nil {
+		v, err := s.store.SchemaVersion(c.Request.Context())
+		if err != nil {
+			checks["storage"] = err.Error()
+			status, code = "unhealthy", http.StatusServiceUnavailable
+		}
+		schemaVersion = v
+	}
+
+	c.JSON(code, gin.H{
+		"status":         status,
+		"degraded":       degraded,
+		"checks":         checks,
+		"workers":        workers,
+		"schema_version": schemaVersion,
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/plugin"
+)
+
+func TestPluginRouteAndMiddleware(t *testing.T) {
+	// Registrations are process-global, so only add a route that no other
+	// test uses and middleware that is a no-op elsewhere.
+	plugin.RegisterMiddleware(func(c *gin.Context) {
+		if c.Request.URL.Path == "/plugin-test" {
+			c.Header("X-Plugin", "seen")
+		}
+		c.Next()
+	})
+	plugin.RegisterRoute(http.MethodGet, "/plugin-test", func(c *gin.Context) {
+		c.String(http.StatusOK, "from plugin")
+	})
+
+	r := setupRouter(newTestServer(""))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plugin-test", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "from plugin" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Plugin") != "seen" {
+		t.Fatal("expected plugin middleware to run")
+	}
+}
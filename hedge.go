@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// hedgeSamples is the number of recent upstream latencies kept for
+	// percentile-based hedge delays.
+	hedgeSamples = 256
+	// hedgeMinSamples is how many latencies are needed before a percentile
+	// delay is trusted; until then calls are not hedged.
+	hedgeMinSamples = 20
+)
+
+// upstreamHedger fires a second, identical upstream request when the first has
+// not answered within a delay, and uses whichever succeeds first. The delay is
+// either fixed or a percentile of recent upstream latencies.
+type upstreamHedger struct {
+	after      time.Duration
+	percentile float64 // in (0, 1); 0 means the fixed delay after
+	metrics    *metrics
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// newUpstreamHedger parses spec, either a duration ("150ms") or a percentile
+// of recent latencies ("p95"). It returns nil (no hedging) for an empty spec.
+func newUpstreamHedger(spec string, m *metrics) (*upstreamHedger, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	h := &upstreamHedger{metrics: m}
+	if p, ok := strings.CutPrefix(spec, "p"); ok {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v <= 0 || v >= 100 {
+			return nil, fmt.Errorf("invalid hedge percentile %q", spec)
+		}
+		h.percentile = v / 100
+		return h, nil
+	}
+	d, err := time.ParseDuration(spec)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid hedge delay %q", spec)
+	}
+	h.after = d
+	return h, nil
+}
+
+// delay returns how long to wait before hedging, and false if the call should
+// not be hedged yet.
+func (h *upstreamHedger) delay() (time.Duration, bool) {
+	if h.percentile == 0 {
+		return h.after, true
+	}
+	h.mu.Lock()
+	sorted := slices.Clone(h.samples)
+	h.mu.Unlock()
+	if len(sorted) < hedgeMinSamples {
+		return 0, false
+	}
+	slices.Sort(sorted)
+	return sorted[int(h.percentile*float64(len(sorted)-1))], true
+}
+
+func (h *upstreamHedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % hedgeSamples
+}
+
+// cancelOnClose releases a request's context once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// do GETs url, hedging it if the first request is slow. onHedge is called
+// whenever a hedge request is fired. An error is returned only once every
+// request has failed, or when the first one fails before a hedge is fired.
+func (h *upstreamHedger) do(ctx context.Context, client *http.Client, url string, onHedge func()) (*http.Response, error) {
+	if h == nil {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		return client.Do(req)
+	}
+	start := time.Now()
+	delay, ok := h.delay()
+	if !ok {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+		return resp, err
+	}
+
+	type result struct {
+		resp  *http.Response
+		err   error
+		hedge bool
+	}
+	results := make(chan result, 2)
+	var cancels []context.CancelFunc
+	launch := func(hedge bool) {
+		rctx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		go func() {
+			req, _ := http.NewRequestWithContext(rctx, http.MethodGet, url, nil)
+			resp, err := client.Do(req)
+			results <- result{resp, err, hedge}
+		}()
+	}
+	launch(false)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending, hedged := 1, false
+	var lastErr error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			hedged = true
+			pending++
+			onHedge()
+			h.metrics.upstreamHedgesTotal.WithLabelValues("fired").Inc()
+			launch(true)
+		case r := <-results:
+			pending--
+			winner := 0
+			if r.hedge {
+				winner = 1
+			}
+			if r.err != nil {
+				cancels[winner]()
+				lastErr = r.err
+				if !hedged {
+					return nil, r.err
+				}
+				continue
+			}
+			if hedged {
+				outcome := "primary_won"
+				if r.hedge {
+					outcome = "hedge_won"
+				}
+				h.metrics.upstreamHedgesTotal.WithLabelValues(outcome).Inc()
+			}
+			for i, cancel := range cancels {
+				if i != winner {
+					cancel()
+				}
+			}
+			if pending > 0 {
+				go func() {
+					if l := <-results; l.resp != nil {
+						l.resp.Body.Close()
+					}
+				}()
+			}
+			h.observe(time.Since(start))
+			r.resp.Body = cancelOnClose{r.resp.Body, cancels[winner]}
+			return r.resp, nil
+		}
+	}
+	return nil, lastErr
+}
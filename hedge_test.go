@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewUpstreamHedger(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	if h, err := newUpstreamHedger("", m); h != nil || err != nil {
+		t.Fatalf("expected hedging off, got %+v %v", h, err)
+	}
+	if h, err := newUpstreamHedger("150ms", m); err != nil || h.after != 150*time.Millisecond {
+		t.Fatalf("unexpected fixed hedger %+v %v", h, err)
+	}
+	if h, err := newUpstreamHedger("p95", m); err != nil || h.percentile != 0.95 {
+		t.Fatalf("unexpected percentile hedger %+v %v", h, err)
+	}
+	for _, spec := range []string{"p0", "p100", "soon", "-1s"} {
+		if _, err := newUpstreamHedger(spec, m); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestHedgerPercentileDelay(t *testing.T) {
+	h, _ := newUpstreamHedger("p90", newMetrics(prometheus.NewRegistry()))
+	if _, ok := h.delay(); ok {
+		t.Fatal("expected no hedging before enough samples")
+	}
+	for i := 1; i <= hedgeMinSamples*5; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if d, ok := h.delay(); !ok || d != 90*time.Millisecond {
+		t.Fatalf("expected a 90ms p90 delay, got %v %v", d, ok)
+	}
+}
+
+func TestHedgedUpstreamCall(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// the first request stalls until it is cancelled
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"name":"pikachu"}`)
+	}))
+	defer ts.Close()
+
+	m := newMetrics(prometheus.NewRegistry())
+	hedge, _ := newUpstreamHedger("20ms", m)
+	s := &Server{httpClient: ts.Client(), baseURL: ts.URL, metrics: m, hedge: hedge}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var p pokemonResponse
+	if _, err := s.fetchUpstream(ctx, "/pokemon/pikachu", &p); err != nil || p.Name != "pikachu" {
+		t.Fatalf("expected the hedge to answer, got %+v %v", p, err)
+	}
+	if got := testutil.ToFloat64(m.upstreamHedgesTotal.WithLabelValues("hedge_won")); got != 1 {
+		t.Fatalf("expected one hedge win, got %v", got)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 upstream requests, got %d", got)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator produces request IDs. Implementations are safe for concurrent
+// use.
+type IDGenerator interface {
+	NewID() string
+}
+
+// Request ID formats, selected by REQUEST_ID_FORMAT.
+const (
+	idFormatHex       = "hex"
+	idFormatUUIDv4    = "uuid4"
+	idFormatUUIDv7    = "uuid7"
+	idFormatSnowflake = "snowflake"
+)
+
+// newIDGenerator returns the generator for format. node identifies this
+// instance in snowflake IDs and is ignored by the other formats.
+func newIDGenerator(format string, node int) (IDGenerator, error) {
+	switch format {
+	case idFormatHex, "":
+		return hexIDGenerator{}, nil
+	case idFormatUUIDv4:
+		return uuidV4Generator{}, nil
+	case idFormatUUIDv7:
+		return &uuidV7Generator{}, nil
+	case idFormatSnowflake:
+		if node < 0 || node > snowflakeMaxNode {
+			return nil, fmt.Errorf("snowflake node must be between 0 and %d, got %d", snowflakeMaxNode, node)
+		}
+		return &snowflakeGenerator{node: int64(node)}, nil
+	}
+	return nil, fmt.Errorf("unknown request ID format %q (want hex, uuid4, uuid7 or snowflake)", format)
+}
+
+// hexIDGenerator produces 32 random hex characters, the historical format.
+type hexIDGenerator struct{}
+
+func (hexIDGenerator) NewID() string { return genRequestID() }
+
+// uuidV4Generator produces random (version 4) UUIDs.
+type uuidV4Generator struct{}
+
+func (uuidV4Generator) NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+// uuidV7Generator produces time-ordered (version 7) UUIDs: a 48-bit Unix
+// millisecond timestamp followed by a 12-bit counter and random bits. The
+// counter keeps IDs from one instance strictly increasing within a
+// millisecond; when it overflows, the timestamp is advanced by one.
+type uuidV7Generator struct {
+	mu     sync.Mutex
+	lastMs int64
+	seq    uint16
+}
+
+func (g *uuidV7Generator) NewID() string {
+	g.mu.Lock()
+	ms, seq := nextTick(time.Now().UnixMilli(), &g.lastMs, &g.seq, 0xfff)
+	g.mu.Unlock()
+
+	var b [16]byte
+	_, _ = rand.Read(b[8:])
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	b[6] = 0x70 | byte(seq>>8)
+	b[7] = byte(seq)
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b [16]byte) string {
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+// snowflakeEpoch is the zero time of snowflake timestamps.
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+// snowflakeGenerator produces snowflake-style IDs: 41 bits of milliseconds
+// since snowflakeEpoch, 10 bits of node and a 12-bit sequence, printed as
+// 19 zero-padded decimal digits so they also sort as strings.
+type snowflakeGenerator struct {
+	node int64
+
+	mu     sync.Mutex
+	lastMs int64
+	seq    uint16
+}
+
+func (g *snowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	ms, seq := nextTick(time.Now().UnixMilli(), &g.lastMs, &g.seq, snowflakeMaxSeq)
+	g.mu.Unlock()
+	id := (ms-snowflakeEpoch)<<(snowflakeNodeBits+snowflakeSeqBits) | g.node<<snowflakeSeqBits | int64(seq)
+	return fmt.Sprintf("%019d", id)
+}
+
+// nextTick returns the timestamp and sequence number of the next ID given
+// the current time now and the previous ID's lastMs and seq, which it
+// updates. IDs never go back in time: when the clock stalls or steps back,
+// the previous timestamp is reused, and when the sequence passes maxSeq the
+// timestamp is advanced.
+func nextTick(now int64, lastMs *int64, seq *uint16, maxSeq uint16) (int64, uint16) {
+	if now > *lastMs {
+		*lastMs, *seq = now, 0
+		return now, 0
+	}
+	if *seq >= maxSeq {
+		*lastMs++
+		*seq = 0
+	} else {
+		*seq++
+	}
+	return *lastMs, *seq
+}
+
+// newRequestID returns an ID from the configured generator.
+func (s *Server) newRequestID() string {
+	if s == nil || s.requestIDs == nil {
+		return genRequestID()
+	}
+	return s.requestIDs.NewID()
+}
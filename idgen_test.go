@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIDGeneratorFormats(t *testing.T) {
+	cases := map[string]*regexp.Regexp{
+		idFormatHex:       regexp.MustCompile(`^[0-9a-f]{32}$`),
+		idFormatUUIDv4:    regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		idFormatUUIDv7:    regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		idFormatSnowflake: regexp.MustCompile(`^[0-9]{19}$`),
+	}
+	for format, pattern := range cases {
+		g, err := newIDGenerator(format, 7)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		seen := map[string]bool{}
+		for i := 0; i < 1000; i++ {
+			id := g.NewID()
+			if !pattern.MatchString(id) {
+				t.Fatalf("%s: unexpected ID %q", format, id)
+			}
+			if seen[id] {
+				t.Fatalf("%s: duplicate ID %q", format, id)
+			}
+			seen[id] = true
+		}
+	}
+
+	for _, tc := range []struct {
+		format string
+		node   int
+	}{{"ulid", 0}, {idFormatSnowflake, -1}, {idFormatSnowflake, 1024}} {
+		if _, err := newIDGenerator(tc.format, tc.node); err == nil {
+			t.Errorf("expected %s with node %d to be rejected", tc.format, tc.node)
+		}
+	}
+}
+
+func TestTimeOrderedIDsSort(t *testing.T) {
+	for _, format := range []string{idFormatUUIDv7, idFormatSnowflake} {
+		g, _ := newIDGenerator(format, 3)
+		ids := make([]string, 10000) // several IDs per millisecond, overflowing the sequence
+		for i := range ids {
+			ids[i] = g.NewID()
+		}
+		if !sort.StringsAreSorted(ids) {
+			t.Fatalf("%s: expected IDs in generation order to sort lexically", format)
+		}
+	}
+
+	g := &snowflakeGenerator{node: 5}
+	id, _ := strconv.ParseInt(g.NewID(), 10, 64)
+	if node := id >> snowflakeSeqBits & snowflakeMaxNode; node != 5 {
+		t.Fatalf("expected node 5 in the snowflake ID, got %d", node)
+	}
+	issued := time.UnixMilli(id>>(snowflakeNodeBits+snowflakeSeqBits) + snowflakeEpoch)
+	if d := time.Since(issued); d < 0 || d > time.Minute {
+		t.Fatalf("expected the snowflake timestamp to be now, got %v", issued)
+	}
+}
+
+func TestNextTickNeverGoesBack(t *testing.T) {
+	var last int64
+	var seq uint16
+	if ms, s := nextTick(100, &last, &seq, 1); ms != 100 || s != 0 {
+		t.Fatalf("expected (100, 0), got (%d, %d)", ms, s)
+	}
+	if ms, s := nextTick(90, &last, &seq, 1); ms != 100 || s != 1 {
+		t.Fatalf("expected a clock step back to reuse 100, got (%d, %d)", ms, s)
+	}
+	if ms, s := nextTick(100, &last, &seq, 1); ms != 101 || s != 0 {
+		t.Fatalf("expected sequence overflow to advance to 101, got (%d, %d)", ms, s)
+	}
+}
+
+func TestRequestIDMiddlewareUsesGenerator(t *testing.T) {
+	s := newTestServer("http://unused")
+	s.requestIDs = &snowflakeGenerator{node: 1}
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rid := w.Header().Get("X-Request-ID"); !regexp.MustCompile(`^[0-9]{19}$`).MatchString(rid) {
+		t.Fatalf("expected a snowflake request ID, got %q", rid)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("X-Request-ID", "from-client")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if rid := w.Header().Get("X-Request-ID"); rid != "from-client" {
+		t.Fatalf("expected the client's request ID to be kept, got %q", rid)
+	}
+}
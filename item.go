@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// itemDetail is the subset of the upstream item payload we use. Fling power
+// is null for items that cannot be flung.
+type itemDetail struct {
+	Name          string          `json:"name"`
+	Cost          int             `json:"cost"`
+	FlingPower    *int            `json:"fling_power"`
+	Category      namedResource   `json:"category"`
+	Attributes    []namedResource `json:"attributes"`
+	EffectEntries []struct {
+		Effect      string        `json:"effect"`
+		ShortEffect string        `json:"short_effect"`
+		Language    namedResource `json:"language"`
+	} `json:"effect_entries"`
+	Sprites struct {
+		Default string `json:"default"`
+	} `json:"sprites"`
+	HeldByPokemon []struct {
+		Pokemon namedResource `json:"pokemon"`
+	} `json:"held_by_pokemon"`
+}
+
+// effect returns the effect and short effect in lang.
+func (it itemDetail) effect(lang string) (string, string) {
+	for _, e := range it.EffectEntries {
+		if e.Language.Name == lang {
+			return e.Effect, e.ShortEffect
+		}
+	}
+	return "", ""
+}
+
+// itemResponse is the response of GET /item/:name.
+type itemResponse struct {
+	Name        string   `json:"name"`
+	Category    string   `json:"category"`
+	Cost        int      `json:"cost"`
+	FlingPower  *int     `json:"fling_power"`
+	Attributes  []string `json:"attributes"`
+	Effect      string   `json:"effect,omitempty"`
+	ShortEffect string   `json:"short_effect,omitempty"`
+	Sprite      string   `json:"sprite,omitempty"`
+	HeldBy      []string `json:"held_by"`
+}
+
+// fetchItem returns an item, via the s.items cache.
+func (s *Server) fetchItem(ctx context.Context, name string) (itemDetail, int, error) {
+	return fetchCached(ctx, s, s.items, name, "/item/"+name)
+}
+
+// itemHandler serves GET /item/:name?lang=en: the item's category, cost,
+// effect in lang (English by default) and the pokemon that can hold it in the
+// wild.
+func (s *Server) itemHandler(c *gin.Context) {
+	it, status, err := s.fetchItem(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "item not found"))
+		return
+	}
+	effect, short := it.effect(c.DefaultQuery("lang", "en"))
+	resp := itemResponse{
+		Name: it.Name, Category: it.Category.Name, Cost: it.Cost, FlingPower: it.FlingPower,
+		Attributes: make([]string, len(it.Attributes)), Effect: effect, ShortEffect: short,
+		Sprite: it.Sprites.Default, HeldBy: make([]string, len(it.HeldByPokemon)),
+	}
+	for i, a := range it.Attributes {
+		resp.Attributes[i] = a.Name
+	}
+	for i, h := range it.HeldByPokemon {
+		resp.HeldBy[i] = h.Pokemon.Name
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestItemEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/item/light-ball": `{"name":"light-ball","cost":1000,"fling_power":30,"category":{"name":"species-specific"},
+			"attributes":[{"name":"holdable"},{"name":"holdable-active"}],
+			"effect_entries":[{"effect":"Doubles Pikachu's Attack.","short_effect":"Doubles Attack for Pikachu.","language":{"name":"en"}}],
+			"sprites":{"default":"https://example.test/light-ball.png"},
+			"held_by_pokemon":[{"pokemon":{"name":"pikachu"}}]}`,
+		"/item/master-ball": `{"name":"master-ball","cost":0,"fling_power":null,"category":{"name":"standard-balls"},
+			"attributes":[],"effect_entries":[],"sprites":{"default":null},"held_by_pokemon":[]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.items = newTTLCache[itemDetail](time.Minute)
+	r := setupRouter(s)
+
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"/item/light-ball": `{"name":"light-ball","category":"species-specific","cost":1000,"fling_power":30,` +
+			`"attributes":["holdable","holdable-active"],"effect":"Doubles Pikachu's Attack.","short_effect":"Doubles Attack for Pikachu.",` +
+			`"sprite":"https://example.test/light-ball.png","held_by":["pikachu"]}`,
+		"/item/master-ball": `{"name":"master-ball","category":"standard-balls","cost":0,"fling_power":null,"attributes":[],"held_by":[]}`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Fatalf("%s: expected 200 %s, got %d %s", path, want, w.Code, w.Body)
+		}
+		if errs := rs.validate(rs.schemaFor("/item/:name", http.StatusOK), w.Body.Bytes()); len(errs) > 0 {
+			t.Fatalf("%s: response violates its schema: %v", path, errs)
+		}
+	}
+	if _, ok := s.items.get("light-ball"); !ok {
+		t.Fatal("expected the item to be cached")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/rare-candy-plus", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
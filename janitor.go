@@ -0,0 +1,90 @@
+package main
+
+import (
+	"time"
+)
+
+// cacheJanitor periodically removes expired cache entries. Up to maxKeys
+// expired keys are collected under the read lock and deleted in batches
+// under the write lock, so a sweep never holds either lock for long; a sweep
+// that exceeds maxSweep is cut short, and the rest is reclaimed on later
+// ticks.
+type cacheJanitor[V any] struct {
+	cache     *ttlCache[V]
+	interval  time.Duration
+	batchSize int
+	maxKeys   int
+	maxSweep  time.Duration
+	metrics   *metrics
+}
+
+// newCacheJanitor returns nil (no background sweeping) when interval is not
+// positive; expired entries are then only dropped lazily on read.
+func newCacheJanitor[V any](c *ttlCache[V], interval time.Duration, batchSize, maxKeys int, maxSweep time.Duration, m *metrics) *cacheJanitor[V] {
+	if interval <= 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = 256
+	}
+	if maxKeys <= 0 {
+		maxKeys = 4096
+	}
+	return &cacheJanitor[V]{cache: c, interval: interval, batchSize: batchSize, maxKeys: maxKeys, maxSweep: maxSweep, metrics: m}
+}
+
+// start sweeps every interval as the worker janitor:<cache name>.
+func (j *cacheJanitor[V]) start(sv *supervisor) {
+	if j == nil {
+		return
+	}
+	sv.goWorker("janitor:"+j.cache.name, func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			j.sweep()
+		}
+	})
+}
+
+// sweep runs one pass and returns the number of reclaimed entries.
+func (j *cacheJanitor[V]) sweep() int {
+	start := time.Now()
+	c := j.cache
+
+	// map order is random, so keys left over by the cap are reached by a
+	// later sweep
+	c.mu.RLock()
+	var expired []string
+	for k, e := range c.data {
+		if start.After(e.expiresAt) {
+			expired = append(expired, k)
+			if len(expired) >= j.maxKeys {
+				break
+			}
+		}
+	}
+	c.mu.RUnlock()
+
+	reclaimed := 0
+	for i := 0; i < len(expired); i += j.batchSize {
+		if j.maxSweep > 0 && time.Since(start) > j.maxSweep {
+			break
+		}
+		end := min(i+j.batchSize, len(expired))
+		now := time.Now()
+		c.mu.Lock()
+		for _, k := range expired[i:end] {
+			// re-check: the entry may have been refreshed since collection
+			if e, ok := c.data[k]; ok && now.After(e.expiresAt) {
+				c.deleteLocked(k)
+				reclaimed++
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	j.metrics.janitorSweepDurationSec.Observe(time.Since(start).Seconds())
+	j.metrics.janitorReclaimedTotal.Add(float64(reclaimed))
+	return reclaimed
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCacheJanitorSweep(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	c := newPokemonCache(time.Hour)
+	c.set("fresh", pokemonCacheEntry{pokemon: pokemonResponse{Name: "fresh"}})
+	c.mu.Lock()
+	for _, k := range []string{"a", "b", "c"} {
+		c.data[k] = cacheEntry[pokemonCacheEntry]{expiresAt: time.Now().Add(-time.Minute)}
+	}
+	c.mu.Unlock()
+
+	j := newCacheJanitor(c, time.Minute, 2, 0, 0, m)
+	if n := j.sweep(); n != 3 {
+		t.Fatalf("expected 3 reclaimed entries, got %d", n)
+	}
+	if _, ok := c.get("fresh"); !ok {
+		t.Fatal("fresh entry should survive the sweep")
+	}
+	if v := testutil.ToFloat64(m.janitorReclaimedTotal); v != 3 {
+		t.Fatalf("expected reclaimed counter 3, got %v", v)
+	}
+	if newCacheJanitor(c, 0, 0, 0, 0, m) != nil {
+		t.Fatal("expected disabled janitor for zero interval")
+	}
+}
+
+func TestCacheJanitorSweepCollectsAtMostMaxKeys(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	c := newPokemonCache(time.Hour)
+	c.mu.Lock()
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		c.data[k] = cacheEntry[pokemonCacheEntry]{expiresAt: time.Now().Add(-time.Minute)}
+	}
+	c.mu.Unlock()
+
+	j := newCacheJanitor(c, time.Minute, 10, 2, 0, m)
+	if n := j.sweep(); n != 2 {
+		t.Fatalf("expected 2 reclaimed entries, got %d", n)
+	}
+	if n := c.len(); n != 3 {
+		t.Fatalf("expected 3 entries left for later sweeps, got %d", n)
+	}
+	j.sweep()
+	j.sweep()
+	if n := c.len(); n != 0 {
+		t.Fatalf("expected later sweeps to reclaim the rest, got %d left", n)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import "sync"
+
+// jobRegistry keeps the most recent background jobs by id for status
+// queries. The zero value is ready to use.
+type jobRegistry[J any] struct {
+	mu    sync.Mutex
+	jobs  map[string]J
+	order []string
+}
+
+// add registers j under id, dropping the oldest jobs beyond limit.
+func (r *jobRegistry[J]) add(id string, j J, limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobs == nil {
+		r.jobs = map[string]J{}
+	}
+	r.jobs[id] = j
+	r.order = append(r.order, id)
+	if len(r.order) > limit {
+		delete(r.jobs, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+func (r *jobRegistry[J]) get(id string) (J, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	return j, ok
+}
+
+// removeIf drops the jobs matching drop and reports how many it dropped.
+func (r *jobRegistry[J]) removeIf(drop func(J) bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.order[:0]
+	for _, id := range r.order {
+		if drop(r.jobs[id]) {
+			delete(r.jobs, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	n := len(r.order) - len(kept)
+	r.order = kept
+	return n
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// journalEntry is one recorded upstream attempt.
+type journalEntry struct {
+	At         time.Time         `json:"at"`
+	URL        string            `json:"url"`
+	Attempt    int               `json:"attempt"`
+	Status     int               `json:"status,omitempty"`
+	DurationMS float64           `json:"duration_ms"`
+	Error      string            `json:"error,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Truncated  bool              `json:"truncated,omitempty"`
+}
+
+// sensitiveHeaders are never recorded in the journal.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true, "Cookie": true, "Set-Cookie": true, "Proxy-Authorization": true,
+}
+
+// upstreamJournal keeps the last size upstream attempts in a ring buffer,
+// with bodies capped at maxBody bytes and credentials stripped.
+type upstreamJournal struct {
+	maxBody int
+
+	mu      sync.Mutex
+	entries []journalEntry
+	next    int
+	full    bool
+}
+
+// newUpstreamJournal returns nil (journal disabled) when size is not positive.
+func newUpstreamJournal(size, maxBody int) *upstreamJournal {
+	if size <= 0 {
+		return nil
+	}
+	return &upstreamJournal{maxBody: maxBody, entries: make([]journalEntry, size)}
+}
+
+func (j *upstreamJournal) add(e journalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries[j.next] = e
+	j.next = (j.next + 1) % len(j.entries)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// recordError records an attempt that got no response.
+func (j *upstreamJournal) recordError(rawURL string, attempt int, started time.Time, err error) {
+	if j == nil {
+		return
+	}
+	j.add(journalEntry{
+		At: started.UTC(), URL: sanitizeURL(rawURL), Attempt: attempt,
+		DurationMS: msSince(started), Error: err.Error(),
+	})
+}
+
+// recordResponse records an attempt's response with its already-read body;
+// bodies longer than maxBody are marked truncated.
+func (j *upstreamJournal) recordResponse(rawURL string, attempt int, started time.Time, resp *http.Response, body []byte) {
+	if j == nil {
+		return
+	}
+	e := journalEntry{
+		At: started.UTC(), URL: sanitizeURL(rawURL), Attempt: attempt, Status: resp.StatusCode,
+		DurationMS: msSince(started), Headers: make(map[string]string, len(resp.Header)),
+	}
+	for k, v := range resp.Header {
+		if !sensitiveHeaders[k] {
+			e.Headers[k] = strings.Join(v, ", ")
+		}
+	}
+	if len(body) > j.maxBody {
+		body, e.Truncated = body[:j.maxBody], true
+	}
+	e.Body = string(body)
+	j.add(e)
+}
+
+// bodyLimit is how much of an error body recordResponse needs to keep and
+// tell whether it was truncated.
+func (j *upstreamJournal) bodyLimit() int64 {
+	if j == nil {
+		return 0
+	}
+	return int64(j.maxBody) + 1
+}
+
+// snapshot returns entries oldest first.
+func (j *upstreamJournal) snapshot() []journalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.full {
+		return append([]journalEntry(nil), j.entries[:j.next]...)
+	}
+	return append(append([]journalEntry(nil), j.entries[j.next:]...), j.entries[:j.next]...)
+}
+
+// sanitizeURL drops userinfo from u.
+func sanitizeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
+
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t).Microseconds()) / 1000
+}
+
+// adminJournalHandler returns the recorded upstream attempts, oldest first.
+func (s *Server) adminJournalHandler(c *gin.Context) {
+	if s.journal == nil {
+		writeError(c, apierror.NotFound("upstream journal is not enabled"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": s.journal.snapshot()})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpstreamJournal(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu": `{"name":"pikachu","height":4,"weight":60,"base_experience":112}`,
+	})
+	s := newTestServer(strings.Replace(ts.URL, "http://", "http://user:secret@", 1))
+	s.journal = newUpstreamJournal(2, 10)
+	r := setupRouter(withAdminKey(t, s))
+
+	for _, name := range []string{"pikachu", "missingno", "pikachu2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/pokemon/"+name, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/upstream/journal", nil)))
+	var body struct {
+		Entries []journalEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(body.Entries) != 2 {
+		t.Fatalf("expected ring of 2 entries, got %d", len(body.Entries))
+	}
+	first := body.Entries[0]
+	if !strings.HasSuffix(first.URL, "/pokemon/missingno") || first.Status != http.StatusNotFound {
+		t.Fatalf("unexpected oldest entry: %+v", first)
+	}
+	if strings.Contains(first.URL, "secret") {
+		t.Fatalf("credentials leaked into journal: %s", first.URL)
+	}
+	if first.Body != "Not Found" {
+		t.Fatalf("expected 404 body recorded, got %q", first.Body)
+	}
+}
+
+func TestUpstreamJournalTruncates(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu": `{"name":"pikachu","height":4,"weight":60,"base_experience":112}`,
+	})
+	s := newTestServer(ts.URL)
+	s.journal = newUpstreamJournal(4, 10)
+	setupRouter(s).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil))
+
+	e := s.journal.snapshot()[0]
+	if !e.Truncated || len(e.Body) != 10 {
+		t.Fatalf("expected body truncated to 10 bytes, got %+v", e)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// upstreamSaturatedAt is the bulkhead occupancy past which the upstream is
+// considered the bottleneck.
+const upstreamSaturatedAt = 0.9
+
+// loadTracker counts the requests being served, for the autoscaling signal
+// of GET /admin/load. A replica is at target with targetInFlight requests in
+// flight; below scaleDownBelow of that it recommends scaling in.
+type loadTracker struct {
+	targetInFlight int
+	scaleDownBelow float64
+
+	inFlight atomic.Int64
+}
+
+// newLoadTracker returns nil (no signal) when targetInFlight is not positive.
+func newLoadTracker(targetInFlight int, scaleDownBelow float64) *loadTracker {
+	if targetInFlight <= 0 {
+		return nil
+	}
+	return &loadTracker{targetInFlight: targetInFlight, scaleDownBelow: scaleDownBelow}
+}
+
+type upstreamLoad struct {
+	Bulkhead  float64 `json:"bulkhead"`
+	Quota     float64 `json:"quota"`
+	Saturated bool    `json:"saturated"`
+}
+
+// loadReport is the response of GET /admin/load.
+type loadReport struct {
+	InFlight                int64        `json:"in_flight"`
+	QueueDepth              int64        `json:"queue_depth"`
+	TargetInFlight          int          `json:"target_in_flight"`
+	Utilization             float64      `json:"utilization"`
+	Upstream                upstreamLoad `json:"upstream"`
+	RecommendedReplicaDelta int          `json:"recommended_replica_delta"`
+	Reason                  string       `json:"reason"`
+}
+
+// recommend fills in the replica delta. Demand beyond the target asks for
+// as many more replicas as it would take to bring this one back to target,
+// unless the upstream is what is saturated: more replicas would only queue
+// on it. An idle replica asks for one less.
+func (l *loadTracker) recommend(r *loadReport) {
+	switch {
+	case r.QueueDepth > 0 || r.Utilization > 1:
+		if r.Upstream.Saturated {
+			r.Reason = "upstream_saturated"
+			return
+		}
+		r.RecommendedReplicaDelta = max(int(math.Ceil(r.Utilization))-1, 1)
+		r.Reason = "over_target"
+	case r.Utilization < l.scaleDownBelow:
+		r.RecommendedReplicaDelta = -1
+		r.Reason = "under_target"
+	default:
+		r.Reason = "within_target"
+	}
+}
+
+// sampleLoad reports the current load of this instance.
+func (s *Server) sampleLoad() loadReport {
+	r := loadReport{
+		InFlight:       s.load.inFlight.Load(),
+		QueueDepth:     s.admission.queueDepth(),
+		TargetInFlight: s.load.targetInFlight,
+	}
+	demand := float64(r.InFlight+r.QueueDepth) / float64(r.TargetInFlight)
+	r.Utilization = math.Round(demand*100) / 100
+	quota, throttled := s.quota.usage()
+	r.Upstream = upstreamLoad{
+		Bulkhead:  math.Round(s.bulkhead.saturation()*100) / 100,
+		Quota:     math.Round(quota*100) / 100,
+		Saturated: throttled || s.bulkhead.saturation() >= upstreamSaturatedAt,
+	}
+	s.load.recommend(&r)
+	return r
+}
+
+// middleware: count requests in flight for the load signal. Probes, scrapes
+// and the signal itself are not load.
+func loadMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.load == nil || admissionExempt[c.FullPath()] || c.FullPath() == "/admin/load" {
+			c.Next()
+			return
+		}
+		s.load.inFlight.Add(1)
+		defer s.load.inFlight.Add(-1)
+		c.Next()
+	}
+}
+
+// adminLoadHandler serves GET /admin/load: a machine-readable load signal
+// for autoscalers (KEDA's metrics-api scaler, custom controllers). Requests
+// in flight and queued, the upstream limiters' saturation and a
+// recommended replica delta say more about this service, which mostly waits
+// on PokeAPI, than CPU does.
+func (s *Server) adminLoadHandler(c *gin.Context) {
+	if s.load == nil {
+		writeError(c, apierror.NotFound("load signal is disabled"))
+		return
+	}
+	c.JSON(http.StatusOK, s.sampleLoad())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLoadRecommend(t *testing.T) {
+	l := newLoadTracker(10, 0.3)
+	for _, tc := range []struct {
+		name   string
+		r      loadReport
+		delta  int
+		reason string
+	}{
+		{"idle", loadReport{Utilization: 0.1}, -1, "under_target"},
+		{"steady", loadReport{Utilization: 0.8}, 0, "within_target"},
+		{"queueing", loadReport{Utilization: 0.9, QueueDepth: 3}, 1, "over_target"},
+		{"well over", loadReport{Utilization: 2.4}, 2, "over_target"},
+		{"upstream bound", loadReport{Utilization: 2.4, Upstream: upstreamLoad{Saturated: true}}, 0, "upstream_saturated"},
+	} {
+		l.recommend(&tc.r)
+		if tc.r.RecommendedReplicaDelta != tc.delta || tc.r.Reason != tc.reason {
+			t.Errorf("%s: got %d %s, want %d %s", tc.name, tc.r.RecommendedReplicaDelta, tc.r.Reason, tc.delta, tc.reason)
+		}
+	}
+}
+
+func TestAdminLoad(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{metrics: m, load: newLoadTracker(2, 0.3), bulkhead: newUpstreamBulkhead(4, time.Millisecond, m)}
+	s.bulkhead.acquire(context.Background())
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(loadMiddleware(s))
+	r.GET("/slow", func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.GET("/admin/load", s.adminLoadHandler)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		}()
+	}
+	waitFor(t, func() bool { return s.load.inFlight.Load() == 3 })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/load", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got loadReport
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := loadReport{InFlight: 3, TargetInFlight: 2, Utilization: 1.5, Upstream: upstreamLoad{Bulkhead: 0.25},
+		RecommendedReplicaDelta: 1, Reason: "over_target"}
+	if got != want {
+		t.Fatalf("load = %+v, want %+v", got, want)
+	}
+
+	close(release)
+	wg.Wait()
+	if n := s.load.inFlight.Load(); n != 0 {
+		t.Fatalf("expected nothing in flight, got %d", n)
+	}
+
+	w = httptest.NewRecorder()
+	setupRouter(withAdminKey(t, newTestServer(""))).ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/load", nil)))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with the signal disabled, got %d", w.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLanguage is the fallback for localized texts.
+const defaultLanguage = "en"
+
+// localizedPokemon is the /pokemon/:name response when a language is
+// requested: the pokemon plus its species' name in that language.
+type localizedPokemon struct {
+	pokemonResponse
+	DisplayName     string `json:"display_name,omitempty"` // empty unless a language is requested
+	DisplayLanguage string `json:"display_name_lang,omitempty"`
+}
+
+// requestedLanguages returns the languages a request asks for, most
+// preferred first: ?lang= alone when set, else the Accept-Language ranges by
+// descending quality. Wildcards and q=0 ranges are dropped.
+func requestedLanguages(c *gin.Context) []string {
+	if lang := strings.TrimSpace(c.Query("lang")); lang != "" {
+		return []string{lang}
+	}
+	type rangeQ struct {
+		tag string
+		q   float64
+	}
+	var ranges []rangeQ
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		ranges = append(ranges, rangeQ{tag, q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	langs := make([]string, len(ranges))
+	for i, r := range ranges {
+		langs[i] = r.tag
+	}
+	return langs
+}
+
+// localizedName returns the species name in the first of langs PokeAPI has,
+// matching tags case-insensitively and then by primary subtag (fr-CA picks
+// fr), falling back to English. Both are empty when there is no match.
+func (sp speciesDetail) localizedName(langs []string) (name, lang string) {
+	find := func(tag string) (string, string, bool) {
+		for _, n := range sp.Names {
+			if strings.EqualFold(n.Language.Name, tag) {
+				return n.Name, n.Language.Name, true
+			}
+		}
+		return "", "", false
+	}
+	for _, tag := range langs {
+		if name, lang, ok := find(tag); ok {
+			return name, lang
+		}
+		if primary, _, ok := strings.Cut(tag, "-"); ok {
+			if name, lang, ok := find(primary); ok {
+				return name, lang
+			}
+		}
+	}
+	name, lang, _ = find(defaultLanguage)
+	return name, lang
+}
+
+// localizePokemon adds p's display name in langs. The species is looked up
+// through the cached pokemon details, so forms such as deoxys-normal find
+// theirs; when it cannot be fetched or has no matching name, the pokemon's
+// own name is used.
+func (s *Server) localizePokemon(ctx context.Context, p pokemonResponse, langs []string) localizedPokemon {
+	lp := localizedPokemon{pokemonResponse: p, DisplayName: p.Name}
+	d, _, err := s.fetchPokemonDetail(ctx, p.Name)
+	if err != nil {
+		return lp
+	}
+	if sp, _, err := s.fetchSpecies(ctx, d.speciesName()); err == nil {
+		if name, lang := sp.localizedName(langs); name != "" {
+			lp.DisplayName, lp.DisplayLanguage = name, lang
+		}
+	}
+	return lp
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestedLanguages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		target, header string
+		want           []string
+	}{
+		{"/", "", []string{}},
+		{"/?lang=ja", "fr", []string{"ja"}},
+		{"/", "fr-CA, fr;q=0.9, en;q=0.8", []string{"fr-CA", "fr", "en"}},
+		{"/", "de;q=0.5, ko, *;q=0.1, es;q=0", []string{"ko", "de"}},
+		{"/", "ja;q=bogus, it", []string{"it"}},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.header != "" {
+			c.Request.Header.Set("Accept-Language", tc.header)
+		}
+		if got := requestedLanguages(c); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s with %q: expected %v, got %v", tc.target, tc.header, tc.want, got)
+		}
+	}
+}
+
+func TestLocalizedPokemonName(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu":        `{"name":"pikachu","height":4,"weight":60,"base_experience":112}`,
+		"/pokemon/mew":            `{"name":"mew","height":4,"weight":40,"base_experience":300}`,
+		"/pokemon/deoxys-normal":  `{"name":"deoxys-normal","height":4,"species":{"name":"deoxys"}}`,
+		"/pokemon-species/deoxys": `{"name":"deoxys","names":[{"name":"デオキシス","language":{"name":"ja"}}]}`,
+		"/pokemon-species/pikachu": `{"name":"pikachu","names":[{"name":"ピカチュウ","language":{"name":"ja"}},
+			{"name":"Pikachu","language":{"name":"fr"}},{"name":"Pikachu","language":{"name":"en"}}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.species = newTTLCache[speciesDetail](time.Minute)
+	r := setupRouter(s)
+
+	cases := []struct {
+		target, header     string
+		wantName, wantLang string
+	}{
+		{"/pokemon/pikachu?lang=ja", "", "ピカチュウ", "ja"},
+		{"/pokemon/pikachu", "fr-CA, ja;q=0.5", "Pikachu", "fr"},
+		{"/pokemon/pikachu", "zh-Hant", "Pikachu", "en"},
+		{"/pokemon/mew?lang=ja", "", "mew", ""},
+		{"/pokemon/deoxys-normal?lang=ja", "", "デオキシス", "ja"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.header != "" {
+			req.Header.Set("Accept-Language", tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tc.target, w.Code, w.Body)
+		}
+		var lp localizedPokemon
+		if err := json.Unmarshal(w.Body.Bytes(), &lp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if lp.DisplayName != tc.wantName || lp.DisplayLanguage != tc.wantLang || lp.Height != 4 {
+			t.Errorf("%s %q: expected %s (%s), got %+v", tc.target, tc.header, tc.wantName, tc.wantLang, lp)
+		}
+		if got := w.Header().Get("Content-Language"); got != tc.wantLang {
+			t.Errorf("%s %q: expected Content-Language %q, got %q", tc.target, tc.header, tc.wantLang, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil))
+	var plain map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &plain); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if _, ok := plain["display_name"]; ok || w.Header().Get("Vary") != "Accept-Language" {
+		t.Fatalf("expected the plain pokemon without a language, got %s (Vary %q)", w.Body, w.Header().Get("Vary"))
+	}
+}
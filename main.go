@@ -1,32 +1,107 @@
 package main
 
 import (
+	"container/list"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/singleflight"
+
+	"ci_education/apierror"
+	"ci_education/plugin"
+	"ci_education/storage"
 )
 
 // Server bundles dependencies for handlers.
 type Server struct {
 	httpClient *http.Client
 	cache      *pokemonCache
+	details    *ttlCache[pokemonDetail]
+	types      *ttlCache[typeDetail]
+	species    *ttlCache[speciesDetail]
+	lists      *ttlCache[resourceList] // /pokemon list pages
+	abilities  *ttlCache[abilityDetail]
+	moves      *ttlCache[moveDetail]
+	items      *ttlCache[itemDetail]
+	berries    *ttlCache[berryDetail]
+	encounters *ttlCache[[]encounterArea]
+	chains     *ttlCache[evolutionChain] // by upstream path
+	sprites    *spriteCache
 	metrics    *metrics
 	baseURL    string
+	shadow     *shadowMirror
+	canary     *canaryRouter
+	slo        *sloTracker
+	anomaly    *latencyMonitor
+	breaker    *circuitBreaker
+	quota      *upstreamQuota
+	bulkhead   *upstreamBulkhead
+	hedge      *upstreamHedger
+	proxy      *reverseProxy
+	store      storage.Store
+	retry      retryPolicy
+	budget     *retryBudget
+	rateLimit  *rateLimiter
+	admission  *admissionController
+	names      *nameIndex
+	exporter   *pokedexExporter
+	scripts    map[string]*scriptHook
+	journal    *upstreamJournal
+	dailySeed  string
+	dexSize    int // national dex ids 1..dexSize the daily pokemon is drawn from
+	warmJobs   jobRegistry[*warmJob]
+	takeouts   jobRegistry[*takeoutJob]
+	erasures   jobRegistry[*erasureJob]
+	inflight   singleflight.Group // coalesces concurrent pokemon fetches
+	flights    flightWaiters      // contexts of the coalesced fetches
+
+	maxResponseBytes int
+	maxDeadline      time.Duration
+
+	upstreamErrorDetails bool // pass sanitized upstream error messages to clients
+	cachePolicies        []cachePolicy
+	defaultCachePolicy   cachePolicy
+	callers              map[string]bool // allowlisted X-Caller values
+
+	clientRateLimit *clientRateLimiter
+	trustedProxies  []string // peers whose X-Forwarded-For is believed
+
+	erasureWebhook *erasureWebhook  // notified when a data erasure finishes
+	signer         *urlSigner       // nil disables signed URLs
+	requestIDs     IDGenerator      // REQUEST_ID_FORMAT; nil generates random hex IDs
+	schemas        *responseSchemas // strict mode response contract; nil disables validation
+	drift          *driftMonitor    // nil decodes upstream payloads without recording drift
+
+	budgets        map[string]routeBudget // by route
+	enforceBudgets bool                   // fail requests that exceed a budget
+
+	debugHeaders bool   // add X-Served-By, X-Cache and X-Upstream-Duration
+	instanceID   string // X-Served-By
+
+	standby      *standbyController // nil outside blue/green standby mode
+	dedup        *requestDeduper    // nil disables request deduplication
+	routeIndex   map[string]route   // the route registry by routeKey, set by setupRouter
+	workers      *supervisor        // runs background workers; nil runs them unsupervised
+	peerClient   *http.Client       // for other instances of this service
+	cacheImports jobRegistry[*cacheImportJob]
+	generations  *ttlCache[generationDetail]
+	load         *loadTracker
 }
 
 // pokemonResponse is the response model returned by our API.
@@ -38,22 +113,108 @@ type pokemonResponse struct {
 }
 
 // simple in-memory TTL cache
-type cacheEntry struct {
-	value     pokemonResponse
+type cacheEntry[V any] struct {
+	value     V
+	storedAt  time.Time
 	expiresAt time.Time
+	elem      *list.Element // position in lru; nil when unbounded
+}
+
+// ttlCache is a TTL cache, optionally bounded to maxEntries with LRU eviction.
+type ttlCache[V any] struct {
+	mu         sync.RWMutex
+	data       map[string]cacheEntry[V]
+	ttl        time.Duration
+	maxEntries int
+	lru        *list.List // most recently used at the front
+
+	// name labels lookup metrics; metrics is nil for uninstrumented caches
+	name    string
+	metrics *metrics
+}
+
+// pokemonCacheEntry is a cached /pokemon/:name outcome: either a pokemon or
+// a remembered upstream 404.
+type pokemonCacheEntry struct {
+	pokemon    pokemonResponse
+	detail     *pokemonDetail // the decoded upstream payload, for ?expand=; nil when not kept
+	notFound   bool
+	freshUntil time.Time // zero = fresh until the entry expires
+}
+
+// stale reports whether the entry is past its TTL and only kept for
+// stale-while-revalidate.
+func (e pokemonCacheEntry) stale() bool {
+	return !e.freshUntil.IsZero() && time.Now().After(e.freshUntil)
+}
+
+// pokemonCache holds /pokemon/:name responses and negative (404) results.
+type pokemonCache = ttlCache[pokemonCacheEntry]
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return newLRUCache[V](ttl, 0)
 }
 
-type pokemonCache struct {
-	mu   sync.RWMutex
-	data map[string]cacheEntry
-	ttl  time.Duration
+// newLRUCache returns a TTL cache holding at most maxEntries entries; a
+// non-positive maxEntries leaves it unbounded.
+func newLRUCache[V any](ttl time.Duration, maxEntries int) *ttlCache[V] {
+	c := &ttlCache[V]{data: make(map[string]cacheEntry[V]), ttl: ttl}
+	if maxEntries > 0 {
+		c.maxEntries = maxEntries
+		c.lru = list.New()
+	}
+	return c
 }
 
 func newPokemonCache(ttl time.Duration) *pokemonCache {
-	return &pokemonCache{data: make(map[string]cacheEntry), ttl: ttl}
+	return newTTLCache[pokemonCacheEntry](ttl)
+}
+
+// instrument makes lookups count hits, misses and expirations under name.
+func (c *ttlCache[V]) instrument(name string, m *metrics) *ttlCache[V] {
+	c.name, c.metrics = name, m
+	return c
+}
+
+// observe records one lookup result: "hit", "miss" or "expired".
+func (c *ttlCache[V]) observe(result string) {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.cacheLookupsTotal.WithLabelValues(c.name, result).Inc()
+}
+
+// observeEviction records one entry dropped before expiry.
+func (c *ttlCache[V]) observeEviction(reason string) {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.cacheEvictionsTotal.WithLabelValues(c.name, reason).Inc()
 }
 
-func (c *pokemonCache) get(key string) (pokemonResponse, bool) {
+func (c *ttlCache[V]) get(key string) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+	if c.lru != nil {
+		// a hit reorders the LRU list, so bounded caches take the write lock
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entry, ok := c.data[key]
+		if !ok {
+			c.observe("miss")
+			return zero, false
+		}
+		if time.Now().After(entry.expiresAt) {
+			c.deleteLocked(key)
+			c.observe("expired")
+			return zero, false
+		}
+		c.lru.MoveToFront(entry.elem)
+		c.observe("hit")
+		return entry.value, true
+	}
 	c.mu.RLock()
 	entry, ok := c.data[key]
 	c.mu.RUnlock()
@@ -61,18 +222,149 @@ func (c *pokemonCache) get(key string) (pokemonResponse, bool) {
 		if ok {
 			// cleanup expired
 			c.mu.Lock()
-			delete(c.data, key)
+			c.deleteLocked(key)
 			c.mu.Unlock()
+			c.observe("expired")
+		} else {
+			c.observe("miss")
 		}
-		return pokemonResponse{}, false
+		return zero, false
 	}
+	c.observe("hit")
 	return entry.value, true
 }
 
-func (c *pokemonCache) set(key string, value pokemonResponse) {
+func (c *ttlCache[V]) set(key string, value V) {
+	if c == nil {
+		return
+	}
+	c.setTTL(key, value, c.ttl)
+}
+
+// setTTL stores value with a TTL other than the cache default.
+func (c *ttlCache[V]) setTTL(key string, value V, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.putLocked(key, cacheEntry[V]{value: value, storedAt: now, expiresAt: now.Add(ttl)})
+}
+
+// putLocked stores entry under key, evicting beyond maxEntries; c.mu must be
+// held for writing.
+func (c *ttlCache[V]) putLocked(key string, entry cacheEntry[V]) {
+	if c.lru != nil {
+		if old, ok := c.data[key]; ok && old.elem != nil {
+			entry.elem = old.elem
+			c.lru.MoveToFront(old.elem)
+		} else {
+			entry.elem = c.lru.PushFront(key)
+		}
+	}
+	c.data[key] = entry
+	for c.lru != nil && len(c.data) > c.maxEntries {
+		c.deleteLocked(c.lru.Back().Value.(string))
+		c.observeEviction("capacity")
+	}
+}
+
+// cacheItem is a copy of one live entry, used for snapshots.
+type cacheItem[V any] struct {
+	key       string
+	value     V
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// items returns the unexpired entries, least recently used first for bounded
+// caches so that restoring them in order keeps the recency ranking.
+func (c *ttlCache[V]) items() []cacheItem[V] {
+	if c == nil {
+		return nil
+	}
+	now := time.Now()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]cacheItem[V], 0, len(c.data))
+	add := func(k string) {
+		if e := c.data[k]; now.Before(e.expiresAt) {
+			out = append(out, cacheItem[V]{key: k, value: e.value, storedAt: e.storedAt, expiresAt: e.expiresAt})
+		}
+	}
+	if c.lru != nil {
+		for el := c.lru.Back(); el != nil; el = el.Prev() {
+			add(el.Value.(string))
+		}
+		return out
+	}
+	for k := range c.data {
+		add(k)
+	}
+	return out
+}
+
+// restore stores an entry with its original timestamps.
+func (c *ttlCache[V]) restore(it cacheItem[V]) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.putLocked(it.key, cacheEntry[V]{value: it.value, storedAt: it.storedAt, expiresAt: it.expiresAt})
+}
+
+// delete removes key and reports whether it was present.
+func (c *ttlCache[V]) delete(key string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.data[key]
+	c.deleteLocked(key)
+	return ok
+}
+
+// invalidate drops key after the data behind it changed.
+func (c *ttlCache[V]) invalidate(key string) {
+	if c.delete(key) {
+		c.observeEviction("invalidated")
+	}
+}
+
+// flush removes every entry and returns how many were dropped.
+func (c *ttlCache[V]) flush() int {
+	if c == nil {
+		return 0
+	}
 	c.mu.Lock()
-	c.data[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
-	c.mu.Unlock()
+	defer c.mu.Unlock()
+	n := len(c.data)
+	c.data = make(map[string]cacheEntry[V])
+	if c.lru != nil {
+		c.lru.Init()
+	}
+	return n
+}
+
+// len returns the number of stored entries, expired or not.
+func (c *ttlCache[V]) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.data)
+}
+
+// deleteLocked removes key; c.mu must be held for writing.
+func (c *ttlCache[V]) deleteLocked(key string) {
+	if e, ok := c.data[key]; ok && e.elem != nil {
+		c.lru.Remove(e.elem)
+	}
+	delete(c.data, key)
 }
 
 // metrics setup
@@ -81,6 +373,57 @@ type metrics struct {
 	requestDurationSec *prometheus.HistogramVec
 	extCallsTotal      *prometheus.CounterVec
 	extCallDurationSec *prometheus.HistogramVec
+
+	shadowRequestsTotal *prometheus.CounterVec
+	shadowStatusTotal   *prometheus.CounterVec
+
+	upstreamRequestsTotal *prometheus.CounterVec
+	upstreamDurationSec   *prometheus.HistogramVec
+	canaryRolledBack      prometheus.Gauge
+
+	shadowDiffsTotal *prometheus.CounterVec
+
+	sloBurnRate             *prometheus.GaugeVec
+	sloErrorBudgetRemaining *prometheus.GaugeVec
+
+	latencyDegraded *prometheus.GaugeVec
+
+	rateLimitTotal *prometheus.CounterVec
+
+	admissionTotal      *prometheus.CounterVec
+	admissionQueueDepth prometheus.Gauge
+	admissionInFlight   prometheus.Gauge
+
+	storePurgedTotal *prometheus.CounterVec
+
+	janitorSweepDurationSec prometheus.Histogram
+	janitorReclaimedTotal   prometheus.Counter
+	workerRestartsTotal     *prometheus.CounterVec
+	requestsAbortedTotal    *prometheus.CounterVec
+	workerUp                *prometheus.GaugeVec
+
+	responseSizeBytes     *prometheus.HistogramVec
+	responseTooLargeTotal *prometheus.CounterVec
+	schemaViolationsTotal *prometheus.CounterVec
+	upstreamDriftTotal    *prometheus.CounterVec
+	budgetViolationsTotal *prometheus.CounterVec
+	dedupedRequestsTotal  *prometheus.CounterVec
+
+	cacheLookupsTotal   *prometheus.CounterVec
+	cacheEvictionsTotal *prometheus.CounterVec
+
+	upstreamRedirectsTotal *prometheus.CounterVec
+
+	upstreamCircuitState prometheus.Gauge
+
+	upstreamQuotaRemaining *prometheus.GaugeVec
+
+	upstreamInFlight prometheus.Gauge
+
+	upstreamHedgesTotal *prometheus.CounterVec
+
+	upstreamRetriesTotal    *prometheus.CounterVec
+	upstreamRetryBudgetUsed prometheus.Gauge
 }
 
 func newMetrics(reg prometheus.Registerer) *metrics {
@@ -90,11 +433,11 @@ func newMetrics(reg prometheus.Registerer) *metrics {
 	m := &metrics{
 		requestsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{Name: "http_requests_total", Help: "Total HTTP requests"},
-			[]string{"route", "method", "status"},
+			[]string{"route", "method", "status", "caller"},
 		),
 		requestDurationSec: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{Name: "http_request_duration_seconds", Help: "HTTP request duration", Buckets: prometheus.DefBuckets},
-			[]string{"route", "method"},
+			[]string{"route", "method", "caller"},
 		),
 		extCallsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{Name: "external_api_requests_total", Help: "External API requests"},
@@ -104,115 +447,462 @@ func newMetrics(reg prometheus.Registerer) *metrics {
 			prometheus.HistogramOpts{Name: "external_api_request_duration_seconds", Help: "External API call duration", Buckets: prometheus.DefBuckets},
 			[]string{"target"},
 		),
+		shadowRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "shadow_requests_total", Help: "Mirrored shadow upstream requests by comparison result"},
+			[]string{"result"},
+		),
+		shadowStatusTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "shadow_status_total", Help: "Mirrored shadow upstream requests by primary and shadow status"},
+			[]string{"primary", "shadow"},
+		),
+		upstreamRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "upstream_requests_total", Help: "Upstream calls by upstream (primary/canary) and result"},
+			[]string{"upstream", "result"},
+		),
+		upstreamDurationSec: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "upstream_request_duration_seconds", Help: "Upstream call duration by upstream (primary/canary)", Buckets: prometheus.DefBuckets},
+			[]string{"upstream"},
+		),
+		canaryRolledBack: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "canary_rolled_back", Help: "1 if canary traffic was rolled back to primary"},
+		),
+		shadowDiffsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "shadow_diff_responses_total", Help: "Primary/shadow JSON body comparisons by result"},
+			[]string{"result"},
+		),
+		sloBurnRate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "slo_burn_rate", Help: "SLO error budget burn rate over a sliding window"},
+			[]string{"slo", "window"},
+		),
+		sloErrorBudgetRemaining: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "slo_error_budget_remaining", Help: "Fraction of SLO error budget left over the longest window"},
+			[]string{"slo"},
+		),
+		latencyDegraded: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "latency_degraded", Help: "1 if the latency anomaly detector flags the signal as degraded"},
+			[]string{"signal"},
+		),
+		rateLimitTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "rate_limit_decisions_total", Help: "Rate limiter decisions by route group and result"},
+			[]string{"route", "result"},
+		),
+		admissionTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "admission_requests_total", Help: "Concurrency cap decisions by result"},
+			[]string{"result"},
+		),
+		admissionQueueDepth: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "admission_queue_depth", Help: "Requests waiting for a concurrency slot"},
+		),
+		admissionInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "admission_in_flight", Help: "Requests holding a concurrency slot"},
+		),
+		storePurgedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "store_purged_total", Help: "Records permanently removed by the purge job, by kind"},
+			[]string{"kind"},
+		),
+		janitorSweepDurationSec: prometheus.NewHistogram(
+			prometheus.HistogramOpts{Name: "cache_janitor_sweep_duration_seconds", Help: "Cache janitor sweep duration", Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8)},
+		),
+		janitorReclaimedTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{Name: "cache_janitor_reclaimed_entries_total", Help: "Expired cache entries removed by the janitor"},
+		),
+		workerRestartsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "worker_restarts_total", Help: "Background worker restarts after a panic"},
+			[]string{"worker"},
+		),
+		requestsAbortedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "http_requests_aborted_total", Help: "Requests abandoned by the client before the response was written"},
+			[]string{"route"},
+		),
+		workerUp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "worker_up", Help: "1 while a background worker is running, 0 while it is restarting or stopped"},
+			[]string{"worker"},
+		),
+		responseSizeBytes: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "http_response_size_bytes", Help: "HTTP response body size", Buckets: prometheus.ExponentialBuckets(64, 4, 10)},
+			[]string{"route"},
+		),
+		responseTooLargeTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "http_response_too_large_total", Help: "Responses rejected for exceeding the maximum size"},
+			[]string{"route"},
+		),
+		schemaViolationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "schema_violations_total", Help: "Responses replaced in strict mode for violating their schema"},
+			[]string{"route"},
+		),
+		upstreamDriftTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "upstream_schema_drift_total", Help: "Upstream payload fields that drifted from what we decode"},
+			[]string{"kind", "reason"},
+		),
+		budgetViolationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "route_budget_violations_total", Help: "Requests that exceeded a budget of their route"},
+			[]string{"route", "budget"},
+		),
+		dedupedRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "requests_deduplicated_total", Help: "GET requests answered with an identical request's response, by source (in_flight/recent)"},
+			[]string{"route", "source"},
+		),
+		cacheLookupsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "cache_lookups_total", Help: "Cache lookups by cache and result (hit/miss/expired)"},
+			[]string{"cache", "result"},
+		),
+		cacheEvictionsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "cache_evictions_total", Help: "Cache entries dropped before expiry by cache and reason (capacity/invalidated)"},
+			[]string{"cache", "reason"},
+		),
+		upstreamRedirectsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "upstream_redirects_total", Help: "Upstream redirects by result (followed/refused)"},
+			[]string{"result"},
+		),
+		upstreamCircuitState: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "upstream_circuit_state", Help: "Upstream circuit breaker state (0 closed, 1 open, 2 half-open)"},
+		),
+		upstreamQuotaRemaining: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "upstream_quota_remaining", Help: "Upstream calls left in the current quota window"},
+			[]string{"window"},
+		),
+		upstreamInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "upstream_in_flight", Help: "PokeAPI calls holding an upstream bulkhead slot"},
+		),
+		upstreamHedgesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "upstream_hedges_total", Help: "Hedged upstream requests by outcome (fired/primary_won/hedge_won)"},
+			[]string{"outcome"},
+		),
+		upstreamRetriesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "upstream_retries_total", Help: "Upstream retries by retry budget decision (allowed/denied)"},
+			[]string{"result"},
+		),
+		upstreamRetryBudgetUsed: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "upstream_retry_budget_used", Help: "Fraction of the upstream retry budget spent in the current window"},
+		),
 	}
-	reg.MustRegister(m.requestsTotal, m.requestDurationSec, m.extCallsTotal, m.extCallDurationSec)
+	reg.MustRegister(m.requestsTotal, m.requestDurationSec, m.extCallsTotal, m.extCallDurationSec,
+		m.shadowRequestsTotal, m.shadowStatusTotal,
+		m.upstreamRequestsTotal, m.upstreamDurationSec, m.canaryRolledBack,
+		m.shadowDiffsTotal, m.sloBurnRate, m.sloErrorBudgetRemaining,
+		m.latencyDegraded, m.rateLimitTotal, m.admissionTotal, m.admissionQueueDepth, m.admissionInFlight, m.storePurgedTotal,
+		m.janitorSweepDurationSec, m.janitorReclaimedTotal, m.workerRestartsTotal, m.workerUp, m.requestsAbortedTotal,
+		m.responseSizeBytes, m.responseTooLargeTotal, m.schemaViolationsTotal, m.upstreamDriftTotal, m.budgetViolationsTotal, m.dedupedRequestsTotal, m.cacheLookupsTotal, m.cacheEvictionsTotal, m.upstreamRedirectsTotal, m.upstreamCircuitState,
+		m.upstreamQuotaRemaining, m.upstreamInFlight, m.upstreamHedgesTotal,
+		m.upstreamRetriesTotal, m.upstreamRetryBudgetUsed)
 	return m
 }
 
+// pokemonRoute is the route whose cache policy governs cached pokemon.
+const pokemonRoute = "/pokemon/:name"
+
 // setupRouter configures routes and middleware.
 func setupRouter(s *Server) *gin.Engine {
+	rts := s.routes()
+	s.routeIndex = indexRoutes(rts)
 	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(requestIDMiddleware())
+	setTrustedProxies(r, s.trustedProxies)
+	r.Use(recoveryMiddleware())
+	r.Use(requestIDMiddleware(s))
+	r.Use(watermarkMiddleware(s))
+	r.Use(callerMiddleware(s))
+	r.Use(deadlineMiddleware(s))
 	r.Use(accessLogMiddleware(s))
 	r.Use(metricsMiddleware(s))
+	r.Use(standbyMiddleware(s))
+	r.Use(responseLimitMiddleware(s))
+	r.Use(schemaValidationMiddleware(s))
+	r.Use(routeBudgetMiddleware(s))
+	r.Use(plugin.Middlewares()...)
+	r.Use(clientRateLimitMiddleware(s))
+	r.Use(rateLimitMiddleware(s))
+	r.Use(dedupMiddleware(s))
+	r.Use(admissionMiddleware(s))
+	r.Use(loadMiddleware(s))
+	r.Use(scriptMiddleware(s))
 
-	r.GET("/health", func(c *gin.Context) {
-		c.String(http.StatusOK, "ok")
-	})
+	registerRoutes(r, s, rts)
 
-	r.GET("/hello", func(c *gin.Context) {
-		name := c.Query("name")
-		if name == "" {
-			name = "world"
-		}
-		c.JSON(http.StatusOK, gin.H{"message": "hello " + name})
-	})
+	// routes contributed by forks
+	for _, rt := range plugin.Routes() {
+		r.Handle(rt.Method, rt.Path, rt.Handlers...)
+	}
 
-	r.GET("/pokemon/:name", func(c *gin.Context) {
-		name := c.Param("name")
-		if name == "" {
-			writeError(c, http.StatusBadRequest, "bad_request", "name is required")
-			return
+	// everything else: pass-through proxy for PROXY_PREFIXES
+	r.NoRoute(s.proxyHandler)
+
+	return r
+}
+
+// pokemonHandler serves GET /pokemon/:name under its cache policy.
+func (s *Server) pokemonHandler(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		writeError(c, apierror.BadRequest("name is required"))
+		return
+	}
+
+	expand, err := parseExpand(c.Query("expand"))
+	if err != nil {
+		writeError(c, apierror.BadRequest(err.Error()))
+		return
+	}
+
+	policy := s.cachePolicyFor(c.FullPath())
+	e, status, err := s.getPokemonEntry(c.Request.Context(), name, policy, cacheBypassed(c, policy))
+	if err != nil {
+		// normalize status and message
+		writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+		return
+	}
+	p := e.pokemon
+	c.Header("Vary", "Accept-Language")
+	if len(expand) > 0 {
+		s.writeExpandedPokemon(c, name, e, expand)
+		return
+	}
+	if langs := requestedLanguages(c); len(langs) > 0 {
+		lp := s.localizePokemon(c.Request.Context(), p, langs)
+		if lp.DisplayLanguage != "" {
+			c.Header("Content-Language", lp.DisplayLanguage)
 		}
+		c.JSON(http.StatusOK, lp)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// pokemonFetch is the shared outcome of a coalesced pokemon fetch.
+type pokemonFetch struct {
+	detail pokemonDetail
+	status int
+}
+
+// getPokemon returns a pokemon under the /pokemon/:name cache policy.
+func (s *Server) getPokemon(ctx context.Context, name string) (pokemonResponse, int, error) {
+	return s.getPokemonWithPolicy(ctx, name, s.cachePolicyFor(pokemonRoute), false)
+}
 
-		// cache first
+// getPokemonWithPolicy returns a pokemon from the cache, fetching and caching
+// it on a miss (or always, with bypass). Upstream 404s are cached for the
+// policy's negative TTL. Stale entries within the stale-while-revalidate
+// window are returned as-is and refreshed in the background. Concurrent
+// fetches for the same name share one upstream call, which is canceled only
+// once every caller waiting on it has gone; each caller stops waiting when
+// its own context ends.
+func (s *Server) getPokemonWithPolicy(ctx context.Context, name string, p cachePolicy, bypass bool) (pokemonResponse, int, error) {
+	e, status, err := s.getPokemonEntry(ctx, name, p, bypass)
+	return e.pokemon, status, err
+}
+
+// getPokemonEntry is getPokemonWithPolicy returning the whole cache entry,
+// including the upstream payload when it was kept.
+func (s *Server) getPokemonEntry(ctx context.Context, name string, p cachePolicy, bypass bool) (pokemonCacheEntry, int, error) {
+	if !bypass {
 		if v, ok := s.cache.get(name); ok {
-			c.JSON(http.StatusOK, v)
-			return
+			if v.stale() {
+				traceFrom(ctx).cacheResult(cacheStale)
+				go s.refreshPokemon(name, p)
+			} else {
+				traceFrom(ctx).cacheResult(cacheHit)
+			}
+			if v.notFound {
+				return pokemonCacheEntry{}, http.StatusNotFound, errors.New("pokemon not found")
+			}
+			return v, http.StatusOK, nil
 		}
-
-		p, status, err := s.fetchPokemon(c.Request.Context(), name)
-		if err != nil {
-			// normalize status and message
-			if status == http.StatusNotFound {
-				writeError(c, status, "not_found", "pokemon not found")
-				return
+	}
+	traceFrom(ctx).cacheResult(cacheMiss)
+	for retried := false; ; retried = true {
+		ch, leave := s.fetchPokemonShared(ctx, name, p)
+		select {
+		case res := <-ch:
+			leave()
+			f := res.Val.(pokemonFetch)
+			if errors.Is(res.Err, errClientGone) && ctx.Err() == nil && !retried {
+				continue // joined a fetch every other caller had abandoned
 			}
-			writeError(c, status, "upstream_error", err.Error())
-			return
+			if res.Err != nil {
+				return pokemonCacheEntry{}, f.status, res.Err
+			}
+			return newPokemonCacheEntry(f.detail), http.StatusOK, nil
+		case <-ctx.Done():
+			leave()
+			return pokemonCacheEntry{}, http.StatusBadGateway, fmt.Errorf("failed to call upstream: %w", ctx.Err())
 		}
-		s.cache.set(name, p)
-		c.JSON(http.StatusOK, p)
-	})
+	}
+}
+
+// refreshPokemon revalidates a stale entry, detached from any request.
+func (s *Server) refreshPokemon(name string, p cachePolicy) {
+	ch, leave := s.fetchPokemonShared(context.Background(), name, p)
+	<-ch
+	leave()
+}
 
-	// Prometheus metrics endpoint
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+// fetchPokemonShared fetches name once per concurrent caller group and stores
+// the outcome under policy p. The fetch runs on its own context (see
+// flightWaiters), which the caller releases by calling leave once it stops
+// waiting.
+func (s *Server) fetchPokemonShared(ctx context.Context, name string, p cachePolicy) (<-chan singleflight.Result, func()) {
+	fl, leave := s.flights.join(ctx, name)
+	return s.inflight.DoChan(name, func() (any, error) {
+		defer s.flights.done(name, fl)
+		d, status, err := s.fetchPokemon(fl.ctx, name)
+		switch {
+		case err == nil:
+			s.storePokemon(name, newPokemonCacheEntry(d), p.TTL, p)
+		case status == http.StatusNotFound && p.NegativeTTL > 0:
+			s.storePokemon(name, pokemonCacheEntry{notFound: true}, p.NegativeTTL, p)
+		}
+		return pokemonFetch{detail: d, status: status}, err
+	}), leave
+}
 
-	return r
+// storePokemon caches v as fresh for ttl (the cache default when zero) and
+// keeps it for the stale-while-revalidate window beyond that.
+func (s *Server) storePokemon(name string, v pokemonCacheEntry, ttl time.Duration, p cachePolicy) {
+	if s.cache == nil {
+		return
+	}
+	if ttl <= 0 {
+		ttl = s.cache.ttl
+	}
+	v.freshUntil = time.Now().Add(ttl)
+	s.cache.setTTL(name, v, ttl+p.StaleWhileRevalidate)
 }
 
 // HTTP fetch with timeout + retry + metrics
-func (s *Server) fetchPokemon(ctx context.Context, name string) (pokemonResponse, int, error) {
-	url := fmt.Sprintf("%s/pokemon/%s", s.baseURL, name)
+func (s *Server) fetchPokemon(ctx context.Context, name string) (pokemonDetail, int, error) {
+	var d pokemonDetail
+	status, err := s.fetchUpstream(ctx, "/pokemon/"+name, &d)
+	if status == http.StatusNotFound {
+		return pokemonDetail{}, status, errors.New("pokemon not found")
+	}
+	return d, status, err
+}
+
+// fetchUpstream GETs path from the upstream with retry and metrics and decodes
+// a 200 JSON body into out. The returned status is normalized: 200, 404, 429
+// when PokeAPI rate-limits us, 503 while the circuit breaker is open or the
+// call quota is used up, or 502 for everything else. Calls whose client has
+// gone away are not started, or are aborted, without counting against the
+// quota, the circuit breaker or the canary.
+func (s *Server) fetchUpstream(ctx context.Context, path string, out any) (status int, _ error) {
+	if clientGone(ctx) {
+		s.metrics.extCallsTotal.WithLabelValues("pokeapi", "canceled").Inc()
+		return http.StatusBadGateway, errClientGone
+	}
+	if !s.breaker.allow() {
+		s.metrics.extCallsTotal.WithLabelValues("pokeapi", "circuit_open").Inc()
+		return http.StatusServiceUnavailable, errCircuitOpen
+	}
+	if !s.quota.allow() {
+		s.breaker.release()
+		s.metrics.extCallsTotal.WithLabelValues("pokeapi", "quota_exhausted").Inc()
+		return http.StatusServiceUnavailable, errQuotaExhausted
+	}
+	if !s.bulkhead.acquire(ctx) {
+		s.breaker.release()
+		s.metrics.extCallsTotal.WithLabelValues("pokeapi", "too_busy").Inc()
+		return http.StatusServiceUnavailable, errUpstreamBusy
+	}
+	defer s.bulkhead.release()
+	base, upstream := s.upstreamBaseURL()
+	url := base + path
 	const target = "pokeapi"
+	var body []byte
 	start := time.Now()
 	defer func() {
-		s.metrics.extCallDurationSec.WithLabelValues(target).Observe(time.Since(start).Seconds())
+		traceFrom(ctx).upstreamCall(time.Since(start))
+		elapsed := time.Since(start).Seconds()
+		s.metrics.extCallDurationSec.WithLabelValues(target).Observe(elapsed)
+		if clientGone(ctx) {
+			// an abandoned call says nothing about the upstream's health, but
+			// it may hold the half-open probe
+			s.breaker.release()
+			return
+		}
+		s.recordUpstreamOutcome(upstream, status, elapsed)
+		s.shadow.mirror(path, status, body)
 	}()
 
+	s.budget.request()
 	var lastErr error
-	maxAttempts := 3
+	retry := s.retry.withDefaults()
+	maxAttempts := retry.MaxAttempts
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		resp, err := s.httpClient.Do(req)
+		if attempt > 1 {
+			s.quota.count()
+		}
+		attemptStart := time.Now()
+		resp, err := s.hedge.do(ctx, s.httpClient, url, s.quota.count)
 		if err != nil {
-			// retry on temporary network errors
-			if isRetryable(err) && attempt < maxAttempts {
-				backoff(attempt)
+			s.journal.recordError(url, attempt, attemptStart, err)
+			// retry on temporary network errors, unless the caller's deadline is gone
+			if ctx.Err() == nil && isRetryable(err) && attempt < maxAttempts && s.budget.allow() && retry.backoff(ctx, attempt) {
 				lastErr = err
 				continue
 			}
+			if clientGone(ctx) {
+				s.metrics.extCallsTotal.WithLabelValues(target, "canceled").Inc()
+				return http.StatusBadGateway, errClientGone
+			}
 			s.metrics.extCallsTotal.WithLabelValues(target, "error").Inc()
-			return pokemonResponse{}, http.StatusBadGateway, fmt.Errorf("failed to call upstream: %w", err)
+			return http.StatusBadGateway, fmt.Errorf("failed to call upstream: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode == http.StatusOK {
-			var data pokemonResponse
-			if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+			if body, err = io.ReadAll(resp.Body); err == nil {
+				s.journal.recordResponse(url, attempt, attemptStart, resp, body)
+				err = s.drift.decode(path, body, out)
+			}
+			if err != nil {
 				s.metrics.extCallsTotal.WithLabelValues(target, "parse_error").Inc()
-				return pokemonResponse{}, http.StatusBadGateway, fmt.Errorf("failed to parse response: %w", err)
+				return http.StatusBadGateway, fmt.Errorf("failed to parse response: %w", err)
 			}
 			s.metrics.extCallsTotal.WithLabelValues(target, "200").Inc()
-			return data, http.StatusOK, nil
+			return http.StatusOK, nil
 		}
+		// one bounded read of the error body serves the journal and the
+		// error detail
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, max(maxUpstreamErrorBodyBytes, s.journal.bodyLimit())))
+		s.journal.recordResponse(url, attempt, attemptStart, resp, errBody)
 
-		if resp.StatusCode >= 500 && attempt < maxAttempts {
-			// server error: retry
-			backoff(attempt)
+		if (resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests) && attempt < maxAttempts &&
+			s.budget.allow() && sleepCtx(ctx, retry.retryDelay(attempt, resp)) {
+			// server error or upstream rate limit: retry
 			lastErr = fmt.Errorf("upstream status %d", resp.StatusCode)
 			continue
 		}
 		// non-retryable status
 		s.metrics.extCallsTotal.WithLabelValues(target, strconv.Itoa(resp.StatusCode)).Inc()
-		if resp.StatusCode == http.StatusNotFound {
-			return pokemonResponse{}, http.StatusNotFound, errors.New("pokemon not found")
+		detail := s.upstreamErrorDetail(resp.StatusCode, errBody)
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return http.StatusNotFound, withUpstreamDetail(errors.New("resource not found"), detail)
+		case http.StatusTooManyRequests:
+			return http.StatusTooManyRequests, withUpstreamDetail(errors.New("upstream rate limit exceeded"), detail)
 		}
-		return pokemonResponse{}, http.StatusBadGateway, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+		return http.StatusBadGateway, withUpstreamDetail(fmt.Errorf("upstream returned status %d", resp.StatusCode), detail)
 	}
 	s.metrics.extCallsTotal.WithLabelValues(target, "error").Inc()
-	return pokemonResponse{}, http.StatusBadGateway, fmt.Errorf("upstream retries exhausted: %v", lastErr)
+	return http.StatusBadGateway, fmt.Errorf("upstream retries exhausted: %v", lastErr)
+}
+
+// fetchCached returns the upstream resource at path, via cache c under key.
+// It adds caching to fetchUpstream's retries and metrics for any resource
+// type; failures are not cached.
+func fetchCached[V any](ctx context.Context, s *Server, c *ttlCache[V], key, path string) (V, int, error) {
+	if v, ok := c.get(key); ok {
+		traceFrom(ctx).cacheResult(cacheHit)
+		return v, http.StatusOK, nil
+	}
+	traceFrom(ctx).cacheResult(cacheMiss)
+	var v V
+	status, err := s.fetchUpstream(ctx, path, &v)
+	if err != nil {
+		var zero V
+		return zero, status, err
+	}
+	c.set(key, v)
+	return v, http.StatusOK, nil
 }
 
 func isRetryable(err error) bool {
@@ -223,16 +913,94 @@ func isRetryable(err error) bool {
 	return true // treat unknown transport errors as retryable
 }
 
-func backoff(attempt int) {
-	// exponential backoff with jitter, base 100ms
-	base := 100 * time.Millisecond
-	max := 1 * time.Second
-	d := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
-	if d > max {
-		d = max
+// retryPolicy configures upstream retries. Zero MaxAttempts, BaseBackoff,
+// MaxBackoff and MaxRetryAfter take the defaults; a zero Jitter disables
+// jitter.
+type retryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Jitter      time.Duration // up to this much is subtracted from each delay
+
+	MaxRetryAfter time.Duration // cap on an honored upstream Retry-After
+}
+
+var defaultRetryPolicy = retryPolicy{MaxAttempts: 3, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second,
+	Jitter: 30 * time.Millisecond, MaxRetryAfter: 5 * time.Second}
+
+func (p retryPolicy) withDefaults() retryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = defaultRetryPolicy.BaseBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultRetryPolicy.MaxBackoff
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = defaultRetryPolicy.MaxRetryAfter
+	}
+	return p
+}
+
+// delay returns the exponential backoff before the attempt after attempt,
+// with jitter.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := time.Duration(float64(p.BaseBackoff) * math.Pow(2, float64(attempt-1)))
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(randByte()) * p.Jitter / 256
+	}
+	return max(d, 0)
+}
+
+// backoff waits before the attempt after attempt; see sleepCtx.
+func (p retryPolicy) backoff(ctx context.Context, attempt int) bool {
+	return sleepCtx(ctx, p.delay(attempt))
+}
+
+// retryDelay is the wait before retrying a failed response: the upstream's
+// Retry-After on 429/503, capped at MaxRetryAfter, or the usual backoff.
+func (p retryPolicy) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(d, p.MaxRetryAfter)
+		}
+	}
+	return p.delay(attempt)
+}
+
+// parseRetryAfter parses a Retry-After value in delay-seconds or HTTP-date form.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleepCtx sleeps for d unless ctx ends first. It returns false without
+// sleeping when ctx's deadline would pass before d elapses.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
-	// small jitter
-	time.Sleep(d - time.Duration(randByte()%30)*time.Millisecond)
 }
 
 func randByte() byte {
@@ -244,11 +1012,11 @@ func randByte() byte {
 }
 
 // middleware: request ID
-func requestIDMiddleware() gin.HandlerFunc {
+func requestIDMiddleware(s *Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		rid := c.GetHeader("X-Request-ID")
 		if rid == "" {
-			rid = genRequestID()
+			rid = s.newRequestID()
 		}
 		c.Set("request_id", rid)
 		c.Writer.Header().Set("X-Request-ID", rid)
@@ -273,41 +1041,76 @@ func accessLogMiddleware(s *Server) gin.HandlerFunc {
 		c.Next()
 		rid, _ := c.Get("request_id")
 		status := c.Writer.Status()
-		route := c.FullPath()
-		if route == "" {
-			route = c.Request.URL.Path
+		route := s.routeLabel(c)
+		if by := impersonator(c); by != "" {
+			log.Printf("rid=%v caller=%s method=%s route=%s status=%d duration=%s impersonator=%s", rid, callerLabel(c), c.Request.Method, route, status, time.Since(start), by)
+			return
 		}
-		log.Printf("rid=%v method=%s route=%s status=%d duration=%s", rid, c.Request.Method, route, status, time.Since(start))
+		log.Printf("rid=%v caller=%s method=%s route=%s status=%d duration=%s", rid, callerLabel(c), c.Request.Method, route, status, time.Since(start))
 	}
 }
 
+// metricsHandler serves the default registry. Compression is negotiated via
+// Accept-Encoding and the OpenMetrics format via Accept.
+func metricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics: true,
+		}),
+	)
+}
+
 // middleware: record metrics per request
 func metricsMiddleware(s *Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		route := c.FullPath()
-		if route == "" {
-			route = c.Request.URL.Path
-		}
+		route := s.routeLabel(c)
 		method := c.Request.Method
 		start := time.Now()
 		c.Next()
-		duration := time.Since(start).Seconds()
+		elapsed := time.Since(start)
+		duration := elapsed.Seconds()
+		caller := callerLabel(c)
+		if clientGone(c.Request.Context()) {
+			// the client left before the response; keep it out of the SLO
+			// and latency signals
+			s.metrics.requestsAbortedTotal.WithLabelValues(route).Inc()
+			s.metrics.requestsTotal.WithLabelValues(route, method, strconv.Itoa(statusClientClosedRequest), caller).Inc()
+			s.metrics.requestDurationSec.WithLabelValues(route, method, caller).Observe(duration)
+			return
+		}
 		status := strconv.Itoa(c.Writer.Status())
-		s.metrics.requestsTotal.WithLabelValues(route, method, status).Inc()
-		s.metrics.requestDurationSec.WithLabelValues(route, method).Observe(duration)
+		s.metrics.requestsTotal.WithLabelValues(route, method, status, caller).Inc()
+		s.metrics.requestDurationSec.WithLabelValues(route, method, caller).Observe(duration)
+		if size := c.Writer.Size(); size >= 0 {
+			s.metrics.responseSizeBytes.WithLabelValues(route).Observe(float64(size))
+		}
+		s.slo.observe(route, c.Writer.Status(), elapsed)
+		s.anomaly.observe("request", duration)
 	}
 }
 
 // unified error writer
-func writeError(c *gin.Context, code int, errCode, msg string) {
+func writeError(c *gin.Context, e *apierror.Error) {
 	rid, _ := c.Get("request_id")
-	c.JSON(code, gin.H{
-		"error": gin.H{
-			"code":       errCode,
-			"message":    msg,
-			"request_id": rid,
-		},
-	})
+	body := gin.H{
+		"code":       e.Code,
+		"message":    e.Message,
+		"request_id": rid,
+	}
+	if e.Upstream != nil {
+		body["upstream_status"] = e.Upstream.Status
+		body["upstream_message"] = e.Upstream.Message
+	}
+	if e.IncidentID != "" {
+		body["incident_id"] = e.IncidentID
+	}
+	c.JSON(e.Status, gin.H{"error": body})
+}
+
+// errorCatalogHandler documents every error code clients may receive.
+func errorCatalogHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"errors": apierror.Catalog()})
 }
 
 func getenv(key, def string) string {
@@ -326,15 +1129,273 @@ func getenvInt(key string, def int) int {
 	return def
 }
 
-func main() {
+func getenvFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+func getenvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// splitList parses a comma-separated env value, dropping empty items.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// newServerFromEnv builds the server from environment configuration.
+func newServerFromEnv() *Server {
 	timeoutSec := getenvInt("HTTP_TIMEOUT_SEC", 5)
 	cacheTTL := time.Duration(getenvInt("POKEMON_CACHE_TTL_SEC", 300)) * time.Second
+	timeout := time.Duration(timeoutSec) * time.Second
+	client := &http.Client{Timeout: timeout}
+	m := newMetrics(prometheus.DefaultRegisterer)
+	baseURL := getenv("POKEAPI_BASE_URL", "https://pokeapi.co/api/v2")
+	egress, err := newEgressPolicy(splitList(getenv("UPSTREAM_ALLOWED_HOSTS", "")),
+		getenvBool("UPSTREAM_ALLOW_PRIVATE", false), splitList(getenv("UPSTREAM_ALLOWED_CIDRS", "")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := egress.checkBaseURLs(baseURL, getenv("SHADOW_BASE_URL", ""), getenv("CANARY_BASE_URL", "")); err != nil {
+		log.Fatal(err)
+	}
+	egress.apply(client)
+	hedge, err := newUpstreamHedger(getenv("UPSTREAM_HEDGE_AFTER", ""), m)
+	if err != nil {
+		log.Fatal(err)
+	}
+	requestIDs, err := newIDGenerator(getenv("REQUEST_ID_FORMAT", idFormatHex), getenvInt("REQUEST_ID_NODE", 0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	newRedirectPolicy(getenvInt("UPSTREAM_MAX_REDIRECTS", 10), splitList(getenv("UPSTREAM_REDIRECT_HOSTS", "")),
+		getenvBool("UPSTREAM_REDIRECT_FORWARD_HEADERS", true), m).apply(client)
+	shadow := newShadowMirror(client, getenv("SHADOW_BASE_URL", ""), getenvInt("SHADOW_PERCENT", 10), timeout, m)
+	if shadow != nil && getenvBool("SHADOW_DIFF", false) {
+		shadow.differ = newShadowDiffer(splitList(getenv("SHADOW_DIFF_IGNORE", "")), getenvInt("SHADOW_DIFF_SAMPLES", 20), m)
+	}
+	// migrations run here, before the server accepts traffic, unless operators
+	// run "migrate up" themselves
+	store, err := storage.Open(context.Background(), getenv("STORAGE_DRIVER", storage.DriverMemory), getenv("STORAGE_DSN", ""),
+		getenvBool("STORAGE_AUTO_MIGRATE", true))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if key := getenv("ADMIN_API_KEY", ""); key != "" {
+		if err := seedAdminKey(context.Background(), store, key); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, inMemory := store.(*storage.Memory); !inMemory {
+		store = newCachedStore(store, time.Duration(getenvInt("STORAGE_CACHE_TTL_SEC", 30))*time.Second,
+			getenvInt("STORAGE_CACHE_MAX_ENTRIES", 10000), m)
+	}
 
 	s := &Server{
-		httpClient: &http.Client{Timeout: time.Duration(timeoutSec) * time.Second},
-		cache:      newPokemonCache(cacheTTL),
-		metrics:    newMetrics(prometheus.DefaultRegisterer),
-		baseURL:    getenv("POKEAPI_BASE_URL", "https://pokeapi.co/api/v2"),
+		httpClient: client,
+		store:      store,
+		cache:      newLRUCache[pokemonCacheEntry](cacheTTL, getenvInt("POKEMON_CACHE_MAX_ENTRIES", 0)).instrument("pokemon", m),
+		details:    newTTLCache[pokemonDetail](cacheTTL).instrument("pokemon_detail", m),
+		types:      newTTLCache[typeDetail](time.Duration(getenvInt("TYPE_CHART_TTL_SEC", 86400))*time.Second).instrument("types", m),
+		species:    newTTLCache[speciesDetail](cacheTTL).instrument("species", m),
+		abilities:  newTTLCache[abilityDetail](cacheTTL).instrument("ability", m),
+		moves:      newTTLCache[moveDetail](cacheTTL).instrument("move", m),
+		items:      newTTLCache[itemDetail](cacheTTL).instrument("item", m),
+		berries:    newTTLCache[berryDetail](cacheTTL).instrument("berry", m),
+		encounters: newTTLCache[[]encounterArea](cacheTTL).instrument("encounters", m),
+		chains:     newTTLCache[evolutionChain](cacheTTL).instrument("evolution_chain", m),
+		lists:      newTTLCache[resourceList](time.Duration(getenvInt("POKEMON_LIST_CACHE_TTL_SEC", 3600))*time.Second).instrument("pokemon_list", m),
+		metrics:    m,
+		baseURL:    baseURL,
+		shadow:     shadow,
+		canary: newCanaryRouter(baseURL, getenv("CANARY_BASE_URL", ""), getenvInt("CANARY_PERCENT", 5),
+			getenvFloat("CANARY_MAX_ERROR_RATE", 0.2), getenvInt("CANARY_MIN_REQUESTS", 20), m),
+		retry: retryPolicy{
+			MaxAttempts: getenvInt("UPSTREAM_RETRY_ATTEMPTS", 3),
+			BaseBackoff: time.Duration(getenvInt("UPSTREAM_RETRY_BASE_MS", 100)) * time.Millisecond,
+			MaxBackoff:  time.Duration(getenvInt("UPSTREAM_RETRY_MAX_MS", 1000)) * time.Millisecond,
+			Jitter:      time.Duration(getenvInt("UPSTREAM_RETRY_JITTER_MS", 30)) * time.Millisecond,
+
+			MaxRetryAfter: time.Duration(getenvInt("UPSTREAM_RETRY_AFTER_MAX_MS", 5000)) * time.Millisecond,
+		},
+		budget: newRetryBudget(getenvFloat("UPSTREAM_RETRY_BUDGET_RATIO", 0.1), getenvFloat("UPSTREAM_RETRY_BUDGET_MIN_PER_SEC", 1),
+			time.Duration(getenvInt("UPSTREAM_RETRY_BUDGET_WINDOW_SEC", 10))*time.Second, m),
+		quota: newUpstreamQuota(getenvInt("UPSTREAM_QUOTA_HOURLY", 0), getenvInt("UPSTREAM_QUOTA_DAILY", 0),
+			getenvFloat("UPSTREAM_QUOTA_THROTTLE_AT", 0.9), m),
+		hedge: hedge,
+		bulkhead: newUpstreamBulkhead(getenvInt("UPSTREAM_MAX_IN_FLIGHT", 0),
+			time.Duration(getenvInt("UPSTREAM_MAX_WAIT_MS", 500))*time.Millisecond, m),
+		breaker: newCircuitBreaker(getenvFloat("CIRCUIT_BREAKER_ERROR_RATE", 0), getenvInt("CIRCUIT_BREAKER_MIN_REQUESTS", 20),
+			time.Duration(getenvInt("CIRCUIT_BREAKER_OPEN_SEC", 30))*time.Second, m),
+		slo:             newSLOTracker(parseSLOs(getenv("SLOS", "")), parseDurations(getenv("SLO_WINDOWS", "5m,1h,24h")), m),
+		rateLimit:       newRateLimiter(parseRouteLimits(getenv("RATE_LIMITS", ""))),
+		clientRateLimit: newClientRateLimiter(getenvFloat("CLIENT_RATE_LIMIT", 0), getenvInt("CLIENT_RATE_BURST", 20)),
+		trustedProxies:  splitList(getenv("TRUSTED_PROXIES", "")),
+		admission: newAdmissionController(getenvInt("MAX_CONCURRENT_REQUESTS", 0), getenvInt("REQUEST_QUEUE_SIZE", 16),
+			time.Duration(getenvInt("REQUEST_QUEUE_MAX_WAIT_MS", 250))*time.Millisecond, m),
+		names:     newNameIndex(time.Duration(getenvInt("NAME_INDEX_TTL_SEC", 3600)) * time.Second),
+		exporter:  newPokedexExporter(getenvInt("EXPORT_WORKERS", 8), getenvInt("EXPORT_MAX_ROWS", 2000)),
+		journal:   newUpstreamJournal(getenvInt("UPSTREAM_JOURNAL_SIZE", 0), getenvInt("UPSTREAM_JOURNAL_BODY_BYTES", 4096)),
+		dailySeed: getenv("DAILY_POKEMON_SEED", ""),
+		dexSize:   getenvInt("DAILY_POKEMON_MAX_ID", defaultDexSize),
+		signer:    newURLSigner(getenv("SIGNED_URL_SECRET", "")),
+		drift:     newDriftMonitor(m),
+		erasureWebhook: newErasureWebhook(getenv("DATA_ERASURE_WEBHOOK_URL", ""),
+			time.Duration(getenvInt("DATA_ERASURE_WEBHOOK_TIMEOUT_SEC", 10))*time.Second),
+		proxy: newReverseProxy(splitList(getenv("PROXY_PREFIXES", "")), getenv("PROXY_PUBLIC_URL", ""),
+			newLRUCache[encodedBody](time.Duration(getenvInt("PROXY_CACHE_TTL_SEC", 300))*time.Second,
+				getenvInt("PROXY_CACHE_MAX_ENTRIES", 1000)).instrument("proxy", m)),
+		generations: newTTLCache[generationDetail](time.Duration(getenvInt("GENERATION_CACHE_TTL_SEC", 604800))*time.Second).instrument("generation", m),
+		load:        newLoadTracker(getenvInt("LOAD_TARGET_IN_FLIGHT", 50), getenvFloat("LOAD_SCALE_DOWN_UTILIZATION", 0.3)),
+		sprites: newSpriteCache(newLRUCache[spriteImage](time.Duration(getenvInt("SPRITE_CACHE_TTL_SEC", 86400))*time.Second,
+			getenvInt("SPRITE_CACHE_MAX_ENTRIES", 200)).instrument("sprite", m), getenv("SPRITE_CACHE_DIR", "")),
+		peerClient: newPeerClient(getenv("PEER_API_KEY", "")),
+		requestIDs: requestIDs,
+		workers:    newSupervisor(m),
+		dedup:      newRequestDeduper(time.Duration(getenvInt("DEDUP_WINDOW_MS", 0)) * time.Millisecond),
+
+		maxResponseBytes: getenvInt("MAX_RESPONSE_BYTES", 0),
+		budgets:          parseRouteBudgets(getenv("ROUTE_BUDGETS", "")),
+		enforceBudgets:   getenvBool("ROUTE_BUDGETS_ENFORCE", false),
+		maxDeadline:      time.Duration(getenvInt("REQUEST_MAX_DEADLINE_MS", 30000)) * time.Millisecond,
+
+		upstreamErrorDetails: getenvBool("UPSTREAM_ERROR_DETAILS", false),
+		callers:              newCallerAllowlist(splitList(getenv("CALLER_ALLOWLIST", ""))),
+
+		debugHeaders: getenvBool("DEBUG_HEADERS", false),
+		instanceID:   getenv("INSTANCE_ID", defaultInstanceID()),
+	}
+	s.defaultCachePolicy = cachePolicy{TTL: cacheTTL, NegativeTTL: time.Duration(getenvInt("POKEMON_NOT_FOUND_TTL_SEC", 30)) * time.Second}
+	s.cachePolicies = parseCachePolicies(getenv("CACHE_POLICIES", ""), s.defaultCachePolicy)
+	if getenvBool("ANOMALY_DETECTION", true) {
+		s.anomaly = newLatencyMonitor(getenvFloat("ANOMALY_EWMA_ALPHA", 0.05), getenvFloat("ANOMALY_ZSCORE", 3),
+			getenvInt("ANOMALY_WARMUP", 30), getenvInt("ANOMALY_TRIGGER", 5), m)
+	}
+	if getenvBool("STRICT_RESPONSE_VALIDATION", false) {
+		schemas, err := loadResponseSchemas(responseSchemasJSON)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.schemas = schemas
+		log.Printf("strict mode: validating responses against their schemas")
+	}
+	s.slo.start(s.workers, 15*time.Second)
+	scripts, err := loadScriptHooks(parseScriptHooks(getenv("SCRIPT_HOOKS", "")),
+		time.Duration(getenvInt("SCRIPT_TIMEOUT_MS", 50))*time.Millisecond)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.scripts = scripts
+	if len(scripts) > 0 {
+		log.Printf("script hooks loaded for %v", scriptRoutes(scripts))
+	}
+	return s
+}
+
+// newSnapshotterFromEnv returns the configured cache snapshotter, after
+// restoring the snapshot unless CACHE_SNAPSHOT_SKIP_LOAD is set.
+func newSnapshotterFromEnv(s *Server) *cacheSnapshotter {
+	snapshotter := newCacheSnapshotter(s.cache, getenv("CACHE_SNAPSHOT_PATH", ""),
+		time.Duration(getenvInt("CACHE_SNAPSHOT_INTERVAL_SEC", 60))*time.Second,
+		time.Duration(getenvInt("CACHE_SNAPSHOT_MAX_AGE_SEC", 0))*time.Second)
+	if snapshotter != nil && !getenvBool("CACHE_SNAPSHOT_SKIP_LOAD", false) {
+		if n, err := snapshotter.load(); err != nil {
+			log.Printf("cache snapshot: load failed: %v", err)
+		} else {
+			log.Printf("cache snapshot: restored %d entries", n)
+		}
+	}
+	return snapshotter
+}
+
+func main() {
+	selftest := false
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "warm":
+			os.Exit(warmCommand(os.Args[2:]))
+		case "migrate":
+			os.Exit(migrateCommand(os.Args[2:]))
+		case "serve":
+			var err error
+			if selftest, err = parseServeArgs(os.Args[2:], os.Stderr); err != nil {
+				os.Exit(2)
+			}
+		}
+	}
+
+	s := newServerFromEnv()
+	if selftest && !s.runSelftest(os.Stdout) {
+		os.Exit(1)
+	}
+	m := s.metrics
+	janitorInterval := time.Duration(getenvInt("CACHE_JANITOR_INTERVAL_SEC", 60)) * time.Second
+	janitorBatch := getenvInt("CACHE_JANITOR_BATCH_SIZE", 256)
+	janitorMaxKeys := getenvInt("CACHE_JANITOR_MAX_SWEEP_KEYS", 4096)
+	janitorMaxSweep := time.Duration(getenvInt("CACHE_JANITOR_MAX_SWEEP_MS", 50)) * time.Millisecond
+	newCacheJanitor(s.cache, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.details, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.species, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.lists, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.abilities, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.moves, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.items, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.berries, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.encounters, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.chains, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.generations, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	newCacheJanitor(s.sprites.mem, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	if s.proxy != nil {
+		newCacheJanitor(s.proxy.cache, janitorInterval, janitorBatch, janitorMaxKeys, janitorMaxSweep, m).start(s.workers)
+	}
+	newStorePurger(s.store, time.Duration(getenvInt("STORAGE_DELETED_RETENTION_DAYS", 30))*24*time.Hour,
+		getenvInt("STORAGE_USAGE_RETENTION_MONTHS", 13),
+		time.Duration(getenvInt("STORAGE_PURGE_INTERVAL_SEC", 3600))*time.Second, m).start(s.workers)
+
+	newSnapshotterFromEnv(s).start(s.workers)
+	s.startNameIndexSync(time.Duration(getenvInt("NAME_INDEX_REFRESH_SEC", 1800)) * time.Second)
+
+	warmup := splitList(getenv("CACHE_WARMUP", ""))
+	if path := getenv("CACHE_WARMUP_FILE", ""); path != "" {
+		names, err := readWarmupFile(path)
+		if err != nil {
+			log.Printf("cache warm-up: %v", err)
+		}
+		warmup = append(warmup, names...)
+	}
+	if getenvBool("STANDBY_MODE", false) {
+		// the standby warm-up covers the warm-up list too
+		peer := getenv("STANDBY_PEER_URL", "")
+		source := "snapshot"
+		if peer != "" {
+			source = "peer"
+		}
+		s.standby = newStandbyController(getenvFloat("STANDBY_TARGET_HIT_RATIO", 0.9), getenvInt("CACHE_WARMUP_WORKERS", 4),
+			getenv("CACHE_SNAPSHOT_PATH", ""), warmup)
+		if err := s.standby.startWarm(); err == nil {
+			go s.runStandbyWarm(context.Background(), source, peer)
+		}
+	} else if len(warmup) > 0 {
+		// runs in the background so startup is never blocked on PokeAPI
+		go func() {
+			n := s.warmCache(context.Background(), warmup, getenvInt("CACHE_WARMUP_WORKERS", 4))
+			log.Printf("cache warm-up: %d of %d pokemon cached", n, len(warmup))
+		}()
 	}
 
 	r := setupRouter(s)
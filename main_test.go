@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestHealth(t *testing.T) {
@@ -76,3 +82,319 @@ func TestMetricsEndpoint(t *testing.T) {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
 }
+
+func TestMetricsEndpointGzip(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := &Server{httpClient: &http.Client{}, cache: newPokemonCache(0), metrics: newMetrics(reg), baseURL: ""}
+	r := setupRouter(s)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", enc)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Fatalf("expected openmetrics content type, got %q", ct)
+	}
+}
+
+func TestDocsPlayground(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := &Server{httpClient: &http.Client{}, cache: newPokemonCache(0), metrics: newMetrics(reg), baseURL: ""}
+	r := setupRouter(s)
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/playground", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected html content type, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "/pokemon/pikachu") {
+		t.Fatalf("expected playground examples in body")
+	}
+}
+
+// fakePokeAPI serves canned upstream payloads keyed by request path (with
+// query), and 404 for anything else.
+func fakePokeAPI(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.RequestURI()]
+		if !ok {
+			body, ok = routes[r.URL.Path]
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "Not Found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// newTestServer builds a Server against baseURL with a private registry.
+func newTestServer(baseURL string) *Server {
+	return &Server{
+		httpClient: &http.Client{},
+		cache:      newPokemonCache(time.Minute),
+		metrics:    newMetrics(prometheus.NewRegistry()),
+		baseURL:    baseURL,
+	}
+}
+
+func TestErrorCatalog(t *testing.T) {
+	r := setupRouter(newTestServer(""))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/errors", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var body struct {
+		Errors []struct {
+			Code   string `json:"code"`
+			Status int    `json:"status"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	found := false
+	for _, e := range body.Errors {
+		if e.Code == "not_found" && e.Status == http.StatusNotFound {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected not_found in catalog: %+v", body.Errors)
+	}
+}
+
+func TestCacheLRUEviction(t *testing.T) {
+	c := newLRUCache[int](time.Minute, 2)
+	c.set("a", 1)
+	c.set("b", 2)
+	if _, ok := c.get("a"); !ok { // a becomes most recently used
+		t.Fatal("expected a to be cached")
+	}
+	c.set("c", 3)
+	if _, ok := c.get("b"); ok {
+		t.Fatal("expected b to be evicted as least recently used")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected a to survive eviction")
+	}
+	c.set("a", 10) // overwrite must not grow the cache
+	if n := c.len(); n != 2 {
+		t.Fatalf("expected 2 entries, got %d", n)
+	}
+	if v, _ := c.get("a"); v != 10 {
+		t.Fatalf("expected updated value, got %d", v)
+	}
+}
+
+func TestCacheLookupMetrics(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	c := newPokemonCache(time.Minute).instrument("pokemon", m)
+	c.set("pikachu", pokemonCacheEntry{pokemon: pokemonResponse{Name: "pikachu"}})
+	c.get("pikachu")
+	c.get("eevee")
+	c.mu.Lock()
+	c.data["ditto"] = cacheEntry[pokemonCacheEntry]{expiresAt: time.Now().Add(-time.Second)}
+	c.mu.Unlock()
+	c.get("ditto")
+
+	for result, want := range map[string]float64{"hit": 1, "miss": 1, "expired": 1} {
+		if v := testutil.ToFloat64(m.cacheLookupsTotal.WithLabelValues("pokemon", result)); v != want {
+			t.Fatalf("expected %s=%v, got %v", result, want, v)
+		}
+	}
+}
+
+func TestGetPokemonCoalescesConcurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		fmt.Fprint(w, `{"name":"pikachu","height":4}`)
+	}))
+	defer ts.Close()
+
+	s := &Server{httpClient: ts.Client(), cache: newPokemonCache(time.Minute), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL}
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, _, err := s.getPokemon(context.Background(), "pikachu")
+			if err == nil && p.Name != "pikachu" {
+				err = fmt.Errorf("unexpected pokemon %+v", p)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // let every caller join the flight
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", n)
+	}
+}
+
+func TestSharedFetchSurvivesFirstCallerCancel(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-release
+		fmt.Fprint(w, `{"name":"pikachu","height":4}`)
+	}))
+	defer ts.Close()
+	s := &Server{httpClient: ts.Client(), cache: newPokemonCache(time.Minute), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := s.getPokemon(ctx, "pikachu")
+		first <- err
+	}()
+	<-started
+	second := make(chan error, 1)
+	go func() {
+		p, status, err := s.getPokemon(context.Background(), "pikachu")
+		if err == nil && (status != http.StatusOK || p.Name != "pikachu") {
+			err = fmt.Errorf("unexpected %d %+v", status, p)
+		}
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond) // let the second caller join the flight
+	cancel()
+	if err := <-first; err == nil {
+		t.Fatal("expected the canceled caller to stop waiting")
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Fatalf("expected the second caller to get the pokemon, got %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", n)
+	}
+}
+
+func TestPokemonNotFoundIsCached(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	s := &Server{httpClient: ts.Client(), cache: newPokemonCache(time.Minute), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL, defaultCachePolicy: cachePolicy{NegativeTTL: time.Minute}}
+	r := setupRouter(s)
+	for range 2 {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno", nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected the 404 to be served from cache, got %d upstream calls", n)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := retryPolicy{BaseBackoff: 10 * time.Millisecond, MaxBackoff: 25 * time.Millisecond}.withDefaults()
+	if p.MaxAttempts != defaultRetryPolicy.MaxAttempts {
+		t.Fatalf("expected default attempts, got %d", p.MaxAttempts)
+	}
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 5: 25 * time.Millisecond} {
+		if d := p.delay(attempt); d != want {
+			t.Fatalf("attempt %d: expected %v, got %v", attempt, want, d)
+		}
+	}
+
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	s := &Server{httpClient: ts.Client(), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL,
+		retry: retryPolicy{MaxAttempts: 5, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+	if status, _ := s.fetchUpstream(context.Background(), "/pokemon/x", &pokemonResponse{}); status != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", status)
+	}
+	if n := calls.Load(); n != 5 {
+		t.Fatalf("expected 5 attempts, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sleepCtx(ctx, time.Minute) {
+		t.Fatal("expected a cancelled context to cut the sleep short")
+	}
+}
+
+func TestUpstreamRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for v, want := range map[string]time.Duration{"3": 3 * time.Second, now.Add(2 * time.Second).Format(http.TimeFormat): 2 * time.Second, "-1": 0} {
+		if d, ok := parseRetryAfter(v, now); !ok || d != want {
+			t.Fatalf("%q: expected %v, got %v %v", v, want, d, ok)
+		}
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Fatal("expected invalid Retry-After to be rejected")
+	}
+	p := retryPolicy{MaxRetryAfter: time.Second}.withDefaults()
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"120"}}}
+	if d := p.retryDelay(1, resp); d != time.Second {
+		t.Fatalf("expected Retry-After capped at 1s, got %v", d)
+	}
+
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 || r.URL.Path == "/pokemon/limited" {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"name":"pikachu"}`)
+	}))
+	defer ts.Close()
+	s := &Server{httpClient: ts.Client(), cache: newPokemonCache(0), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL}
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil))
+	if w.Code != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("expected a retry after 429, got %d after %d calls", w.Code, calls.Load())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/limited", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "upstream_rate_limited") {
+		t.Fatalf("expected 503 upstream_rate_limited, got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// typeDetail is the subset of the upstream type payload we use: one row of
+// the type chart.
+type typeDetail struct {
+	Name            string `json:"name"`
+	DamageRelations struct {
+		DoubleDamageFrom []namedResource `json:"double_damage_from"`
+		DoubleDamageTo   []namedResource `json:"double_damage_to"`
+		HalfDamageFrom   []namedResource `json:"half_damage_from"`
+		HalfDamageTo     []namedResource `json:"half_damage_to"`
+		NoDamageFrom     []namedResource `json:"no_damage_from"`
+		NoDamageTo       []namedResource `json:"no_damage_to"`
+	} `json:"damage_relations"`
+	Pokemon []struct {
+		Slot    int           `json:"slot"`
+		Pokemon namedResource `json:"pokemon"`
+	} `json:"pokemon"`
+}
+
+// fetchType returns a type chart row, via the s.types cache.
+func (s *Server) fetchType(ctx context.Context, name string) (typeDetail, int, error) {
+	return fetchCached(ctx, s, s.types, name, "/type/"+name)
+}
+
+type matchupEntry struct {
+	Type       string   `json:"type"`
+	Multiplier float64  `json:"multiplier"`
+	Via        []string `json:"via,omitempty"`
+}
+
+type matchupsResponse struct {
+	Name          string         `json:"name"`
+	Types         []string       `json:"types"`
+	StrongAgainst []matchupEntry `json:"strong_against"`
+	WeakAgainst   []matchupEntry `json:"weak_against"`
+}
+
+// weaknesses returns the attacking types that deal more than normal damage
+// to a defender of the given types, strongest first. Multipliers of dual
+// types combine (2 x 2 = 4, 2 x 0.5 = 1).
+func weaknesses(defender []typeDetail) []matchupEntry {
+	mult := map[string]float64{}
+	apply := func(rs []namedResource, f float64) {
+		for _, r := range rs {
+			if _, ok := mult[r.Name]; !ok {
+				mult[r.Name] = 1
+			}
+			mult[r.Name] *= f
+		}
+	}
+	for _, t := range defender {
+		apply(t.DamageRelations.DoubleDamageFrom, 2)
+		apply(t.DamageRelations.HalfDamageFrom, 0.5)
+		apply(t.DamageRelations.NoDamageFrom, 0)
+	}
+	out := []matchupEntry{}
+	for name, m := range mult {
+		if m > 1 {
+			out = append(out, matchupEntry{Type: name, Multiplier: m})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Multiplier != out[j].Multiplier {
+			return out[i].Multiplier > out[j].Multiplier
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
+
+// strengths returns the defending types that at least one of the attacker's
+// types hits super-effectively, ranked by how many of its types do.
+func strengths(attacker []typeDetail) []matchupEntry {
+	via := map[string][]string{}
+	for _, t := range attacker {
+		for _, r := range t.DamageRelations.DoubleDamageTo {
+			via[r.Name] = append(via[r.Name], t.Name)
+		}
+	}
+	out := []matchupEntry{}
+	for name, v := range via {
+		out = append(out, matchupEntry{Type: name, Multiplier: 2, Via: v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].Via) != len(out[j].Via) {
+			return len(out[i].Via) > len(out[j].Via)
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
+
+// matchupsHandler serves GET /pokemon/:name/matchups.
+func (s *Server) matchupsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	p, status, err := s.fetchPokemonDetail(ctx, c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+		return
+	}
+
+	names := p.typeNames()
+	chart := make([]typeDetail, 0, len(names))
+	for _, name := range names {
+		t, status, err := s.fetchType(ctx, name)
+		if err != nil {
+			writeError(c, apierror.FromUpstream(status, err, "type not found"))
+			return
+		}
+		chart = append(chart, t)
+	}
+
+	c.JSON(http.StatusOK, matchupsResponse{
+		Name:          p.Name,
+		Types:         names,
+		StrongAgainst: strengths(chart),
+		WeakAgainst:   weaknesses(chart),
+	})
+}
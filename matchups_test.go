@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchups(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/charizard": `{"name":"charizard","types":[{"slot":1,"type":{"name":"fire"}},{"slot":2,"type":{"name":"flying"}}]}`,
+		"/type/fire": `{"name":"fire","damage_relations":{
+			"double_damage_from":[{"name":"water"},{"name":"rock"},{"name":"ground"}],
+			"half_damage_from":[{"name":"grass"},{"name":"fire"},{"name":"bug"}],
+			"double_damage_to":[{"name":"grass"},{"name":"bug"},{"name":"ice"}]}}`,
+		"/type/flying": `{"name":"flying","damage_relations":{
+			"double_damage_from":[{"name":"rock"},{"name":"electric"}],
+			"half_damage_from":[{"name":"grass"},{"name":"bug"}],
+			"no_damage_from":[{"name":"ground"}],
+			"double_damage_to":[{"name":"grass"},{"name":"bug"},{"name":"fighting"}]}}`,
+	})
+	r := setupRouter(newTestServer(ts.URL))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/charizard/matchups", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body matchupsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	// rock is 4x; ground is cancelled by flying's immunity
+	wantWeak := []matchupEntry{{Type: "rock", Multiplier: 4}, {Type: "electric", Multiplier: 2}, {Type: "water", Multiplier: 2}}
+	if len(body.WeakAgainst) != len(wantWeak) {
+		t.Fatalf("unexpected weaknesses: %+v", body.WeakAgainst)
+	}
+	for i, want := range wantWeak {
+		if got := body.WeakAgainst[i]; got.Type != want.Type || got.Multiplier != want.Multiplier {
+			t.Fatalf("weakness %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// grass and bug are hit by both types, so they rank first
+	wantStrong := []string{"bug", "grass", "fighting", "ice"}
+	if len(body.StrongAgainst) != len(wantStrong) {
+		t.Fatalf("unexpected strengths: %+v", body.StrongAgainst)
+	}
+	for i, want := range wantStrong {
+		if got := body.StrongAgainst[i].Type; got != want {
+			t.Fatalf("strength %d: expected %s, got %s", i, want, got)
+		}
+	}
+}
+
+func TestMatchupsNotFound(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{})
+	r := setupRouter(newTestServer(ts.URL))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno/matchups", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"ci_education/storage"
+)
+
+// migrateCommand implements "migrate up|down|status" against the store
+// configured by STORAGE_DRIVER and STORAGE_DSN, so schema changes can be
+// applied or rolled back without starting the server (set
+// STORAGE_AUTO_MIGRATE=false to keep the server from migrating on start). It
+// returns the process exit code.
+func migrateCommand(args []string) int {
+	return runMigrate(context.Background(), args, getenv("STORAGE_DRIVER", storage.DriverMemory), getenv("STORAGE_DSN", ""), os.Stdout, os.Stderr)
+}
+
+func runMigrate(ctx context.Context, args []string, driver, dsn string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: migrate up | down [-steps N] | status")
+		fs.PrintDefaults()
+	}
+	steps := fs.Int("steps", 1, "migrations to revert with down")
+	if len(args) == 0 {
+		fs.Usage()
+		return 2
+	}
+	action := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	mg, err := storage.NewMigrator(ctx, driver, dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, "migrate:", err)
+		return 1
+	}
+	defer mg.Close()
+
+	switch action {
+	case "up":
+		applied, err := mg.Up(ctx)
+		for _, v := range applied {
+			fmt.Fprintf(stdout, "applied %d\n", v)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "migrate:", err)
+			return 1
+		}
+	case "down":
+		reverted, err := mg.Down(ctx, *steps)
+		for _, v := range reverted {
+			fmt.Fprintf(stdout, "reverted %d\n", v)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "migrate:", err)
+			return 1
+		}
+	case "status":
+		st, err := mg.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, "migrate:", err)
+			return 1
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(st)
+		return 0
+	default:
+		fs.Usage()
+		return 2
+	}
+	v, err := mg.Version(ctx)
+	if err != nil {
+		fmt.Fprintln(stderr, "migrate:", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "schema version %d (latest %d)\n", v, mg.Latest())
+	return 0
+}
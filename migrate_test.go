@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ci_education/storage"
+)
+
+func TestMigrateCommandAndHealthz(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "app.db")
+	run := func(args ...string) (int, string) {
+		var out, errOut bytes.Buffer
+		code := runMigrate(ctx, args, storage.DriverSQLite, dsn, &out, &errOut)
+		return code, out.String() + errOut.String()
+	}
+
+	if code, out := run("up"); code != 0 || !strings.Contains(out, "applied 1") {
+		t.Fatalf("up: %d %s", code, out)
+	}
+	if code, out := run("status"); code != 0 || !strings.Contains(out, `"applied": true`) {
+		t.Fatalf("status: %d %s", code, out)
+	}
+
+	store, err := storage.Open(ctx, storage.DriverSQLite, dsn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer("")
+	s.store = store
+	w := httptest.NewRecorder()
+	setupRouter(s).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var body struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK || body.SchemaVersion != 2 {
+		t.Fatalf("healthz: %d %s", w.Code, w.Body)
+	}
+	store.Close()
+
+	if code, out := run("down", "-steps", "1"); code != 0 || !strings.Contains(out, "schema version 1") {
+		t.Fatalf("down: %d %s", code, out)
+	}
+	if code, _ := run("sideways"); code != 2 {
+		t.Fatalf("expected usage error for an unknown action, got %d", code)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// moveDetail is the subset of the upstream move payload we use. Power and
+// accuracy are null for status moves and moves that never miss.
+type moveDetail struct {
+	Name        string        `json:"name"`
+	Power       *int          `json:"power"`
+	Accuracy    *int          `json:"accuracy"`
+	PP          int           `json:"pp"`
+	Priority    int           `json:"priority"`
+	Type        namedResource `json:"type"`
+	DamageClass namedResource `json:"damage_class"`
+}
+
+// moveResponse is the response of GET /move/:name.
+type moveResponse struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	DamageClass string `json:"damage_class"`
+	Power       *int   `json:"power"`
+	Accuracy    *int   `json:"accuracy"`
+	PP          int    `json:"pp"`
+	Priority    int    `json:"priority"`
+}
+
+// fetchMove returns a move, via the s.moves cache.
+func (s *Server) fetchMove(ctx context.Context, name string) (moveDetail, int, error) {
+	return fetchCached(ctx, s, s.moves, name, "/move/"+name)
+}
+
+// moveHandler serves GET /move/:name.
+func (s *Server) moveHandler(c *gin.Context) {
+	m, status, err := s.fetchMove(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "move not found"))
+		return
+	}
+	c.JSON(http.StatusOK, moveResponse{
+		Name: m.Name, Type: m.Type.Name, DamageClass: m.DamageClass.Name,
+		Power: m.Power, Accuracy: m.Accuracy, PP: m.PP, Priority: m.Priority,
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMoveEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/move/thunderbolt": `{"name":"thunderbolt","power":90,"accuracy":100,"pp":15,"priority":0,"type":{"name":"electric"},"damage_class":{"name":"special"}}`,
+		"/move/swift":       `{"name":"swift","power":60,"accuracy":null,"pp":20,"priority":0,"type":{"name":"normal"},"damage_class":{"name":"special"}}`,
+	})
+	s := newTestServer(ts.URL)
+	s.moves = newTTLCache[moveDetail](time.Minute)
+	r := setupRouter(s)
+
+	for path, want := range map[string]string{
+		"/move/thunderbolt": `{"name":"thunderbolt","type":"electric","damage_class":"special","power":90,"accuracy":100,"pp":15,"priority":0}`,
+		"/move/swift":       `{"name":"swift","type":"normal","damage_class":"special","power":60,"accuracy":null,"pp":20,"priority":0}`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Fatalf("%s: expected 200 %s, got %d %s", path, want, w.Code, w.Body)
+		}
+	}
+	if _, ok := s.moves.get("swift"); !ok {
+		t.Fatal("expected the move to be cached")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/move/splash-dance", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
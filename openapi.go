@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// embeddedSchemas is the response contract, parsed once for the OpenAPI
+// document whether or not strict mode validates against it.
+var embeddedSchemas = sync.OnceValues(func() (*responseSchemas, error) {
+	return loadResponseSchemas(responseSchemasJSON)
+})
+
+// openAPI converts a contract schema to an OpenAPI schema object, pointing
+// references at components/schemas.
+func (sch *jsonSchema) openAPI() map[string]any {
+	if sch.Ref != "" {
+		return map[string]any{"$ref": "#/components/schemas/" + sch.Ref}
+	}
+	m := map[string]any{}
+	if sch.Type != "" {
+		m["type"] = sch.Type
+	}
+	if sch.Nullable {
+		m["nullable"] = true
+	}
+	if len(sch.Required) > 0 {
+		m["required"] = sch.Required
+	}
+	if len(sch.Properties) > 0 {
+		props := make(map[string]any, len(sch.Properties))
+		for name, p := range sch.Properties {
+			props[name] = p.openAPI()
+		}
+		m["properties"] = props
+	}
+	if sch.Items != nil {
+		m["items"] = sch.Items.openAPI()
+	}
+	return m
+}
+
+// openAPIPath converts a gin pattern to an OpenAPI path and its path
+// parameters: /pokemon/:name becomes /pokemon/{name}.
+func openAPIPath(pattern string) (string, []string) {
+	segs := strings.Split(pattern, "/")
+	var params []string
+	for i, seg := range segs {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			segs[i] = "{" + name + "}"
+			params = append(params, name)
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// openAPIOperation describes rt, with the response schemas the contract
+// has for its path. x-rate-tier and x-cached carry the registry's rate tier
+// and cache flag.
+func openAPIOperation(rt route, rs *responseSchemas) map[string]any {
+	tag, _, _ := strings.Cut(strings.TrimPrefix(rt.Path, "/"), "/")
+	op := map[string]any{
+		"operationId": rt.Name,
+		"summary":     rt.Summary,
+		"tags":        []string{tag},
+		"x-rate-tier": rt.Tier,
+		"x-cached":    rt.Cached,
+	}
+	_, params := openAPIPath(rt.Path)
+	if len(params) > 0 {
+		ps := make([]map[string]any, len(params))
+		for i, name := range params {
+			ps[i] = map[string]any{"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"}}
+		}
+		op["parameters"] = ps
+	}
+	if rt.Auth == authAPIKey || rt.Auth == authAdmin {
+		op["security"] = []map[string][]string{{"apiKey": {}}}
+	}
+
+	responses := map[string]any{
+		"default": map[string]any{
+			"description": "Error",
+			"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/error"}}},
+		},
+	}
+	for status, sch := range rs.routes[rt.Path] {
+		code, _ := strconv.Atoi(status)
+		responses[status] = map[string]any{
+			"description": http.StatusText(code),
+			"content":     map[string]any{"application/json": map[string]any{"schema": sch.openAPI()}},
+		}
+	}
+	if len(responses) == 1 {
+		responses["200"] = map[string]any{"description": "OK"}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// openAPIDocument builds the OpenAPI 3 document of the route registry.
+func openAPIDocument(rts []route, rs *responseSchemas) map[string]any {
+	paths := map[string]map[string]any{}
+	for _, rt := range rts {
+		p, _ := openAPIPath(rt.Path)
+		if paths[p] == nil {
+			paths[p] = map[string]any{}
+		}
+		paths[p][strings.ToLower(rt.Method)] = openAPIOperation(rt, rs)
+	}
+	schemas := make(map[string]any, len(rs.components))
+	for name, sch := range rs.components {
+		schemas[name] = sch.openAPI()
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "ci_education", "version": "1"},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// openAPIHandler serves GET /openapi.json, generated from the route registry
+// and the response contract.
+func (s *Server) openAPIHandler(c *gin.Context) {
+	rs, err := embeddedSchemas()
+	if err != nil {
+		writeError(c, apierror.Internal(err.Error()))
+		return
+	}
+	rts := make([]route, 0, len(s.routeIndex))
+	for _, rt := range s.routeIndex {
+		rts = append(rts, rt)
+	}
+	c.JSON(http.StatusOK, openAPIDocument(rts, rs))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// payloadLimitExempt lists routes whose bodies are legitimately large: the
+// scrape and the exports, which stream and bound their own size, and would
+// otherwise be held in memory whole.
+var payloadLimitExempt = map[string]bool{
+	"/metrics":            true,
+	"/export/pokedex.csv": true,
+	"/me/export":          true,
+	"/me/export/:id":      true,
+	"/admin/cache/export": true,
+}
+
+// middleware: reject responses larger than s.maxResponseBytes. The body is
+// buffered so an oversized response can still be replaced by a 500; routes
+// are therefore not streamed while the limit is enabled.
+func responseLimitMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.maxResponseBytes <= 0 || payloadLimitExempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+		orig := c.Writer
+		bw := newBufferedWriter(orig)
+		bw.limit = s.maxResponseBytes
+		c.Writer = bw
+		c.Next()
+		c.Writer = orig
+
+		if bw.overflow {
+			rid, _ := c.Get("request_id")
+			log.Printf("rid=%v route=%s response of %d bytes exceeds limit of %d bytes", rid, c.FullPath(), bw.attempts, bw.limit)
+			s.metrics.responseTooLargeTotal.WithLabelValues(c.FullPath()).Inc()
+			bw.discardBodyHeaders()
+			writeError(c, apierror.Internal("response exceeded the maximum size"))
+			return
+		}
+		bw.flushTo(bw.bytes())
+	}
+}
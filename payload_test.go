@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestResponseSizeLimit(t *testing.T) {
+	s := newTestServer("")
+	s.maxResponseBytes = 20
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("small response should pass through, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello?name=a-very-long-name-indeed", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for oversized response, got %d: %s", w.Code, w.Body.String())
+	}
+	if v := testutil.ToFloat64(s.metrics.responseTooLargeTotal.WithLabelValues("/hello")); v != 1 {
+		t.Fatalf("expected too-large counter 1, got %v", v)
+	}
+	if n := testutil.CollectAndCount(s.metrics.responseSizeBytes); n != 2 {
+		t.Fatalf("expected size histograms for 2 routes, got %d", n)
+	}
+
+	routes := map[string]bool{}
+	for _, rt := range s.routes() {
+		routes[rt.Path] = true
+	}
+	for path := range payloadLimitExempt {
+		if !routes[path] && path != "/metrics" {
+			t.Errorf("exempt path %s is not a route", path)
+		}
+	}
+}
+
+func TestResponseSizeLimitSkipsExports(t *testing.T) {
+	s := withAdminKey(t, newTestServer(""))
+	s.maxResponseBytes = 20
+	for i := 0; i < 3; i++ {
+		s.cache.set(fmt.Sprintf("pokemon-%d", i), pokemonCacheEntry{pokemon: pokemonResponse{Name: "a-long-enough-name"}})
+	}
+	w := httptest.NewRecorder()
+	setupRouter(s).ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/cache/export", nil)))
+	if w.Code != http.StatusOK || w.Body.Len() <= 20 {
+		t.Fatalf("expected the cache export to stream past the limit, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+}
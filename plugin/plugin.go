@@ -0,0 +1,95 @@
+// Package plugin is the extension seam for forks of this service. Extra
+// middleware, routes and health checks are registered from an init function
+// in a fork-specific file, and the server picks them up when building its
+// router, so forks never need to patch setupRouter:
+//
+//	func init() {
+//		plugin.RegisterMiddleware(companyAuth())
+//		plugin.RegisterRoute(http.MethodGet, "/internal/whoami", whoami)
+//		plugin.RegisterHealthCheck("ldap", pingLDAP)
+//	}
+//
+// Registration must happen before the router is built.
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck reports an error when the checked dependency is unhealthy.
+type HealthCheck func(ctx context.Context) error
+
+// Route is a registered extra route.
+type Route struct {
+	Method   string
+	Path     string
+	Handlers []gin.HandlerFunc
+}
+
+// NamedCheck is a registered health check.
+type NamedCheck struct {
+	Name  string
+	Check HealthCheck
+}
+
+var (
+	mu          sync.Mutex
+	middlewares []gin.HandlerFunc
+	routes      []Route
+	checks      []NamedCheck
+)
+
+// RegisterMiddleware adds middleware that runs on every request, after the
+// built-in request ID, logging and metrics middleware and before rate
+// limiting and admission control.
+func RegisterMiddleware(mw ...gin.HandlerFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	middlewares = append(middlewares, mw...)
+}
+
+// RegisterRoute adds a route. Registering a path that collides with a
+// built-in route panics when the router is built, as gin does.
+func RegisterRoute(method, path string, handlers ...gin.HandlerFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	routes = append(routes, Route{Method: method, Path: path, Handlers: handlers})
+}
+
+// RegisterHealthCheck adds a check reported by the detailed health endpoint.
+func RegisterHealthCheck(name string, check HealthCheck) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks = append(checks, NamedCheck{Name: name, Check: check})
+}
+
+// Middlewares returns the registered middleware in registration order.
+func Middlewares() []gin.HandlerFunc {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]gin.HandlerFunc(nil), middlewares...)
+}
+
+// Routes returns the registered routes in registration order.
+func Routes() []Route {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Route(nil), routes...)
+}
+
+// HealthChecks returns the registered health checks in registration order.
+func HealthChecks() []NamedCheck {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]NamedCheck(nil), checks...)
+}
+
+// reset clears all registrations; used by tests.
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	middlewares, routes, checks = nil, nil, nil
+}
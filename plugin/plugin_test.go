@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegistration(t *testing.T) {
+	t.Cleanup(reset)
+
+	RegisterMiddleware(func(c *gin.Context) {}, func(c *gin.Context) {})
+	RegisterRoute(http.MethodGet, "/extra", func(c *gin.Context) {})
+	RegisterHealthCheck("db", func(context.Context) error { return nil })
+
+	if got := len(Middlewares()); got != 2 {
+		t.Fatalf("expected 2 middlewares, got %d", got)
+	}
+	if r := Routes(); len(r) != 1 || r[0].Path != "/extra" || r[0].Method != http.MethodGet {
+		t.Fatalf("unexpected routes: %+v", r)
+	}
+	if c := HealthChecks(); len(c) != 1 || c[0].Name != "db" {
+		t.Fatalf("unexpected checks: %+v", c)
+	}
+
+	// Returned slices are copies.
+	Routes()[0].Path = "/changed"
+	if Routes()[0].Path != "/extra" {
+		t.Fatal("Routes must return a copy")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// namedResource is PokeAPI's {name, url} reference.
+type namedResource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// resourceList is PokeAPI's paginated list payload.
+type resourceList struct {
+	Count   int             `json:"count"`
+	Results []namedResource `json:"results"`
+}
+
+// pokemonDetail is the subset of the upstream pokemon payload used by
+// endpoints that need more than pokemonResponse (types and base stats).
+type pokemonDetail struct {
+	ID             int           `json:"id"`
+	Name           string        `json:"name"`
+	Height         int           `json:"height"`
+	Weight         int           `json:"weight"`
+	BaseExperience int           `json:"base_experience"`
+	Species        namedResource `json:"species"` // differs from Name for forms such as charizard-mega
+	Abilities      []struct {
+		Ability  namedResource `json:"ability"`
+		IsHidden bool          `json:"is_hidden"`
+		Slot     int           `json:"slot"`
+	} `json:"abilities"`
+	Types []struct {
+		Slot int           `json:"slot"`
+		Type namedResource `json:"type"`
+	} `json:"types"`
+	Stats []struct {
+		BaseStat int           `json:"base_stat"`
+		Stat     namedResource `json:"stat"`
+	} `json:"stats"`
+	Sprites struct {
+		FrontDefault string `json:"front_default"`
+		Other        struct {
+			OfficialArtwork struct {
+				FrontDefault string `json:"front_default"`
+			} `json:"official-artwork"`
+		} `json:"other"`
+	} `json:"sprites"`
+}
+
+// typeNames returns the pokemon's type names in slot order.
+func (d pokemonDetail) typeNames() []string {
+	out := make([]string, 0, len(d.Types))
+	for _, t := range d.Types {
+		out = append(out, t.Type.Name)
+	}
+	return out
+}
+
+// baseStat returns the named base stat, or 0 if absent.
+func (d pokemonDetail) baseStat(name string) int {
+	for _, st := range d.Stats {
+		if st.Stat.Name == name {
+			return st.BaseStat
+		}
+	}
+	return 0
+}
+
+// defaultAbility returns the first non-hidden ability, if any.
+func (d pokemonDetail) defaultAbility() (string, bool) {
+	for _, a := range d.Abilities {
+		if !a.IsHidden {
+			return a.Ability.Name, true
+		}
+	}
+	return "", false
+}
+
+// speciesName returns the pokemon's species, which differs from its name for
+// forms such as deoxys-normal; payloads without one fall back to the name.
+func (d pokemonDetail) speciesName() string {
+	if d.Species.Name != "" {
+		return d.Species.Name
+	}
+	return d.Name
+}
+
+// statNames lists the base stats in PokeAPI order.
+var statNames = []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"}
+
+// fullListLimit is large enough to fetch the entire pokemon list in one call.
+const fullListLimit = 100000
+
+// nameIndex caches the full pokemon name list, refreshed after ttl, and a
+// prefix tree over it built lazily after each refresh.
+type nameIndex struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	names     []namedResource
+	fetchedAt time.Time
+	tree      *radixTree
+}
+
+func newNameIndex(ttl time.Duration) *nameIndex {
+	return &nameIndex{ttl: ttl}
+}
+
+// pokedexNames returns every pokemon in pokedex order, from the name index
+// when fresh. Callers must not modify the returned slice.
+func (s *Server) pokedexNames(ctx context.Context) ([]namedResource, int, error) {
+	ix := s.names
+	if ix != nil {
+		ix.mu.Lock()
+		defer ix.mu.Unlock()
+		if ix.names != nil && time.Since(ix.fetchedAt) < ix.ttl {
+			return ix.names, http.StatusOK, nil
+		}
+	}
+	var list resourceList
+	status, err := s.fetchUpstream(ctx, "/pokemon?limit="+strconv.Itoa(fullListLimit)+"&offset=0", &list)
+	if err != nil {
+		return nil, status, err
+	}
+	if ix != nil {
+		ix.names, ix.fetchedAt, ix.tree = list.Results, time.Now(), nil
+	}
+	return list.Results, http.StatusOK, nil
+}
+
+// nameTree returns a prefix tree over all pokemon names.
+func (s *Server) nameTree(ctx context.Context) (*radixTree, int, error) {
+	names, status, err := s.pokedexNames(ctx)
+	if err != nil {
+		return nil, status, err
+	}
+	ix := s.names
+	if ix != nil {
+		ix.mu.Lock()
+		defer ix.mu.Unlock()
+		if ix.tree != nil {
+			return ix.tree, http.StatusOK, nil
+		}
+	}
+	keys := make([]string, len(names))
+	for i, n := range names {
+		keys[i] = n.Name
+	}
+	tree := buildRadixTree(keys)
+	if ix != nil {
+		ix.tree = tree
+	}
+	return tree, http.StatusOK, nil
+}
+
+// fetchPokemonDetail returns the full pokemon payload subset, cached in
+// s.details.
+func (s *Server) fetchPokemonDetail(ctx context.Context, name string) (pokemonDetail, int, error) {
+	return fetchCached(ctx, s, s.details, name, "/pokemon/"+name)
+}
+
+// detailFetch is the outcome of fetching one pokemon's details.
+type detailFetch struct {
+	detail pokemonDetail
+	status int
+	err    error
+}
+
+// fetchPokemonDetails fetches names concurrently with at most workers
+// in-flight upstream calls. Results are returned in input order. Once ctx is
+// done no further fetches start; the names left fail with ctx's error.
+func (s *Server) fetchPokemonDetails(ctx context.Context, names []string, workers int) []detailFetch {
+	results := make([]detailFetch, len(names))
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		if !acquireSlot(ctx, sem) {
+			for j := i; j < len(names); j++ {
+				results[j] = detailFetch{status: http.StatusGatewayTimeout, err: ctx.Err()}
+			}
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			d, status, err := s.fetchPokemonDetail(ctx, name)
+			results[i] = detailFetch{detail: d, status: status, err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
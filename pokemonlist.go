@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// fetchPokemonList returns one page of PokeAPI's pokemon list, via the
+// s.lists cache keyed by offset and limit.
+func (s *Server) fetchPokemonList(ctx context.Context, offset, limit int) (resourceList, int, error) {
+	path := "/pokemon?limit=" + strconv.Itoa(limit) + "&offset=" + strconv.Itoa(offset)
+	return fetchCached(ctx, s, s.lists, path, path)
+}
+
+// listPage parses the ?offset=&limit= of a paginated list, capping limit at
+// maxListLimit. On invalid values it writes a 400 and returns false.
+func listPage(c *gin.Context) (offset, limit int, ok bool) {
+	offset, err1 := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, err2 := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultListLimit)))
+	if err1 != nil || err2 != nil || offset < 0 || limit <= 0 {
+		writeError(c, apierror.BadRequest("offset must be a non-negative and limit a positive integer"))
+		return 0, 0, false
+	}
+	return offset, min(limit, maxListLimit), true
+}
+
+// pokemonListHandler serves GET /pokemon?limit=&offset= with one page of
+// names and PokeAPI URLs. limit is capped at maxListLimit.
+func (s *Server) pokemonListHandler(c *gin.Context) {
+	offset, limit, ok := listPage(c)
+	if !ok {
+		return
+	}
+
+	l, status, err := s.fetchPokemonList(c.Request.Context(), offset, limit)
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon list not found"))
+		return
+	}
+	results := l.Results
+	if results == nil {
+		results = []namedResource{}
+	}
+	c.JSON(http.StatusOK, gin.H{"count": l.Count, "offset": offset, "limit": limit, "results": results})
+}
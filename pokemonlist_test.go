@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPokemonListEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon?limit=2&offset=1": `{"count":3,"results":[{"name":"ivysaur","url":"https://pokeapi.co/api/v2/pokemon/2/"},{"name":"venusaur","url":"https://pokeapi.co/api/v2/pokemon/3/"}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.lists = newTTLCache[resourceList](time.Minute)
+	r := setupRouter(s)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon?limit=2&offset=1", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Count   int             `json:"count"`
+			Results []namedResource `json:"results"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if body.Count != 3 || len(body.Results) != 2 || body.Results[1].Name != "venusaur" || body.Results[1].URL == "" {
+			t.Fatalf("unexpected page %+v", body)
+		}
+		if i == 0 {
+			ts.Close() // the second request must be served from the cache
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for limit=0, got %d", w.Code)
+	}
+}
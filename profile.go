@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// speciesDetail is the subset of the upstream pokemon-species payload we use.
+type speciesDetail struct {
+	Name              string        `json:"name"`
+	Color             namedResource `json:"color"`
+	Habitat           namedResource `json:"habitat"`
+	IsLegendary       bool          `json:"is_legendary"`
+	IsMythical        bool          `json:"is_mythical"`
+	FlavorTextEntries []struct {
+		FlavorText string        `json:"flavor_text"`
+		Language   namedResource `json:"language"`
+	} `json:"flavor_text_entries"`
+	Genera []struct {
+		Genus    string        `json:"genus"`
+		Language namedResource `json:"language"`
+	} `json:"genera"`
+	Names []struct {
+		Name     string        `json:"name"`
+		Language namedResource `json:"language"`
+	} `json:"names"`
+	EvolutionChain struct {
+		URL string `json:"url"`
+	} `json:"evolution_chain"`
+	Varieties []struct {
+		IsDefault bool          `json:"is_default"`
+		Pokemon   namedResource `json:"pokemon"`
+	} `json:"varieties"`
+}
+
+// defaultVariety returns the pokemon that is the species' default form,
+// which need not share its name (deoxys is deoxys-normal).
+func (sp speciesDetail) defaultVariety() string {
+	for _, v := range sp.Varieties {
+		if v.IsDefault {
+			return v.Pokemon.Name
+		}
+	}
+	return sp.Name
+}
+
+// flavorText returns the first flavor text in lang, with PokeAPI's embedded
+// line breaks and form feeds flattened to spaces.
+func (sp speciesDetail) flavorText(lang string) string {
+	for _, e := range sp.FlavorTextEntries {
+		if e.Language.Name == lang {
+			return strings.Join(strings.Fields(e.FlavorText), " ")
+		}
+	}
+	return ""
+}
+
+func (sp speciesDetail) genus(lang string) string {
+	for _, g := range sp.Genera {
+		if g.Language.Name == lang {
+			return g.Genus
+		}
+	}
+	return ""
+}
+
+// fetchSpecies returns a pokemon species, via the s.species cache.
+func (s *Server) fetchSpecies(ctx context.Context, name string) (speciesDetail, int, error) {
+	return fetchCached(ctx, s, s.species, name, "/pokemon-species/"+name)
+}
+
+// abilityDetail is the subset of the upstream ability payload we use.
+type abilityDetail struct {
+	Name          string `json:"name"`
+	EffectEntries []struct {
+		Effect      string        `json:"effect"`
+		ShortEffect string        `json:"short_effect"`
+		Language    namedResource `json:"language"`
+	} `json:"effect_entries"`
+	Pokemon []struct {
+		IsHidden bool          `json:"is_hidden"`
+		Pokemon  namedResource `json:"pokemon"`
+	} `json:"pokemon"`
+}
+
+// effect returns the effect and short effect in lang.
+func (a abilityDetail) effect(lang string) (string, string) {
+	for _, e := range a.EffectEntries {
+		if e.Language.Name == lang {
+			return e.Effect, e.ShortEffect
+		}
+	}
+	return "", ""
+}
+
+// partError describes why one part of an aggregate document is missing.
+type partError struct {
+	Code    apierror.Code `json:"code"`
+	Message string        `json:"message"`
+}
+
+func newPartError(status int, err error) partError {
+	e := apierror.FromUpstream(status, err, err.Error())
+	return partError{Code: e.Code, Message: e.Message}
+}
+
+type profilePokemon struct {
+	ID             int            `json:"id"`
+	Height         int            `json:"height"`
+	Weight         int            `json:"weight"`
+	BaseExperience int            `json:"base_experience"`
+	Types          []string       `json:"types"`
+	Stats          map[string]int `json:"stats"`
+}
+
+type profileSpecies struct {
+	Genus       string `json:"genus,omitempty"`
+	Color       string `json:"color,omitempty"`
+	Habitat     string `json:"habitat,omitempty"`
+	FlavorText  string `json:"flavor_text,omitempty"`
+	IsLegendary bool   `json:"is_legendary"`
+	IsMythical  bool   `json:"is_mythical"`
+}
+
+type profileAbility struct {
+	Name        string `json:"name"`
+	Effect      string `json:"effect,omitempty"`
+	ShortEffect string `json:"short_effect,omitempty"`
+}
+
+// pokemonProfile is the denormalized document served by /pokemon/:name/profile.
+// Parts that could not be fetched are nil and listed in Errors.
+type pokemonProfile struct {
+	Name    string               `json:"name"`
+	Pokemon *profilePokemon      `json:"pokemon"`
+	Species *profileSpecies      `json:"species"`
+	Ability *profileAbility      `json:"ability"`
+	Partial bool                 `json:"partial"`
+	Errors  map[string]partError `json:"errors,omitempty"`
+}
+
+// profileHandler serves GET /pokemon/:name/profile. The pokemon comes from
+// the s.details cache; its species, looked up by the species name so forms
+// such as charizard-mega resolve, and its default ability are then fetched
+// concurrently. The base pokemon is required; species and ability failures
+// yield a partial document.
+func (s *Server) profileHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	p, status, err := s.fetchPokemonDetail(ctx, c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+		return
+	}
+	var (
+		wg            sync.WaitGroup
+		species       speciesDetail
+		speciesStatus int
+		speciesErr    error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		species, speciesStatus, speciesErr = s.fetchSpecies(ctx, p.speciesName())
+	}()
+
+	profile := pokemonProfile{Name: p.Name, Errors: map[string]partError{}}
+	profile.Pokemon = &profilePokemon{
+		ID: p.ID, Height: p.Height, Weight: p.Weight, BaseExperience: p.BaseExperience,
+		Types: p.typeNames(), Stats: make(map[string]int, len(p.Stats)),
+	}
+	for _, st := range p.Stats {
+		profile.Pokemon.Stats[st.Stat.Name] = st.BaseStat
+	}
+
+	profile.Ability = s.profileAbility(ctx, p, profile.Errors)
+
+	wg.Wait()
+	if speciesErr != nil {
+		profile.Errors["species"] = newPartError(speciesStatus, speciesErr)
+	} else {
+		profile.Species = &profileSpecies{
+			Genus: species.genus("en"), Color: species.Color.Name, Habitat: species.Habitat.Name,
+			FlavorText: species.flavorText("en"), IsLegendary: species.IsLegendary, IsMythical: species.IsMythical,
+		}
+	}
+
+	profile.Partial = len(profile.Errors) > 0
+	c.JSON(http.StatusOK, profile)
+}
+
+func (s *Server) profileAbility(ctx context.Context, p pokemonDetail, errs map[string]partError) *profileAbility {
+	name, ok := p.defaultAbility()
+	if !ok {
+		errs["ability"] = partError{Code: apierror.CodeNotFound, Message: "pokemon has no default ability"}
+		return nil
+	}
+	a, status, err := s.fetchAbility(ctx, name)
+	if err != nil {
+		errs["ability"] = newPartError(status, err)
+		return nil
+	}
+	effect, short := a.effect("en")
+	return &profileAbility{Name: a.Name, Effect: effect, ShortEffect: short}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProfilePartialFailure(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu": `{"id":25,"name":"pikachu","height":4,"weight":60,"base_experience":112,
+			"abilities":[{"ability":{"name":"lightning-rod"},"is_hidden":true},{"ability":{"name":"static"},"is_hidden":false}],
+			"types":[{"slot":1,"type":{"name":"electric"}}],"stats":[{"base_stat":35,"stat":{"name":"hp"}}]}`,
+		"/ability/static": `{"name":"static","effect_entries":[{"effect":"May paralyze.","short_effect":"Paralyzes.","language":{"name":"en"}}]}`,
+	})
+	r := setupRouter(newTestServer(ts.URL))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu/profile", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var p pokemonProfile
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if p.Pokemon == nil || p.Pokemon.Stats["hp"] != 35 || p.Pokemon.Types[0] != "electric" {
+		t.Fatalf("unexpected pokemon part: %+v", p.Pokemon)
+	}
+	if p.Ability == nil || p.Ability.Name != "static" || p.Ability.ShortEffect != "Paralyzes." {
+		t.Fatalf("unexpected ability part: %+v", p.Ability)
+	}
+	if !p.Partial || p.Species != nil || p.Errors["species"].Code != "not_found" {
+		t.Fatalf("expected species to be marked missing: %+v", p)
+	}
+}
+
+func TestProfileNotFound(t *testing.T) {
+	ts := fakePokeAPI(t, nil)
+	r := setupRouter(newTestServer(ts.URL))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno/profile", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestProfileFormUsesSpeciesAndDetailCache(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/charizard-mega-x": `{"id":10034,"name":"charizard-mega-x","species":{"name":"charizard"},
+			"abilities":[{"ability":{"name":"tough-claws"},"is_hidden":false}],"types":[{"slot":1,"type":{"name":"fire"}}]}`,
+		"/pokemon-species/charizard": `{"name":"charizard","color":{"name":"red"},"genera":[{"genus":"Flame Pokémon","language":{"name":"en"}}]}`,
+		"/ability/tough-claws":       `{"name":"tough-claws"}`,
+	})
+	s := newTestServer(ts.URL)
+	s.details = newTTLCache[pokemonDetail](time.Minute)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/charizard-mega-x/profile", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var p pokemonProfile
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if p.Partial || p.Species == nil || p.Species.Genus != "Flame Pokémon" {
+		t.Fatalf("expected the charizard species, got %+v", p)
+	}
+	if _, ok := s.details.get("charizard-mega-x"); !ok {
+		t.Fatal("expected the pokemon to be cached in s.details")
+	}
+}
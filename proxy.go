@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// pokeAPIPublicBase is the base PokeAPI embeds in resource links, whichever
+// mirror served the payload.
+const pokeAPIPublicBase = "https://pokeapi.co/api/v2"
+
+// reverseProxy passes GETs under configured path prefixes that have no
+// dedicated handler straight through to PokeAPI. Embedded PokeAPI links are
+// rewritten to point back at us, so the facade covers every resource without
+// a handler each. Bodies are cached by normalized upstream path only, with
+// links pointing at PROXY_PUBLIC_URL, or at PokeAPI when it is unset and the
+// base is derived per request at serve time; the client's Host never reaches
+// the cache.
+type reverseProxy struct {
+	prefixes  []string
+	publicURL string // our externally visible base; derived per request when empty
+	cache     *ttlCache[encodedBody]
+}
+
+// newReverseProxy returns nil (proxy mode off) when no prefixes are given.
+func newReverseProxy(prefixes []string, publicURL string, cache *ttlCache[encodedBody]) *reverseProxy {
+	var clean []string
+	for _, p := range prefixes {
+		if p = "/" + strings.Trim(p, "/"); p != "/" {
+			clean = append(clean, p)
+		}
+	}
+	if len(clean) == 0 {
+		return nil
+	}
+	return &reverseProxy{prefixes: clean, publicURL: strings.TrimSuffix(publicURL, "/"), cache: cache}
+}
+
+// matches reports whether path lies under one of the proxied prefixes.
+func (p *reverseProxy) matches(path string) bool {
+	for _, prefix := range p.prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// linkBase is the base links point to in cached bodies.
+func (p *reverseProxy) linkBase() string {
+	if p.publicURL != "" {
+		return p.publicURL
+	}
+	return pokeAPIPublicBase
+}
+
+// baseFor returns the base URL links should point to for a request.
+func (p *reverseProxy) baseFor(r *http.Request) string {
+	if p.publicURL != "" {
+		return p.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// rewriteLinks points links to the public PokeAPI and to upstreamBase at base.
+func rewriteLinks(body []byte, upstreamBase, base string) []byte {
+	body = bytes.ReplaceAll(body, []byte(pokeAPIPublicBase), []byte(base))
+	if upstreamBase != "" && upstreamBase != pokeAPIPublicBase {
+		body = bytes.ReplaceAll(body, []byte(upstreamBase), []byte(base))
+	}
+	return body
+}
+
+// proxyPath returns the upstream path of a proxied URL, cleaned and with its
+// query in canonical order, so equivalent requests share a cache entry.
+func proxyPath(u *url.URL) string {
+	p := path.Clean(u.Path)
+	q, _ := url.ParseQuery(u.RawQuery)
+	if len(q) > 0 {
+		p += "?" + q.Encode()
+	}
+	return p
+}
+
+// fetchProxied returns the upstream body for path (including its query)
+// with its links pointing at base, via the proxy cache. Cached bodies keep
+// their gzip variant; a base other than the cached one is rewritten in per
+// request.
+func (s *Server) fetchProxied(ctx context.Context, path, base string) (encodedBody, int, error) {
+	linkBase := s.proxy.linkBase()
+	body, ok := s.proxy.cache.get(path)
+	if !ok {
+		var raw json.RawMessage
+		status, err := s.fetchUpstream(ctx, path, &raw)
+		if err != nil {
+			return encodedBody{}, status, err
+		}
+		body = newEncodedBody("application/json; charset=utf-8", rewriteLinks(raw, s.baseURL, linkBase))
+		s.proxy.cache.set(path, body)
+	}
+	if base != linkBase {
+		body = newEncodedBody(body.ContentType, rewriteLinks(body.Body, "", base))
+	}
+	return body, http.StatusOK, nil
+}
+
+// proxyHandler serves requests no route matched: proxied prefixes are passed
+// through, anything else keeps gin's plain 404.
+func (s *Server) proxyHandler(c *gin.Context) {
+	if s.proxy == nil || c.Request.Method != http.MethodGet || !s.proxy.matches(c.Request.URL.Path) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+	body, status, err := s.fetchProxied(c.Request.Context(), proxyPath(c.Request.URL), s.proxy.baseFor(c.Request))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "resource not found"))
+		return
+	}
+	body.write(c, http.StatusOK)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestReverseProxy(t *testing.T) {
+	calls := 0
+	var upstream *httptest.Server
+	upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/berry-flavor/nope" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"name":"spicy","firmness":{"url":"https://pokeapi.co/api/v2/berry-firmness/2/"},"item":{"url":"%s/item/126/"}}`, upstream.URL)
+	}))
+	defer upstream.Close()
+
+	s := &Server{
+		httpClient: upstream.Client(),
+		baseURL:    upstream.URL,
+		cache:      newPokemonCache(0),
+		metrics:    newMetrics(prometheus.NewRegistry()),
+		proxy:      newReverseProxy([]string{"berry-flavor", "/berry-firmness/"}, "", newTTLCache[encodedBody](time.Minute)),
+	}
+	r := setupRouter(s)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = "facade.example"
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		w := get("/berry-flavor/spicy")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, `"http://facade.example/berry-firmness/2/"`) || !strings.Contains(body, `"http://facade.example/item/126/"`) {
+			t.Fatalf("links not rewritten: %s", body)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected the second request to be served from cache, got %d upstream calls", calls)
+	}
+
+	if w := get("/berry-flavor/nope"); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"not_found"`) {
+		t.Fatalf("expected a not_found error, got %d: %s", w.Code, w.Body)
+	}
+	if w := get("/machine/1"); w.Code != http.StatusNotFound || calls != 2 {
+		t.Fatalf("expected unlisted prefixes not to be proxied, got %d after %d calls", w.Code, calls)
+	}
+	if w := get("/berry-flavorx"); w.Code != http.StatusNotFound || calls != 2 {
+		t.Fatalf("expected prefix matching on path segments, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestReverseProxyCacheIgnoresHost(t *testing.T) {
+	calls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"firmness":{"url":"https://pokeapi.co/api/v2/berry-firmness/2/"}}`)
+	}))
+	defer upstream.Close()
+
+	s := &Server{
+		httpClient: upstream.Client(),
+		baseURL:    upstream.URL,
+		metrics:    newMetrics(prometheus.NewRegistry()),
+		proxy:      newReverseProxy([]string{"berry-flavor"}, "", newLRUCache[encodedBody](time.Minute, 10)),
+	}
+	r := setupRouter(s)
+
+	for _, tc := range []struct{ host, target string }{
+		{"a.example", "/berry-flavor?limit=2&offset=0"},
+		{"b.example", "/berry-flavor/?offset=0&limit=2"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		req.Host = tc.host
+		r.ServeHTTP(w, req)
+		if !strings.Contains(w.Body.String(), `"http://`+tc.host+`/berry-firmness/2/"`) {
+			t.Fatalf("%s: expected links to the request's host, got %s", tc.host, w.Body)
+		}
+	}
+	if calls != 1 || s.proxy.cache.len() != 1 {
+		t.Fatalf("expected one cache entry for both hosts, got %d calls and %d entries", calls, s.proxy.cache.len())
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ci_education/storage"
+)
+
+// storePurger enforces the store's retention settings: it permanently
+// removes teams and favorites soft-deleted for longer than retention, and
+// daily usage counts older than usageMonths calendar months. A zero setting
+// keeps that kind of record forever.
+type storePurger struct {
+	store       storage.Store
+	retention   time.Duration
+	usageMonths int
+	interval    time.Duration
+	metrics     *metrics
+}
+
+// newStorePurger returns nil (keep everything forever) when interval is not
+// positive or neither retention setting is.
+func newStorePurger(store storage.Store, retention time.Duration, usageMonths int, interval time.Duration, m *metrics) *storePurger {
+	if interval <= 0 || (retention <= 0 && usageMonths <= 0) {
+		return nil
+	}
+	return &storePurger{store: store, retention: retention, usageMonths: usageMonths, interval: interval, metrics: m}
+}
+
+func (p *storePurger) start(sv *supervisor) {
+	if p == nil {
+		return
+	}
+	sv.goWorker("store_purger", func() {
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		for range t.C {
+			p.purge(context.Background())
+		}
+	})
+}
+
+// purge runs one pass and returns how many records it removed.
+func (p *storePurger) purge(ctx context.Context) int64 {
+	now := time.Now()
+	var total int64
+	run := func(kind string, enabled bool, before time.Time, f func(context.Context, time.Time) (int64, error)) {
+		if !enabled {
+			return
+		}
+		n, err := f(ctx, before)
+		if err != nil {
+			log.Printf("purge: %s: %v", kind, err)
+		}
+		if n > 0 {
+			log.Printf("purge: removed %d %s records", n, kind)
+			p.metrics.storePurgedTotal.WithLabelValues(kind).Add(float64(n))
+		}
+		total += n
+	}
+	run("deleted", p.retention > 0, now.Add(-p.retention), p.store.PurgeDeleted)
+	run("usage", p.usageMonths > 0, now.AddDate(0, -p.usageMonths, 0), p.store.PurgeUsage)
+	return total
+}
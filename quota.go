@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// errQuotaExhausted is returned for upstream calls refused by the quota tracker.
+var errQuotaExhausted = errors.New("upstream call quota exhausted")
+
+// quotaWarnLevels are the usage fractions logged once per window.
+var quotaWarnLevels = []float64{0.5, 0.75, 0.9, 1}
+
+// quotaWindow counts calls in a fixed window aligned to period (UTC).
+type quotaWindow struct {
+	name   string
+	period time.Duration
+	limit  int
+
+	start  time.Time
+	used   int
+	warned int // number of quotaWarnLevels already logged
+}
+
+// upstreamQuota tracks our PokeAPI calls per hour and day against configured
+// quotas. Past throttleAt of a quota, calls are let through with a probability
+// that falls linearly to zero at the quota, so usage tapers off instead of
+// hitting a wall; at the quota every call is refused until the window rolls
+// over.
+type upstreamQuota struct {
+	throttleAt float64
+	metrics    *metrics
+
+	mu      sync.Mutex
+	windows []*quotaWindow
+	now     func() time.Time
+}
+
+// newUpstreamQuota returns nil (no quota) when neither limit is positive.
+func newUpstreamQuota(hourly, daily int, throttleAt float64, m *metrics) *upstreamQuota {
+	q := &upstreamQuota{throttleAt: throttleAt, metrics: m, now: time.Now}
+	if hourly > 0 {
+		q.windows = append(q.windows, &quotaWindow{name: "hour", period: time.Hour, limit: hourly})
+	}
+	if daily > 0 {
+		q.windows = append(q.windows, &quotaWindow{name: "day", period: 24 * time.Hour, limit: daily})
+	}
+	if len(q.windows) == 0 {
+		return nil
+	}
+	if q.throttleAt <= 0 || q.throttleAt > 1 {
+		q.throttleAt = 1
+	}
+	return q
+}
+
+// allow reports whether an upstream call may be made and, if so, counts it.
+func (q *upstreamQuota) allow() bool {
+	if q == nil {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	pass := 1.0
+	for _, w := range q.windows {
+		w.roll(now)
+		usage := float64(w.used) / float64(w.limit)
+		switch {
+		case usage >= 1:
+			pass = 0
+		case usage >= q.throttleAt:
+			pass = min(pass, (1-usage)/(1-q.throttleAt))
+		}
+	}
+	if pass <= 0 || (pass < 1 && rand.Float64() >= pass) {
+		return false
+	}
+	q.countLocked()
+	return true
+}
+
+// count records a call made without asking, such as a retry.
+func (q *upstreamQuota) count() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	for _, w := range q.windows {
+		w.roll(now)
+	}
+	q.countLocked()
+}
+
+// countLocked adds one call to every window; q.mu must be held and windows
+// rolled.
+func (q *upstreamQuota) countLocked() {
+	for _, w := range q.windows {
+		w.used++
+		for w.warned < len(quotaWarnLevels) && float64(w.used) >= quotaWarnLevels[w.warned]*float64(w.limit) {
+			log.Printf("upstream quota: %d of %d calls used this %s", w.used, w.limit, w.name)
+			w.warned++
+		}
+		q.metrics.upstreamQuotaRemaining.WithLabelValues(w.name).Set(float64(max(w.limit-w.used, 0)))
+	}
+}
+
+// usage returns the used fraction of the fullest quota window and whether
+// calls are being throttled at that level.
+func (q *upstreamQuota) usage() (float64, bool) {
+	if q == nil {
+		return 0, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	used := 0.0
+	for _, w := range q.windows {
+		w.roll(now)
+		used = max(used, float64(w.used)/float64(w.limit))
+	}
+	return used, used >= q.throttleAt
+}
+
+// roll starts a new window once now has left the current one.
+func (w *quotaWindow) roll(now time.Time) {
+	start := now.UTC().Truncate(w.period)
+	if start.Equal(w.start) {
+		return
+	}
+	w.start, w.used, w.warned = start, 0, 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestUpstreamQuota(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	q := newUpstreamQuota(10, 0, 1, m)
+	q.now = func() time.Time { return now }
+
+	for i := 0; i < 9; i++ {
+		if !q.allow() {
+			t.Fatalf("call %d: expected to be allowed", i)
+		}
+	}
+	q.count() // a retry uses the last call
+	if q.allow() {
+		t.Fatal("expected calls to be refused at the quota")
+	}
+	if v := testutil.ToFloat64(m.upstreamQuotaRemaining.WithLabelValues("hour")); v != 0 {
+		t.Fatalf("expected 0 remaining, got %v", v)
+	}
+
+	now = now.Add(time.Hour)
+	if !q.allow() {
+		t.Fatal("expected a new window to reset the quota")
+	}
+	if newUpstreamQuota(0, 0, 0.9, m) != nil {
+		t.Fatal("expected no quota without limits")
+	}
+}
+
+func TestUpstreamQuotaThrottles(t *testing.T) {
+	q := newUpstreamQuota(0, 1000, 0.5, newMetrics(prometheus.NewRegistry()))
+	allowed := 0
+	for i := 0; i < 2000; i++ {
+		if q.allow() {
+			allowed++
+		}
+	}
+	// the first 500 calls always pass, later ones increasingly rarely
+	if allowed <= 500 || allowed >= 1000 {
+		t.Fatalf("expected throttling between 500 and 1000 calls, got %d", allowed)
+	}
+}
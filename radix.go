@@ -0,0 +1,110 @@
+package main
+
+import "sort"
+
+// radixTree is a compressed prefix tree over a fixed set of strings, used for
+// fast type-ahead lookups. It is immutable once built and safe for concurrent
+// reads.
+type radixTree struct {
+	root radixNode
+}
+
+type radixNode struct {
+	label    string // edge label leading to this node
+	terminal bool   // a key ends here
+	children []*radixNode
+}
+
+func buildRadixTree(keys []string) *radixTree {
+	t := &radixTree{}
+	for _, k := range keys {
+		t.insert(k)
+	}
+	return t
+}
+
+func (t *radixTree) insert(key string) {
+	n := &t.root
+	for {
+		if key == "" {
+			n.terminal = true
+			return
+		}
+		i := sort.Search(len(n.children), func(i int) bool { return n.children[i].label[0] >= key[0] })
+		if i == len(n.children) || n.children[i].label[0] != key[0] {
+			child := &radixNode{label: key, terminal: true}
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = child
+			return
+		}
+		child := n.children[i]
+		common := commonPrefixLen(child.label, key)
+		if common < len(child.label) {
+			// split the edge
+			split := &radixNode{label: child.label[:common], children: []*radixNode{child}}
+			child.label = child.label[common:]
+			n.children[i] = split
+			child = split
+		}
+		key = key[common:]
+		n = child
+	}
+}
+
+// withPrefix returns up to limit keys starting with prefix, in lexical order.
+func (t *radixTree) withPrefix(prefix string, limit int) []string {
+	n, path := &t.root, ""
+	rest := prefix
+	for rest != "" {
+		var next *radixNode
+		for _, c := range n.children {
+			if c.label[0] == rest[0] {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		common := commonPrefixLen(next.label, rest)
+		if common < len(rest) && common < len(next.label) {
+			return nil
+		}
+		path += next.label
+		if common == len(rest) {
+			rest = ""
+		} else {
+			rest = rest[common:]
+		}
+		n = next
+	}
+	out := make([]string, 0, limit)
+	collect(n, path, limit, &out)
+	return out
+}
+
+func collect(n *radixNode, path string, limit int, out *[]string) {
+	if len(*out) >= limit {
+		return
+	}
+	if n.terminal {
+		*out = append(*out, path)
+	}
+	for _, c := range n.children {
+		collect(c, path+c.label, limit, out)
+		if len(*out) >= limit {
+			return
+		}
+	}
+}
+
+func commonPrefixLen(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRadixTreePrefix(t *testing.T) {
+	tree := buildRadixTree([]string{"pikachu", "pichu", "pidgey", "pidgeotto", "pidgeot", "raichu", "pi"})
+
+	cases := []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"pi", 10, []string{"pi", "pichu", "pidgeot", "pidgeotto", "pidgey", "pikachu"}},
+		{"pidge", 2, []string{"pidgeot", "pidgeotto"}},
+		{"pikachu", 10, []string{"pikachu"}},
+		{"pikachuu", 10, nil},
+		{"z", 10, nil},
+		{"", 3, []string{"pi", "pichu", "pidgeot"}},
+	}
+	for _, tc := range cases {
+		got := tree.withPrefix(tc.prefix, tc.limit)
+		if len(got) == 0 && len(tc.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("withPrefix(%q, %d) = %v, want %v", tc.prefix, tc.limit, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+
+	"ci_education/apierror"
+)
+
+// routeLimit is a token-bucket limit for a route group. Pattern is an exact
+// gin route ("/pokemon/:name"), a prefix ending in "*" ("/export/*") or a
+// registry rate tier ("@bulk").
+type routeLimit struct {
+	Pattern string
+	Rate    float64 // tokens per second
+	Burst   int
+}
+
+func (l routeLimit) matches(route string, tier rateTier) bool {
+	if name, ok := strings.CutPrefix(l.Pattern, "@"); ok {
+		return rateTier(name) == tier
+	}
+	if prefix, ok := strings.CutSuffix(l.Pattern, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	return route == l.Pattern
+}
+
+// parseRouteLimits parses a comma-separated list of pattern=rate:burst items,
+// e.g. "/pokemon/:name=20:40,/export/*=0.2:1,@admin=1:5". Invalid items are logged and
+// skipped.
+func parseRouteLimits(v string) []routeLimit {
+	var limits []routeLimit
+	for _, item := range splitList(v) {
+		pattern, spec, ok := strings.Cut(item, "=")
+		rateStr, burstStr, ok2 := strings.Cut(spec, ":")
+		if !ok || !ok2 || pattern == "" {
+			log.Printf("ratelimit: ignoring invalid entry %q", item)
+			continue
+		}
+		r, err1 := strconv.ParseFloat(rateStr, 64)
+		burst, err2 := strconv.Atoi(burstStr)
+		if err1 != nil || err2 != nil || r <= 0 || burst <= 0 {
+			log.Printf("ratelimit: ignoring invalid entry %q", item)
+			continue
+		}
+		limits = append(limits, routeLimit{Pattern: pattern, Rate: r, Burst: burst})
+	}
+	return limits
+}
+
+// rateLimiter enforces per-route-group token buckets. Rules are checked in
+// order and the first match wins; routes with no matching rule are unlimited.
+type rateLimiter struct {
+	rules    []routeLimit
+	limiters []*rate.Limiter
+}
+
+// newRateLimiter returns nil (no limiting) when no rules are configured.
+func newRateLimiter(rules []routeLimit) *rateLimiter {
+	if len(rules) == 0 {
+		return nil
+	}
+	rl := &rateLimiter{rules: rules}
+	for _, r := range rules {
+		rl.limiters = append(rl.limiters, rate.NewLimiter(rate.Limit(r.Rate), r.Burst))
+	}
+	return rl
+}
+
+func (rl *rateLimiter) limiterFor(route string, tier rateTier) (routeLimit, *rate.Limiter, bool) {
+	for i, r := range rl.rules {
+		if r.matches(route, tier) {
+			return r, rl.limiters[i], true
+		}
+	}
+	return routeLimit{}, nil, false
+}
+
+// middleware: per-route rate limiting with X-RateLimit-* headers
+func rateLimitMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.rateLimit == nil {
+			c.Next()
+			return
+		}
+		rt, _ := s.registeredRoute(c)
+		rule, lim, ok := s.rateLimit.limiterFor(s.routeLabel(c), rt.Tier)
+		if !ok {
+			c.Next()
+			return
+		}
+		now := time.Now()
+		allowed := lim.AllowN(now, 1)
+		h := c.Writer.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(rule.Burst))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, lim.TokensAt(now)))))
+		if !allowed {
+			s.metrics.rateLimitTotal.WithLabelValues(rule.Pattern, "rejected").Inc()
+			wait := time.Duration(float64(time.Second) / rule.Rate)
+			h.Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(c, apierror.RateLimited("rate limit exceeded"))
+			c.Abort()
+			return
+		}
+		s.metrics.rateLimitTotal.WithLabelValues(rule.Pattern, "allowed").Inc()
+		c.Next()
+	}
+}
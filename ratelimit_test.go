@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseRouteLimits(t *testing.T) {
+	got := parseRouteLimits("/pokemon/:name=20:40, /export/*=0.5:1, bad, /x=0:1")
+	if len(got) != 2 {
+		t.Fatalf("expected 2 rules, got %+v", got)
+	}
+	if !got[1].matches("/export/pokedex.csv", tierBulk) || got[1].matches("/pokemon/:name", tierDefault) {
+		t.Fatalf("unexpected prefix matching for %+v", got[1])
+	}
+}
+
+func TestRateLimitPerRoute(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := &Server{
+		httpClient: &http.Client{},
+		cache:      newPokemonCache(0),
+		metrics:    newMetrics(reg),
+		rateLimit:  newRateLimiter([]routeLimit{{Pattern: "/hello", Rate: 0.001, Burst: 2}}),
+	}
+	r := setupRouter(s)
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+		if w.Code != want {
+			t.Fatalf("request %d: expected %d, got %d", i, want, w.Code)
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Fatalf("request %d: missing rate limit header", i)
+		}
+		if want == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Fatal("expected Retry-After on 429")
+		}
+	}
+
+	// Other routes are unaffected.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected unlimited /health, got %d", w.Code)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// middleware: turn handler panics into the standard 500 error body carrying an
+// incident id, logged together with the stack trace so user reports can be
+// matched to logs.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// later middleware may swap in a buffering writer; answer on the real one
+		w := c.Writer
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if e, ok := err.(error); ok && errors.Is(e, http.ErrAbortHandler) {
+				panic(err)
+			}
+			incident := genRequestID()[:16]
+			rid, _ := c.Get("request_id")
+			log.Printf("panic incident=%s rid=%v %s %s: %v\n%s", incident, rid, c.Request.Method, c.Request.URL.Path, err, debug.Stack())
+			c.Writer = w
+			if w.Written() {
+				c.Abort()
+				return
+			}
+			e := apierror.Internal("an unexpected error occurred; quote the incident id when reporting it")
+			e.IncidentID = incident
+			writeError(c, e)
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
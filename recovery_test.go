@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRecoveryIncidentID(t *testing.T) {
+	var logs bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&logs)
+	defer log.SetOutput(orig)
+
+	s := &Server{metrics: newMetrics(prometheus.NewRegistry()), maxResponseBytes: 1 << 20}
+	r := gin.New()
+	r.Use(recoveryMiddleware(), requestIDMiddleware(s), responseLimitMiddleware(s))
+	r.GET("/boom", func(c *gin.Context) { panic("kaboom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	var body struct {
+		Error struct {
+			Code       string `json:"code"`
+			IncidentID string `json:"incident_id"`
+			RequestID  string `json:"request_id"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected a JSON error body, got %q", w.Body)
+	}
+	if body.Error.Code != "internal_error" || body.Error.IncidentID == "" || body.Error.RequestID == "" {
+		t.Fatalf("unexpected error body %+v", body.Error)
+	}
+	out := logs.String()
+	if !strings.Contains(out, "incident="+body.Error.IncidentID) || !strings.Contains(out, "kaboom") || !strings.Contains(out, "recovery_test.go") {
+		t.Fatalf("expected the incident id and stack trace in the log, got %q", out)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// redirectPolicy controls how the upstream client follows redirects: how many
+// hops it takes, which hosts it may be sent to and whether request headers
+// are carried over. It wraps any CheckRedirect already installed on the
+// client (such as the egress policy), which still gets the final say.
+type redirectPolicy struct {
+	maxHops        int             // 0 = never follow
+	hosts          map[string]bool // empty = any host
+	forwardHeaders bool
+	metrics        *metrics
+}
+
+func newRedirectPolicy(maxHops int, hosts []string, forwardHeaders bool, m *metrics) *redirectPolicy {
+	p := &redirectPolicy{maxHops: max(maxHops, 0), hosts: make(map[string]bool), forwardHeaders: forwardHeaders, metrics: m}
+	for _, h := range hosts {
+		p.hosts[strings.ToLower(h)] = true
+	}
+	return p
+}
+
+// apply installs the policy on client, chaining to its current CheckRedirect.
+func (p *redirectPolicy) apply(client *http.Client) {
+	prev := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if p.maxHops == 0 {
+			// hand the 3xx back to the caller unfollowed
+			p.metrics.upstreamRedirectsTotal.WithLabelValues("refused").Inc()
+			return http.ErrUseLastResponse
+		}
+		if len(via) > p.maxHops {
+			p.metrics.upstreamRedirectsTotal.WithLabelValues("refused").Inc()
+			return fmt.Errorf("stopped after %d redirects", p.maxHops)
+		}
+		if host := strings.ToLower(req.URL.Hostname()); len(p.hosts) > 0 && !p.hosts[host] {
+			p.metrics.upstreamRedirectsTotal.WithLabelValues("refused").Inc()
+			return fmt.Errorf("redirect to host %q not allowed", host)
+		}
+		if prev != nil {
+			if err := prev(req, via); err != nil {
+				p.metrics.upstreamRedirectsTotal.WithLabelValues("refused").Inc()
+				return err
+			}
+		}
+		if !p.forwardHeaders {
+			req.Header = make(http.Header)
+		}
+		p.metrics.upstreamRedirectsTotal.WithLabelValues("followed").Inc()
+		return nil
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	var gotHeader string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops, _ := strconv.Atoi(r.URL.Query().Get("hops"))
+		if hops > 0 {
+			http.Redirect(w, r, "/?hops="+strconv.Itoa(hops-1), http.StatusFound)
+			return
+		}
+		gotHeader = r.Header.Get("X-Test")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	get := func(p *redirectPolicy, hops int) (*http.Response, error) {
+		client := &http.Client{Transport: ts.Client().Transport}
+		p.apply(client)
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/?hops="+strconv.Itoa(hops), nil)
+		req.Header.Set("X-Test", "yes")
+		return client.Do(req)
+	}
+
+	m := newMetrics(prometheus.NewRegistry())
+	if resp, err := get(newRedirectPolicy(2, nil, true, m), 2); err != nil || resp.StatusCode != http.StatusOK || gotHeader != "yes" {
+		t.Fatalf("expected 2 hops followed with headers, got %v %v %q", resp, err, gotHeader)
+	}
+	if v := testutil.ToFloat64(m.upstreamRedirectsTotal.WithLabelValues("followed")); v != 2 {
+		t.Fatalf("expected 2 followed redirects, got %v", v)
+	}
+	if _, err := get(newRedirectPolicy(2, nil, true, m), 3); err == nil {
+		t.Fatal("expected error beyond max hops")
+	}
+	if resp, err := get(newRedirectPolicy(0, nil, true, m), 1); err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected unfollowed 302, got %v %v", resp, err)
+	}
+	if _, err := get(newRedirectPolicy(5, []string{"pokeapi.co"}, true, m), 1); err == nil {
+		t.Fatal("expected redirect to a non-allowed host to be refused")
+	}
+	if _, err := get(newRedirectPolicy(5, nil, false, m), 1); err != nil || gotHeader != "" {
+		t.Fatalf("expected headers dropped on redirect, got %q %v", gotHeader, err)
+	}
+}
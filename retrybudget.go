@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// retryBudgetBucket counts one second of upstream calls and retries.
+type retryBudgetBucket struct {
+	sec      int64
+	requests int
+	retries  int
+}
+
+// retryBudget caps upstream retries at ratio of the calls made over a sliding
+// window, plus a small per-second floor so quiet periods can still retry.
+// During a full outage every call fails, and without a budget each one would
+// be tried MaxAttempts times, multiplying load on an upstream that is already
+// down.
+type retryBudget struct {
+	ratio     float64
+	minPerSec float64
+	metrics   *metrics
+
+	mu      sync.Mutex
+	buckets []retryBudgetBucket // ring indexed by unix second
+	now     func() time.Time
+}
+
+// newRetryBudget returns nil (unlimited retries) when ratio is not positive.
+func newRetryBudget(ratio, minPerSec float64, window time.Duration, m *metrics) *retryBudget {
+	if ratio <= 0 {
+		return nil
+	}
+	secs := max(int(window/time.Second), 1)
+	return &retryBudget{ratio: ratio, minPerSec: minPerSec, metrics: m, buckets: make([]retryBudgetBucket, secs), now: time.Now}
+}
+
+// bucketLocked returns the current second's bucket, resetting a stale one.
+func (b *retryBudget) bucketLocked() *retryBudgetBucket {
+	sec := b.now().Unix()
+	bk := &b.buckets[sec%int64(len(b.buckets))]
+	if bk.sec != sec {
+		*bk = retryBudgetBucket{sec: sec}
+	}
+	return bk
+}
+
+// totalsLocked sums the buckets still inside the window.
+func (b *retryBudget) totalsLocked() (requests, retries int) {
+	oldest := b.now().Unix() - int64(len(b.buckets)) + 1
+	for _, bk := range b.buckets {
+		if bk.sec >= oldest {
+			requests += bk.requests
+			retries += bk.retries
+		}
+	}
+	return requests, retries
+}
+
+// request records a first attempt at an upstream call.
+func (b *retryBudget) request() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucketLocked().requests++
+}
+
+// allow reports whether one more retry fits in the budget and, if so,
+// spends it.
+func (b *retryBudget) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	requests, retries := b.totalsLocked()
+	budget := b.ratio*float64(requests) + b.minPerSec*float64(len(b.buckets))
+	if float64(retries+1) > budget {
+		b.metrics.upstreamRetriesTotal.WithLabelValues("denied").Inc()
+		b.metrics.upstreamRetryBudgetUsed.Set(float64(retries) / budget)
+		return false
+	}
+	b.bucketLocked().retries++
+	b.metrics.upstreamRetriesTotal.WithLabelValues("allowed").Inc()
+	b.metrics.upstreamRetryBudgetUsed.Set(float64(retries+1) / budget)
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRetryBudget(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	b := newRetryBudget(0.1, 0, 10*time.Second, m)
+	now := time.Unix(1000, 0)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		b.request()
+	}
+	if !b.allow() || !b.allow() {
+		t.Fatal("expected 10% of 20 calls to allow 2 retries")
+	}
+	if b.allow() {
+		t.Fatal("expected the third retry to be denied")
+	}
+	if got := testutil.ToFloat64(m.upstreamRetryBudgetUsed); got != 1 {
+		t.Fatalf("expected the budget to be fully used, got %v", got)
+	}
+
+	// Once the window has slid past, the old calls and retries no longer count.
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 10; i++ {
+		b.request()
+	}
+	if !b.allow() || b.allow() {
+		t.Fatal("expected exactly one retry from the new window")
+	}
+	if got := testutil.ToFloat64(m.upstreamRetriesTotal.WithLabelValues("denied")); got != 2 {
+		t.Fatalf("expected 2 denied retries, got %v", got)
+	}
+}
+
+func TestRetryBudgetStopsAmplification(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{httpClient: ts.Client(), baseURL: ts.URL, metrics: m,
+		retry:  retryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		budget: newRetryBudget(0.1, 0, 10*time.Second, m)}
+
+	for i := 0; i < 20; i++ {
+		var p pokemonResponse
+		if _, err := s.fetchUpstream(context.Background(), "/pokemon/pikachu", &p); err == nil {
+			t.Fatal("expected the outage to fail the call")
+		}
+	}
+	// 20 first attempts plus 10% of them as retries, instead of 60 calls.
+	if got := calls.Load(); got != 22 {
+		t.Fatalf("expected 22 upstream calls, got %d", got)
+	}
+}
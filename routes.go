@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authPolicy is who may call a route.
+type authPolicy string
+
+const (
+	authPublic authPolicy = "public"
+	authAPIKey authPolicy = "api_key" // an X-API-Key or a signed URL, via apiKeyMiddleware
+	authAdmin  authPolicy = "admin"   // an admin X-API-Key, via apiKeyMiddleware and adminKeyMiddleware
+)
+
+// rateTier groups routes for RATE_LIMITS, where a rule may name a tier as
+// "@tier" instead of a route pattern.
+type rateTier string
+
+const (
+	tierDefault rateTier = "default"
+	tierBulk    rateTier = "bulk"  // fans out into many upstream calls or large responses
+	tierOps     rateTier = "ops"   // health, metrics and documentation
+	tierAdmin   rateTier = "admin" // operator endpoints
+)
+
+// route is one entry of the route registry: the single description of an
+// endpoint from which the gin engine, the OpenAPI document and route-level
+// metrics labels are built. Cached routes serve upstream data through a
+// cache governed by CACHE_POLICIES for their path.
+type route struct {
+	Name    string // unique operation id
+	Method  string
+	Path    string // gin pattern; also the route label of metrics
+	Summary string
+	Handler gin.HandlerFunc
+	Auth    authPolicy
+	Cached  bool
+	Tier    rateTier
+}
+
+// routes returns the registry of s's endpoints. Auth and Tier default to
+// authPublic and tierDefault. Admin tier routes must require an admin key;
+// declaring one otherwise panics, so it cannot ship public by omission.
+func (s *Server) routes() []route {
+	rts := []route{
+		{Name: "health", Method: http.MethodGet, Path: "/health", Summary: "Liveness probe", Handler: healthHandler, Tier: tierOps},
+		{Name: "healthz", Method: http.MethodGet, Path: "/healthz", Summary: "Detailed health including latency degradation", Handler: s.healthzHandler, Tier: tierOps},
+		{Name: "readyz", Method: http.MethodGet, Path: "/readyz", Summary: "Readiness probe for load balancers", Handler: s.readyzHandler, Tier: tierOps},
+		{Name: "listErrors", Method: http.MethodGet, Path: "/errors", Summary: "Catalog of error codes", Handler: errorCatalogHandler, Tier: tierOps},
+		{Name: "hello", Method: http.MethodGet, Path: "/hello", Summary: "Greeting", Handler: helloHandler},
+
+		{Name: "getPokemon", Method: http.MethodGet, Path: pokemonRoute, Summary: "Basic pokemon information, optionally with a localized name", Handler: s.pokemonHandler, Cached: true},
+		{Name: "listPokemon", Method: http.MethodGet, Path: "/pokemon", Summary: "One page of pokemon names", Handler: s.pokemonListHandler, Cached: true},
+		{Name: "dailyPokemon", Method: http.MethodGet, Path: "/pokemon/daily", Summary: "Pokemon of the UTC day", Handler: s.dailyPokemonHandler, Cached: true},
+		{Name: "comparePokemon", Method: http.MethodGet, Path: "/pokemon/compare", Summary: "Compare two pokemon", Handler: s.compareHandler, Cached: true},
+		{Name: "searchPokemon", Method: http.MethodGet, Path: "/pokemon/search", Summary: "Pokemon whose name starts with a prefix", Handler: s.searchHandler},
+		{Name: "batchPokemon", Method: http.MethodPost, Path: "/pokemon/batch", Summary: "Fetch up to 50 pokemon at once", Handler: s.batchHandler, Cached: true, Tier: tierBulk},
+		{Name: "pokemonProfile", Method: http.MethodGet, Path: "/pokemon/:name/profile", Summary: "Pokemon, species and default ability in one document", Handler: s.profileHandler, Cached: true},
+		{Name: "pokemonMatchups", Method: http.MethodGet, Path: "/pokemon/:name/matchups", Summary: "Types the pokemon is strong and weak against", Handler: s.matchupsHandler, Cached: true},
+		{Name: "pokemonSpecies", Method: http.MethodGet, Path: "/pokemon/:name/species", Summary: "Species details", Handler: s.speciesHandler, Cached: true},
+		{Name: "pokemonEvolution", Method: http.MethodGet, Path: "/pokemon/:name/evolution", Summary: "Flattened evolution chain", Handler: s.evolutionHandler, Cached: true},
+		{Name: "pokemonEncounters", Method: http.MethodGet, Path: "/pokemon/:name/encounters", Summary: "Where to find the pokemon in the wild", Handler: s.encountersHandler, Cached: true},
+		{Name: "pokemonSprite", Method: http.MethodGet, Path: "/pokemon/:name/sprite", Summary: "Official artwork PNG", Handler: s.spriteHandler, Cached: true},
+
+		{Name: "getType", Method: http.MethodGet, Path: "/type/:name", Summary: "Type damage relations", Handler: s.typeHandler, Cached: true},
+		{Name: "listTypePokemon", Method: http.MethodGet, Path: "/type/:name/pokemon", Summary: "One page of the pokemon having a type", Handler: s.typePokemonHandler, Cached: true},
+		{Name: "listGenerationPokemon", Method: http.MethodGet, Path: "/generation/:id/pokemon", Summary: "The species introduced in a generation", Handler: s.generationPokemonHandler, Cached: true},
+		{Name: "typeEffectiveness", Method: http.MethodGet, Path: "/effectiveness", Summary: "Damage multiplier of an attacking type against defending types", Handler: s.effectivenessHandler, Cached: true},
+		{Name: "getAbility", Method: http.MethodGet, Path: "/ability/:name", Summary: "Ability effect and the pokemon that can have it", Handler: s.abilityHandler, Cached: true},
+		{Name: "getMove", Method: http.MethodGet, Path: "/move/:name", Summary: "Move details", Handler: s.moveHandler, Cached: true},
+		{Name: "getItem", Method: http.MethodGet, Path: "/item/:name", Summary: "Item details", Handler: s.itemHandler, Cached: true},
+		{Name: "getBerry", Method: http.MethodGet, Path: "/berry/:name", Summary: "Berry details", Handler: s.berryHandler, Cached: true},
+
+		{Name: "autocomplete", Method: http.MethodGet, Path: "/autocomplete", Summary: "Pokemon name suggestions", Handler: s.autocompleteHandler},
+		{Name: "aggregateStats", Method: http.MethodPost, Path: "/stats/aggregate", Summary: "Aggregate base stats over many pokemon", Handler: s.statsAggregateHandler, Cached: true, Tier: tierBulk},
+		{Name: "analyzeTeam", Method: http.MethodPost, Path: "/team/analyze", Summary: "Stats, type coverage and shared weaknesses of a team", Handler: s.teamAnalyzeHandler, Cached: true},
+		{Name: "exportPokedex", Method: http.MethodGet, Path: "/export/pokedex.csv", Summary: "Stream the pokedex as CSV", Handler: s.exportPokedexHandler, Cached: true, Tier: tierBulk},
+
+		{Name: "listTeams", Method: http.MethodGet, Path: "/teams", Summary: "The caller's teams", Handler: s.listTeamsHandler, Auth: authAPIKey},
+		{Name: "createTeam", Method: http.MethodPost, Path: "/teams", Summary: "Create a team", Handler: s.createTeamHandler, Auth: authAPIKey},
+		{Name: "getTeam", Method: http.MethodGet, Path: "/teams/:id", Summary: "One team", Handler: s.getTeamHandler, Auth: authAPIKey},
+		{Name: "deleteTeam", Method: http.MethodDelete, Path: "/teams/:id", Summary: "Soft-delete a team", Handler: s.deleteTeamHandler, Auth: authAPIKey},
+		{Name: "restoreTeam", Method: http.MethodPost, Path: "/teams/:id/restore", Summary: "Restore a soft-deleted team", Handler: s.restoreTeamHandler, Auth: authAPIKey},
+		{Name: "importTeams", Method: http.MethodPost, Path: "/teams/import", Summary: "Import teams in bulk", Handler: s.importTeamsHandler, Auth: authAPIKey, Tier: tierBulk},
+
+		{Name: "startDataExport", Method: http.MethodGet, Path: "/me/export", Summary: "Start an export of the caller's data", Handler: s.meExportHandler, Auth: authAPIKey},
+		{Name: "getDataExport", Method: http.MethodGet, Path: "/me/export/:id", Summary: "Status of a data export", Handler: s.meExportStatusHandler, Auth: authAPIKey},
+		{Name: "eraseData", Method: http.MethodDelete, Path: "/me/data", Summary: "Erase the caller's data", Handler: s.meDeleteDataHandler, Auth: authAPIKey},
+		{Name: "getDataErasure", Method: http.MethodGet, Path: "/me/data/erasures/:id", Summary: "Status of a data erasure", Handler: s.meErasureStatusHandler, Auth: authAPIKey},
+
+		{Name: "adminDiffs", Method: http.MethodGet, Path: "/admin/diffs", Summary: "Shadow traffic differences", Handler: s.adminDiffsHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminSLO", Method: http.MethodGet, Path: "/admin/slo", Summary: "SLO burn rates", Handler: s.adminSLOHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminLoad", Method: http.MethodGet, Path: "/admin/load", Summary: "Autoscaling load signal", Handler: s.adminLoadHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminJournal", Method: http.MethodGet, Path: "/admin/upstream/journal", Summary: "Recent upstream calls", Handler: s.adminJournalHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminDrift", Method: http.MethodGet, Path: "/admin/upstream/drift", Summary: "Upstream schema drift", Handler: s.adminDriftHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminCacheExport", Method: http.MethodGet, Path: "/admin/cache/export", Summary: "Stream the pokemon cache", Handler: s.adminCacheExportHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminCacheImport", Method: http.MethodPost, Path: "/admin/cache/import", Summary: "Import a cache export", Handler: s.adminCacheImportHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminCacheImportStatus", Method: http.MethodGet, Path: "/admin/cache/import/:id", Summary: "Status of a cache import", Handler: s.adminCacheImportStatusHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminFlushCache", Method: http.MethodDelete, Path: "/admin/cache", Summary: "Flush every cache", Handler: s.adminFlushCacheHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminEvictCache", Method: http.MethodDelete, Path: "/admin/cache/:name", Summary: "Evict one key from every cache", Handler: s.adminEvictCacheHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminStartWarm", Method: http.MethodPost, Path: "/admin/warm", Summary: "Start a cache warm-up", Handler: s.adminStartWarmHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminWarmStatus", Method: http.MethodGet, Path: "/admin/warm/:id", Summary: "Status of a cache warm-up", Handler: s.adminWarmStatusHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminCreateAPIKey", Method: http.MethodPost, Path: "/admin/api-keys", Summary: "Issue an API key", Handler: s.adminCreateAPIKeyHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminSignedURL", Method: http.MethodPost, Path: "/admin/signed-url", Summary: "Sign a URL", Handler: s.adminSignedURLHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminStandby", Method: http.MethodGet, Path: "/admin/standby", Summary: "Standby state", Handler: s.adminStandbyHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminStandbyWarm", Method: http.MethodPost, Path: "/admin/standby/warm", Summary: "Warm the standby again", Handler: s.adminStandbyWarmHandler, Auth: authAdmin, Tier: tierAdmin},
+		{Name: "adminStandbyPromote", Method: http.MethodPost, Path: "/admin/standby/promote", Summary: "Promote the standby to active", Handler: s.adminStandbyPromoteHandler, Auth: authAdmin, Tier: tierAdmin},
+
+		{Name: "playground", Method: http.MethodGet, Path: "/docs/playground", Summary: "Interactive API console", Handler: playgroundHandler, Tier: tierOps},
+		{Name: "openapi", Method: http.MethodGet, Path: "/openapi.json", Summary: "This API's OpenAPI document", Handler: s.openAPIHandler, Tier: tierOps},
+		// gzip when accepted, OpenMetrics negotiation
+		{Name: "metrics", Method: http.MethodGet, Path: "/metrics", Summary: "Prometheus metrics", Handler: gin.WrapH(metricsHandler()), Tier: tierOps},
+	}
+	for i := range rts {
+		if rts[i].Auth == "" {
+			rts[i].Auth = authPublic
+		}
+		if rts[i].Tier == "" {
+			rts[i].Tier = tierDefault
+		}
+		if rts[i].Tier == tierAdmin && rts[i].Auth != authAdmin {
+			panic("route " + rts[i].Name + ": admin tier routes must use authAdmin")
+		}
+	}
+	return rts
+}
+
+// routeKey indexes the registry by method and gin pattern.
+func routeKey(method, path string) string { return method + " " + path }
+
+// indexRoutes maps routeKey to each registered route.
+func indexRoutes(rts []route) map[string]route {
+	idx := make(map[string]route, len(rts))
+	for _, rt := range rts {
+		idx[routeKey(rt.Method, rt.Path)] = rt
+	}
+	return idx
+}
+
+// registeredRoute returns the registry entry of the route c matched.
+func (s *Server) registeredRoute(c *gin.Context) (route, bool) {
+	rt, ok := s.routeIndex[routeKey(c.Request.Method, c.FullPath())]
+	return rt, ok
+}
+
+// routeLabel is the route label of c's metrics: the registered path, the
+// gin pattern of routes added by plugins, or else the request path.
+func (s *Server) routeLabel(c *gin.Context) string {
+	if rt, ok := s.registeredRoute(c); ok {
+		return rt.Path
+	}
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return c.Request.URL.Path
+}
+
+// registerRoutes adds rts to r, behind apiKeyMiddleware and
+// adminKeyMiddleware where required.
+func registerRoutes(r *gin.Engine, s *Server, rts []route) {
+	for _, rt := range rts {
+		var handlers []gin.HandlerFunc
+		switch rt.Auth {
+		case authAPIKey:
+			handlers = append(handlers, apiKeyMiddleware(s))
+		case authAdmin:
+			handlers = append(handlers, apiKeyMiddleware(s), adminKeyMiddleware())
+		}
+		r.Handle(rt.Method, rt.Path, append(handlers, rt.Handler)...)
+	}
+}
+
+func healthHandler(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+// helloHandler serves GET /hello?name=NAME.
+func helloHandler(c *gin.Context) {
+	name := c.Query("name")
+	if name == "" {
+		name = "world"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "hello " + name})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"ci_education/plugin"
+	"ci_education/storage"
+)
+
+func TestRouteRegistryBuildsEngine(t *testing.T) {
+	s := newTestServer("http://unused")
+	r := setupRouter(s)
+
+	registered := map[string]bool{}
+	for _, ri := range r.Routes() {
+		registered[routeKey(ri.Method, ri.Path)] = true
+	}
+	names := map[string]bool{}
+	for _, rt := range s.routes() {
+		if !registered[routeKey(rt.Method, rt.Path)] {
+			t.Errorf("%s %s is in the registry but not routed", rt.Method, rt.Path)
+		}
+		if names[rt.Name] {
+			t.Errorf("duplicate route name %q", rt.Name)
+		}
+		names[rt.Name] = true
+		if rt.Summary == "" || rt.Handler == nil {
+			t.Errorf("%s: expected a summary and a handler", rt.Name)
+		}
+	}
+	for _, pr := range plugin.Routes() {
+		delete(registered, routeKey(pr.Method, pr.Path))
+	}
+	if len(registered) != len(s.routeIndex) {
+		t.Errorf("expected every non-plugin endpoint to come from the registry: %d routed, %d registered", len(registered), len(s.routeIndex))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/teams", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected api_key routes to require a key, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello?name=ash", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"message":"hello ash"}` {
+		t.Fatalf("unexpected /hello response %d %s", w.Code, w.Body)
+	}
+	if got := testutil.ToFloat64(s.metrics.requestsTotal.WithLabelValues("/hello", http.MethodGet, "200", callerNone)); got != 1 {
+		t.Fatalf("expected the request counted under its registered route, got %v", got)
+	}
+}
+
+func TestAdminRoutesRequireAdminKey(t *testing.T) {
+	s := withAdminKey(t, newTestServer("http://unused"))
+	if err := s.store.CreateAPIKey(context.Background(), storage.APIKey{Key: "user-key", Owner: "ash"}); err != nil {
+		t.Fatal(err)
+	}
+	r := setupRouter(s)
+	for _, rt := range s.routes() {
+		if !strings.HasPrefix(rt.Path, "/admin") {
+			continue
+		}
+		if rt.Auth != authAdmin || rt.Tier != tierAdmin {
+			t.Errorf("%s %s is registered with auth %q and tier %q", rt.Method, rt.Path, rt.Auth, rt.Tier)
+		}
+		path := strings.NewReplacer(":id", "x", ":name", "x").Replace(rt.Path)
+		for key, want := range map[string]int{"": http.StatusUnauthorized, "user-key": http.StatusForbidden} {
+			req := httptest.NewRequest(rt.Method, path, nil)
+			if key != "" {
+				req.Header.Set("X-API-Key", key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%s %s with key %q: expected %d, got %d", rt.Method, path, key, want, w.Code)
+			}
+		}
+	}
+}
+
+func TestRateLimitByTier(t *testing.T) {
+	s := newTestServer("http://unused")
+	s.rateLimit = newRateLimiter(parseRouteLimits("@ops=0.001:1"))
+	r := setupRouter(s)
+
+	// the ops tier shares one bucket
+	for _, tc := range []struct {
+		path string
+		want int
+	}{{"/health", http.StatusOK}, {"/errors", http.StatusTooManyRequests}, {"/hello", http.StatusOK}} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.path, tc.want, w.Code)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	s := newTestServer("http://unused")
+	r := setupRouter(s)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	type operation struct {
+		OperationID string                `json:"operationId"`
+		Parameters  []map[string]any      `json:"parameters"`
+		Security    []map[string][]string `json:"security"`
+		Responses   map[string]struct {
+			Content map[string]struct {
+				Schema map[string]any `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+		Tier   string `json:"x-rate-tier"`
+		Cached bool   `json:"x-cached"`
+	}
+	var doc struct {
+		OpenAPI    string                          `json:"openapi"`
+		Paths      map[string]map[string]operation `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	if len(doc.Paths) == 0 || doc.OpenAPI != "3.0.3" || doc.Components.Schemas["pokemon"] == nil {
+		t.Fatalf("unexpected document header: %s", w.Body.String()[:200])
+	}
+	op := doc.Paths["/pokemon/{name}"]["get"]
+	if op.OperationID != "getPokemon" || len(op.Parameters) != 1 || op.Parameters[0]["name"] != "name" || !op.Cached || op.Tier != "default" {
+		t.Fatalf("unexpected getPokemon operation: %+v", op)
+	}
+	if ref := op.Responses["200"].Content["application/json"].Schema["$ref"]; ref != "#/components/schemas/pokemon" {
+		t.Fatalf("expected the contract's pokemon schema, got %v", ref)
+	}
+	if del := doc.Paths["/teams/{id}"]["delete"]; del.OperationID != "deleteTeam" || len(del.Security) != 1 {
+		t.Fatalf("expected deleteTeam to require an API key: %+v", del)
+	}
+	if tier := doc.Paths["/admin/slo"]["get"].Tier; tier != "admin" {
+		t.Fatalf("expected the admin tier, got %q", tier)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// responseSchemasJSON holds the response contract: for each route and
+// status, the OpenAPI-style schema its JSON body must match. Error
+// responses of every route must match components.error.
+//
+//go:embed schemas/responses.json
+var responseSchemasJSON []byte
+
+// maxSchemaViolations caps the violations reported per response.
+const maxSchemaViolations = 5
+
+// jsonSchema is the subset of OpenAPI schema objects the contract uses.
+// Properties not listed are allowed.
+type jsonSchema struct {
+	Ref        string                 `json:"$ref"`
+	Type       string                 `json:"type"`
+	Nullable   bool                   `json:"nullable"`
+	Required   []string               `json:"required"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Items      *jsonSchema            `json:"items"`
+}
+
+// responseSchemas validates response bodies against the contract.
+type responseSchemas struct {
+	components map[string]*jsonSchema
+	routes     map[string]map[string]*jsonSchema // route -> status -> schema
+}
+
+func loadResponseSchemas(data []byte) (*responseSchemas, error) {
+	var doc struct {
+		Components map[string]*jsonSchema            `json:"components"`
+		Routes     map[string]map[string]*jsonSchema `json:"routes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("schemas: %w", err)
+	}
+	if doc.Components["error"] == nil {
+		return nil, fmt.Errorf("schemas: components.error is missing")
+	}
+	return &responseSchemas{components: doc.Components, routes: doc.Routes}, nil
+}
+
+// schemaFor returns the schema of a route's response with the given status,
+// or nil when the contract does not describe it.
+func (rs *responseSchemas) schemaFor(route string, status int) *jsonSchema {
+	if status >= 400 {
+		return rs.components["error"]
+	}
+	return rs.routes[route][strconv.Itoa(status)]
+}
+
+// validate returns the places where body does not match sch, at most
+// maxSchemaViolations of them.
+func (rs *responseSchemas) validate(sch *jsonSchema, body []byte) []string {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return []string{"$: body is not JSON"}
+	}
+	var out []string
+	rs.check(sch, v, "$", &out)
+	return out
+}
+
+func (rs *responseSchemas) check(sch *jsonSchema, v any, path string, out *[]string) {
+	if len(*out) >= maxSchemaViolations {
+		return
+	}
+	if sch.Ref != "" {
+		ref := rs.components[sch.Ref]
+		if ref == nil {
+			*out = append(*out, path+": unknown schema "+sch.Ref)
+			return
+		}
+		sch = ref
+	}
+	if v == nil {
+		if !sch.Nullable && sch.Type != "" {
+			*out = append(*out, path+": expected "+sch.Type+", got null")
+		}
+		return
+	}
+	if sch.Type != "" && jsonType(v, sch.Type) != sch.Type {
+		*out = append(*out, path+": expected "+sch.Type+", got "+jsonType(v, sch.Type))
+		return
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range sch.Required {
+			if _, ok := v[name]; !ok {
+				*out = append(*out, path+"."+name+": required property is missing")
+			}
+		}
+		names := make([]string, 0, len(sch.Properties))
+		for name := range sch.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if pv, ok := v[name]; ok {
+				rs.check(sch.Properties[name], pv, path+"."+name, out)
+			}
+		}
+	case []any:
+		if sch.Items != nil {
+			for i, item := range v {
+				rs.check(sch.Items, item, path+"["+strconv.Itoa(i)+"]", out)
+			}
+		}
+	}
+}
+
+// jsonType names the JSON type of a decoded value. Whole numbers count as
+// integers when want is "integer", otherwise as numbers.
+func jsonType(v any, want string) string {
+	switch v := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if want == "integer" && v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "null"
+}
+
+// middleware: in strict mode, validate JSON responses against the contract
+// before they are written. A violation replaces the response with a 500
+// listing what failed, and is counted in schema_violations_total.
+func schemaValidationMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.schemas == nil {
+			c.Next()
+			return
+		}
+		orig := c.Writer
+		bw := newBufferedWriter(orig)
+		c.Writer = bw
+		c.Next()
+		c.Writer = orig
+
+		route := c.FullPath()
+		sch := s.schemas.schemaFor(route, bw.status)
+		if sch == nil || !strings.HasPrefix(orig.Header().Get("Content-Type"), "application/json") {
+			bw.flushTo(bw.bytes())
+			return
+		}
+		violations := s.schemas.validate(sch, bw.bytes())
+		if len(violations) == 0 {
+			bw.flushTo(bw.bytes())
+			return
+		}
+		bw.discardBodyHeaders()
+		rid, _ := c.Get("request_id")
+		log.Printf("rid=%v route=%s status=%d response violates its schema: %s", rid, route, bw.status, strings.Join(violations, "; "))
+		s.metrics.schemaViolationsTotal.WithLabelValues(route).Inc()
+		writeError(c, apierror.Internal("response violates its schema: "+strings.Join(violations, "; ")))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestResponseSchemasLoad(t *testing.T) {
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatalf("embedded schemas: %v", err)
+	}
+	for _, route := range []string{"/pokemon/:name", "/move/:name", "/type/:name"} {
+		if rs.schemaFor(route, http.StatusOK) == nil {
+			t.Errorf("expected a 200 schema for %s", route)
+		}
+	}
+	if rs.schemaFor("/move/:name", http.StatusNotFound) != rs.components["error"] {
+		t.Error("expected error responses to use components.error")
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch := rs.schemaFor("/move/:name", http.StatusOK)
+	for body, want := range map[string]string{
+		`{"name":"swift","type":"normal","damage_class":"special","power":60,"accuracy":null,"pp":20,"priority":0}`:   "",
+		`{"name":"swift","type":"normal","damage_class":"special","power":60.5,"accuracy":null,"pp":20,"priority":0}`: "$.power: expected integer, got number",
+		`{"type":"normal","damage_class":"special","power":60,"accuracy":null,"pp":20,"priority":0}`:                  "$.name: required property is missing",
+		`[1]`: "$: expected object, got array",
+	} {
+		got := strings.Join(rs.validate(sch, []byte(body)), "; ")
+		if got != want {
+			t.Errorf("%s: expected %q, got %q", body, want, got)
+		}
+	}
+}
+
+func TestStrictResponseValidation(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/move/swift": `{"name":"swift","power":60,"accuracy":null,"pp":20,"priority":0,"type":{"name":"normal"},"damage_class":{"name":"special"}}`,
+	})
+	s := newTestServer(ts.URL)
+	s.moves = newTTLCache[moveDetail](time.Minute)
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.schemas = rs
+	r := setupRouter(s)
+
+	for _, path := range []string{"/move/swift", "/move/splash-dance"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code == http.StatusInternalServerError {
+			t.Fatalf("%s: expected a valid response, got %d %s", path, w.Code, w.Body)
+		}
+	}
+
+	// A contract the handler does not meet: the response is replaced.
+	rs.routes["/move/:name"]["200"].Required = append(rs.routes["/move/:name"]["200"].Required, "generation")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/move/swift", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "$.generation: required property is missing") {
+		t.Fatalf("expected 500 naming the violation, got %d %s", w.Code, w.Body)
+	}
+	if got := testutil.ToFloat64(s.metrics.schemaViolationsTotal.WithLabelValues("/move/:name")); got != 1 {
+		t.Fatalf("expected 1 schema violation, got %v", got)
+	}
+}
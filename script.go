@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+
+	"ci_education/apierror"
+)
+
+// scriptHook is a Lua script bound to one route. It may define
+//
+//	function on_request(req)   -- req.method, req.path, req.query, req.headers
+//	function on_response(res)  -- res.status, res.headers, res.body
+//
+// and mutate its argument in place. Query and headers map names to a string,
+// or to an array of strings for repeated names. A JSON response body is
+// exposed as a decoded table and re-encoded afterwards, any other body as a
+// string; decoded arrays stay arrays even when empty, and JSON null is the
+// global null rather than nil, so neither is lost in the round trip. Scripts
+// run in a sandbox with only the base (minus file loading), table, string
+// and math libraries.
+type scriptHook struct {
+	route       string
+	proto       *lua.FunctionProto
+	timeout     time.Duration
+	hasRequest  bool
+	hasResponse bool
+	pool        sync.Pool
+}
+
+// Registry keys of each state's JSON null sentinel and array metatable.
+const (
+	luaNullKey  = "ci_education.null"
+	luaArrayKey = "ci_education.array"
+)
+
+// jsonNull is the value of the userdata that stands for JSON null in Lua,
+// where nil would drop an object field or shift the rest of an array.
+type jsonNull struct{}
+
+// parseScriptHooks parses a comma-separated list of route=file items.
+func parseScriptHooks(v string) map[string]string {
+	out := make(map[string]string)
+	for _, item := range splitList(v) {
+		route, file, ok := strings.Cut(item, "=")
+		if !ok || route == "" || file == "" {
+			log.Printf("script: ignoring invalid entry %q", item)
+			continue
+		}
+		out[route] = file
+	}
+	return out
+}
+
+// loadScriptHooks compiles each route's script. Any failure is fatal for
+// startup so a broken transformation is never silently skipped.
+func loadScriptHooks(files map[string]string, timeout time.Duration) (map[string]*scriptHook, error) {
+	hooks := make(map[string]*scriptHook, len(files))
+	for route, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("script for %s: %w", route, err)
+		}
+		h, err := newScriptHook(route, file, string(src), timeout)
+		if err != nil {
+			return nil, err
+		}
+		hooks[route] = h
+	}
+	return hooks, nil
+}
+
+func newScriptHook(route, name, src string, timeout time.Duration) (*scriptHook, error) {
+	chunk, err := parse.Parse(strings.NewReader(src), name)
+	if err != nil {
+		return nil, fmt.Errorf("script for %s: %w", route, err)
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("script for %s: %w", route, err)
+	}
+	h := &scriptHook{route: route, proto: proto, timeout: timeout}
+	L, err := h.newState()
+	if err != nil {
+		return nil, fmt.Errorf("script for %s: %w", route, err)
+	}
+	h.hasRequest = L.GetGlobal("on_request").Type() == lua.LTFunction
+	h.hasResponse = L.GetGlobal("on_response").Type() == lua.LTFunction
+	if !h.hasRequest && !h.hasResponse {
+		L.Close()
+		return nil, fmt.Errorf("script for %s defines neither on_request nor on_response", route)
+	}
+	h.pool.Put(L)
+	return h, nil
+}
+
+func (h *scriptHook) newState() (*lua.LState, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, unsafe := range []string{"dofile", "loadfile", "load", "loadstring", "module", "require"} {
+		L.SetGlobal(unsafe, lua.LNil)
+	}
+	null := L.NewUserData()
+	null.Value = jsonNull{}
+	L.G.Registry.RawSetString(luaNullKey, null)
+	L.SetGlobal("null", null)
+	arrayMeta := L.NewTable()
+	arrayMeta.RawSetString("__jsonarray", lua.LTrue)
+	L.G.Registry.RawSetString(luaArrayKey, arrayMeta)
+	L.Push(L.NewFunctionFromProto(h.proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		L.Close()
+		return nil, err
+	}
+	return L, nil
+}
+
+// call runs the named hook function with arg under the hook's timeout.
+func (h *scriptHook) call(ctx context.Context, fn string, arg *lua.LTable, L *lua.LState) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+	return L.CallByParam(lua.P{Fn: L.GetGlobal(fn), NRet: 0, Protect: true}, arg)
+}
+
+func (h *scriptHook) getState() (*lua.LState, error) {
+	if L, ok := h.pool.Get().(*lua.LState); ok {
+		return L, nil
+	}
+	return h.newState()
+}
+
+// middleware: per-route Lua request/response transformation
+func scriptMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := s.scripts[c.FullPath()]
+		if h == nil {
+			c.Next()
+			return
+		}
+		L, err := h.getState()
+		if err != nil {
+			log.Printf("script %s: %v", h.route, err)
+			writeError(c, apierror.Internal("script hook failed"))
+			c.Abort()
+			return
+		}
+		// A state that raised an error may be left inconsistent; only
+		// successful states go back to the pool.
+		healthy := false
+		defer func() {
+			if healthy {
+				h.pool.Put(L)
+			} else {
+				L.Close()
+			}
+		}()
+
+		if h.hasRequest {
+			req := L.NewTable()
+			req.RawSetString("method", lua.LString(c.Request.Method))
+			req.RawSetString("path", lua.LString(c.Request.URL.Path))
+			req.RawSetString("query", stringMapToLua(L, c.Request.URL.Query()))
+			req.RawSetString("headers", stringMapToLua(L, c.Request.Header))
+			if err := h.call(c.Request.Context(), "on_request", req, L); err != nil {
+				log.Printf("script %s on_request: %v", h.route, err)
+				writeError(c, apierror.Internal("script hook failed"))
+				c.Abort()
+				return
+			}
+			applyRequestTable(c.Request, req)
+		}
+
+		if !h.hasResponse {
+			healthy = true
+			c.Next()
+			return
+		}
+
+		orig := c.Writer
+		bw := newBufferedWriter(orig)
+		c.Writer = bw
+		c.Next()
+		c.Writer = orig
+
+		res := L.NewTable()
+		res.RawSetString("status", lua.LNumber(bw.Status()))
+		res.RawSetString("headers", stringMapToLua(L, orig.Header()))
+		isJSON := strings.HasPrefix(orig.Header().Get("Content-Type"), "application/json")
+		var body any
+		if isJSON && json.Unmarshal(bw.bytes(), &body) == nil {
+			res.RawSetString("body", goToLua(L, body))
+		} else {
+			isJSON = false
+			res.RawSetString("body", lua.LString(bw.bytes()))
+		}
+		if err := h.call(c.Request.Context(), "on_response", res, L); err != nil {
+			log.Printf("script %s on_response: %v", h.route, err)
+			orig.Header().Del("Content-Length")
+			writeError(c, apierror.Internal("script hook failed"))
+			return
+		}
+
+		applyHeaderTable(orig.Header(), res.RawGetString("headers"))
+		if st, ok := res.RawGetString("status").(lua.LNumber); ok && st >= 100 && st <= 599 {
+			bw.status = int(st)
+		}
+		out := bw.bytes()
+		if isJSON {
+			if b, err := json.Marshal(luaToGo(res.RawGetString("body"))); err == nil {
+				out = b
+			}
+		} else if str, ok := res.RawGetString("body").(lua.LString); ok {
+			out = []byte(str)
+		}
+		healthy = true
+		bw.flushTo(out)
+	}
+}
+
+// stringMapToLua exposes query parameters or headers: a single value as a
+// string, repeated values as an array of strings.
+func stringMapToLua(L *lua.LState, m map[string][]string) *lua.LTable {
+	t := L.NewTable()
+	for k, v := range m {
+		switch len(v) {
+		case 0:
+		case 1:
+			t.RawSetString(k, lua.LString(v[0]))
+		default:
+			values := L.CreateTable(len(v), 0)
+			for _, e := range v {
+				values.Append(lua.LString(e))
+			}
+			t.RawSetString(k, values)
+		}
+	}
+	return t
+}
+
+// luaStrings reads back a value of stringMapToLua's tables.
+func luaStrings(v lua.LValue) []string {
+	t, ok := v.(*lua.LTable)
+	if !ok {
+		return []string{v.String()}
+	}
+	out := make([]string, 0, t.MaxN())
+	for i := 1; i <= t.MaxN(); i++ {
+		out = append(out, t.RawGetInt(i).String())
+	}
+	return out
+}
+
+// applyRequestTable copies the script's query and header tables back onto r.
+func applyRequestTable(r *http.Request, t *lua.LTable) {
+	if q, ok := t.RawGetString("query").(*lua.LTable); ok {
+		values := url.Values{}
+		q.ForEach(func(k, v lua.LValue) {
+			values[k.String()] = luaStrings(v)
+		})
+		r.URL.RawQuery = values.Encode()
+	}
+	applyHeaderTable(r.Header, t.RawGetString("headers"))
+}
+
+// applyHeaderTable makes h match the script's header table: keys removed by
+// the script are deleted, others are set.
+func applyHeaderTable(h http.Header, v lua.LValue) {
+	t, ok := v.(*lua.LTable)
+	if !ok {
+		return
+	}
+	want := http.Header{}
+	t.ForEach(func(k, v lua.LValue) {
+		want[http.CanonicalHeaderKey(k.String())] = luaStrings(v)
+	})
+	for k := range h {
+		if _, keep := want[k]; !keep {
+			h.Del(k)
+		}
+	}
+	for k, v := range want {
+		h[k] = v
+	}
+}
+
+// goToLua converts decoded JSON to Lua values. Arrays are tagged with the
+// state's array metatable and null becomes the state's null sentinel.
+func goToLua(L *lua.LState, v any) lua.LValue {
+	switch x := v.(type) {
+	case nil:
+		return L.G.Registry.RawGetString(luaNullKey)
+	case bool:
+		return lua.LBool(x)
+	case float64:
+		return lua.LNumber(x)
+	case string:
+		return lua.LString(x)
+	case []any:
+		t := L.CreateTable(len(x), 0)
+		for _, e := range x {
+			t.Append(goToLua(L, e))
+		}
+		t.Metatable = L.G.Registry.RawGetString(luaArrayKey)
+		return t
+	case map[string]any:
+		t := L.CreateTable(0, len(x))
+		for k, e := range x {
+			t.RawSetString(k, goToLua(L, e))
+		}
+		return t
+	default:
+		return lua.LString(fmt.Sprint(x))
+	}
+}
+
+// luaToGo converts Lua values back to JSON-encodable Go values. Tables
+// tagged as arrays by goToLua, and tables whose keys are exactly 1..n, become
+// arrays; other tables become objects.
+func luaToGo(v lua.LValue) any {
+	switch x := v.(type) {
+	case *lua.LNilType:
+		return nil
+	case *lua.LUserData:
+		if _, ok := x.Value.(jsonNull); ok {
+			return nil
+		}
+		return v.String()
+	case lua.LBool:
+		return bool(x)
+	case lua.LNumber:
+		return float64(x)
+	case lua.LString:
+		return string(x)
+	case *lua.LTable:
+		n, count := x.MaxN(), 0
+		x.ForEach(func(lua.LValue, lua.LValue) { count++ })
+		if isLuaArray(x) || (n > 0 && n == count) {
+			arr := make([]any, 0, n)
+			for i := 1; i <= n; i++ {
+				arr = append(arr, luaToGo(x.RawGetInt(i)))
+			}
+			return arr
+		}
+		obj := make(map[string]any, count)
+		x.ForEach(func(k, v lua.LValue) { obj[k.String()] = luaToGo(v) })
+		return obj
+	default:
+		return v.String()
+	}
+}
+
+// isLuaArray reports whether t was decoded from a JSON array.
+func isLuaArray(t *lua.LTable) bool {
+	mt, ok := t.Metatable.(*lua.LTable)
+	return ok && mt.RawGetString("__jsonarray") == lua.LTrue
+}
+
+// scriptRoutes lists routes with hooks, for startup logging.
+func scriptRoutes(hooks map[string]*scriptHook) []string {
+	out := make([]string, 0, len(hooks))
+	for r := range hooks {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestScriptHooks(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu": `{"name":"pikachu","height":4,"weight":60,"base_experience":112}`,
+	})
+	reqHook, err := newScriptHook("/hello", "hello.lua", `
+function on_request(req)
+  req.query.name = string.upper(req.query.name or "nobody")
+end`, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resHook, err := newScriptHook("/pokemon/:name", "pokemon.lua", `
+function on_response(res)
+  res.body.base_experience = nil
+  res.body.tags = {"mascot", "electric"}
+  res.headers["X-Shaped"] = "lite"
+end`, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(ts.URL)
+	s.scripts = map[string]*scriptHook{"/hello": reqHook, "/pokemon/:name": resHook}
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello?name=ash", nil))
+	if body := w.Body.String(); body != `{"message":"hello ASH"}` {
+		t.Fatalf("unexpected hello body: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Shaped") != "lite" {
+		t.Fatalf("unexpected response %d, headers %v", w.Code, w.Header())
+	}
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if _, ok := body["base_experience"]; ok {
+		t.Fatalf("expected base_experience removed: %v", body)
+	}
+	if tags, ok := body["tags"].([]any); !ok || len(tags) != 2 || tags[0] != "mascot" {
+		t.Fatalf("expected tags array, got %v", body["tags"])
+	}
+}
+
+func TestScriptHooksRoundTrip(t *testing.T) {
+	h, err := newScriptHook("/raw", "noop.lua", `
+function on_request(req) end
+function on_response(res)
+  if res.body.items[2] ~= null then error("expected null") end
+end`, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{scripts: map[string]*scriptHook{"/raw": h}}
+	r := gin.New()
+	r.Use(scriptMiddleware(s))
+	r.GET("/raw", func(c *gin.Context) {
+		c.Header("X-Tags", strings.Join(c.Request.URL.Query()["tag"], ",")+";"+strings.Join(c.Request.Header.Values("X-Trace"), ","))
+		c.Data(http.StatusOK, "application/json", []byte(`{"items":[1,null,3],"results":[]}`))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/raw?tag=a&tag=b", nil)
+	req.Header.Add("X-Trace", "1")
+	req.Header.Add("X-Trace", "2")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if body := w.Body.String(); body != `{"items":[1,null,3],"results":[]}` {
+		t.Fatalf("expected a no-op hook to keep the body, got %s", body)
+	}
+	if got := w.Header().Get("X-Tags"); got != "a,b;1,2" {
+		t.Fatalf("expected repeated query parameters and headers kept, got %q", got)
+	}
+}
+
+func TestScriptHookErrors(t *testing.T) {
+	if _, err := newScriptHook("/x", "x.lua", `local x = 1`, time.Second); err == nil {
+		t.Fatal("expected error for script without hooks")
+	}
+	h, err := newScriptHook("/hello", "loop.lua", `function on_request(req) while true do end end`, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer("")
+	s.scripts = map[string]*scriptHook{"/hello": h}
+	w := httptest.NewRecorder()
+	setupRouter(s).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 on script timeout, got %d", w.Code)
+	}
+}
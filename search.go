@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// refreshNameIndex replaces the name index with a fresh copy of the upstream
+// list. On failure the current index is kept.
+func (s *Server) refreshNameIndex(ctx context.Context) error {
+	var list resourceList
+	if _, err := s.fetchUpstream(ctx, "/pokemon?limit="+strconv.Itoa(fullListLimit)+"&offset=0", &list); err != nil {
+		return err
+	}
+	ix := s.names
+	ix.mu.Lock()
+	ix.names, ix.fetchedAt, ix.tree = list.Results, time.Now(), nil
+	ix.mu.Unlock()
+	return nil
+}
+
+// startNameIndexSync fills the name index in the background and refreshes
+// it every interval, so lookups are served locally instead of waiting on the
+// upstream once the index expires. interval should be below the index TTL.
+func (s *Server) startNameIndexSync(interval time.Duration) {
+	if s.names == nil || interval <= 0 {
+		return
+	}
+	s.workers.goWorker("name_index", func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			if err := s.refreshNameIndex(context.Background()); err != nil {
+				log.Printf("name index: refresh failed: %v", err)
+			}
+			<-t.C
+		}
+	})
+}
+
+// searchHandler serves GET /pokemon/search?q=&limit=: pokemon whose name
+// starts with q, in lexical order, from the local name index.
+func (s *Server) searchHandler(c *gin.Context) {
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	if q == "" {
+		writeError(c, apierror.BadRequest("q is required"))
+		return
+	}
+	limit := defaultSearchLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(c, apierror.BadRequest("limit must be a positive integer"))
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	ctx := c.Request.Context()
+	tree, status, err := s.nameTree(ctx)
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon list not found"))
+		return
+	}
+	matches := tree.withPrefix(q, limit)
+	names, _, _ := s.pokedexNames(ctx) // fresh: nameTree just loaded it
+
+	pos := make(map[string]int, len(matches))
+	results := make([]namedResource, len(matches))
+	for i, name := range matches {
+		pos[name] = i
+		results[i] = namedResource{Name: name}
+	}
+	for _, n := range names {
+		if i, ok := pos[n.Name]; ok {
+			results[i] = n
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"query": q, "results": results})
+}
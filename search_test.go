@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon?limit=100000&offset=0": `{"count":4,"results":[{"name":"pikachu","url":"u/25"},{"name":"pichu","url":"u/172"},{"name":"raichu","url":"u/26"},{"name":"pidgey","url":"u/16"}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.names = newNameIndex(time.Hour)
+	if err := s.refreshNameIndex(context.Background()); err != nil {
+		t.Fatalf("refreshing the name index: %v", err)
+	}
+	ts.Close() // searches must be served from the synced index
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/search?q=Pi&limit=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Results []namedResource `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := []namedResource{{Name: "pichu", URL: "u/172"}, {Name: "pidgey", URL: "u/16"}}
+	if !reflect.DeepEqual(body.Results, want) {
+		t.Fatalf("expected %v, got %v", want, body.Results)
+	}
+
+	for _, q := range []string{"", "?q=pi&limit=0"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/search"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected status 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestRefreshNameIndexKeepsIndexOnFailure(t *testing.T) {
+	s := newTestServer("http://127.0.0.1:1")
+	s.names = newNameIndex(time.Hour)
+	s.names.names = []namedResource{{Name: "pikachu"}}
+	if err := s.refreshNameIndex(context.Background()); err == nil {
+		t.Fatal("expected the refresh to fail")
+	}
+	if len(s.names.names) != 1 {
+		t.Fatal("expected the previous index to be kept")
+	}
+}
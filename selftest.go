@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ci_education/storage"
+)
+
+// selftestOptions are the settings the startup self-test checks against.
+type selftestOptions struct {
+	port         string
+	driver       string   // STORAGE_DRIVER
+	cacheDirs    []string // directories the caches write to
+	maxClockSkew time.Duration
+}
+
+func selftestOptionsFromEnv() selftestOptions {
+	opts := selftestOptions{
+		port:         getenv("PORT", "8080"),
+		driver:       getenv("STORAGE_DRIVER", storage.DriverMemory),
+		maxClockSkew: time.Duration(getenvInt("SELFTEST_MAX_CLOCK_SKEW_SEC", 30)) * time.Second,
+	}
+	if path := getenv("CACHE_SNAPSHOT_PATH", ""); path != "" {
+		opts.cacheDirs = append(opts.cacheDirs, filepath.Dir(path))
+	}
+	if dir := getenv("SPRITE_CACHE_DIR", ""); dir != "" {
+		opts.cacheDirs = append(opts.cacheDirs, dir)
+	}
+	return opts
+}
+
+// selftestResult is the outcome of one self-test check.
+type selftestResult struct {
+	Name   string
+	Err    error
+	Detail string
+}
+
+// selftest runs the startup checks in order: configuration, upstream
+// reachability, cache directories, store migrations and the clock, which is
+// compared with the upstream's Date header when it answered.
+func (s *Server) selftest(ctx context.Context, opts selftestOptions) []selftestResult {
+	var results []selftestResult
+	add := func(name, detail string, err error) {
+		results = append(results, selftestResult{Name: name, Detail: detail, Err: err})
+	}
+
+	detail, err := s.selftestConfig(opts)
+	add("config", detail, err)
+	upstreamDate, detail, err := s.selftestUpstream(ctx)
+	add("upstream", detail, err)
+	detail, err = selftestCacheDirs(opts.cacheDirs)
+	add("cache", detail, err)
+	detail, err = s.selftestMigrations(ctx, opts.driver)
+	add("migrations", detail, err)
+	detail, err = selftestClock(time.Now(), upstreamDate, opts.maxClockSkew)
+	add("clock", detail, err)
+	return results
+}
+
+func (s *Server) selftestConfig(opts selftestOptions) (string, error) {
+	if p, err := strconv.Atoi(opts.port); err != nil || p < 1 || p > 65535 {
+		return "", fmt.Errorf("PORT %q is not a valid port", opts.port)
+	}
+	u, err := url.Parse(s.baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("POKEAPI_BASE_URL %q is not an absolute http(s) URL", s.baseURL)
+	}
+	return "port " + opts.port + ", upstream " + s.baseURL, nil
+}
+
+// selftestUpstream requests one list entry from the upstream and returns its
+// Date header, zero when absent.
+func (s *Server) selftestUpstream(ctx context.Context) (time.Time, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/pokemon?limit=1", nil)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	start := time.Now()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	detail := fmt.Sprintf("%s answered %d in %s", s.baseURL, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	date, _ := http.ParseTime(resp.Header.Get("Date"))
+	if resp.StatusCode != http.StatusOK {
+		return date, detail, errors.New(detail)
+	}
+	return date, detail, nil
+}
+
+// selftestCacheDirs creates and removes a file in each cache directory.
+func selftestCacheDirs(dirs []string) (string, error) {
+	if len(dirs) == 0 {
+		return "in-memory only", nil
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+		f, err := os.CreateTemp(dir, ".selftest*")
+		if err != nil {
+			return "", err
+		}
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			return "", err
+		}
+	}
+	return "writable: " + strings.Join(dirs, ", "), nil
+}
+
+// selftestMigrations checks that the store's schema is the one this build
+// expects, neither behind nor ahead of it.
+func (s *Server) selftestMigrations(ctx context.Context, driver string) (string, error) {
+	latest, err := storage.LatestVersion(driver)
+	if err != nil {
+		return "", err
+	}
+	if s.store == nil {
+		return "no store", nil
+	}
+	v, err := s.store.SchemaVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case v < latest:
+		return "", fmt.Errorf("schema version %d, %d pending (run migrate up)", v, latest-v)
+	case v > latest:
+		return "", fmt.Errorf("schema version %d is newer than this build's %d", v, latest)
+	}
+	return fmt.Sprintf("schema version %d", v), nil
+}
+
+// selftestClock checks that the local clock is past the ID epoch and, when
+// the upstream sent a Date, within maxSkew of it.
+func selftestClock(now, upstream time.Time, maxSkew time.Duration) (string, error) {
+	if now.UnixMilli() < snowflakeEpoch {
+		return "", fmt.Errorf("local time %s is before %s", now.UTC().Format(time.RFC3339), time.UnixMilli(snowflakeEpoch).UTC().Format(time.RFC3339))
+	}
+	if upstream.IsZero() {
+		return "no upstream Date to compare with", nil
+	}
+	// Date has a one-second resolution
+	skew := now.Sub(upstream).Round(time.Second)
+	if skew.Abs() > maxSkew {
+		return "", fmt.Errorf("local clock is %s off the upstream's (max %s)", skew, maxSkew)
+	}
+	return fmt.Sprintf("%s off the upstream's", skew), nil
+}
+
+// writeSelftestReport prints one line per check and returns how many failed.
+func writeSelftestReport(w io.Writer, results []selftestResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL %-10s %v\n", r.Name, r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "ok   %-10s %s\n", r.Name, r.Detail)
+	}
+	return failed
+}
+
+// parseServeArgs parses the flags of "serve" and reports whether the
+// self-test was requested.
+func parseServeArgs(args []string, stderr io.Writer) (selftest bool, err error) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&selftest, "selftest", false, "run the startup checks before binding the port and exit 1 if any fail")
+	err = fs.Parse(args)
+	return selftest, err
+}
+
+// runSelftest runs the self-test for "serve --selftest", bounded by
+// SELFTEST_TIMEOUT_SEC, and prints its report. The server must not start
+// when it returns false.
+func (s *Server) runSelftest(stdout io.Writer) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(getenvInt("SELFTEST_TIMEOUT_SEC", 10))*time.Second)
+	defer cancel()
+	results := s.selftest(ctx, selftestOptionsFromEnv())
+	if failed := writeSelftestReport(stdout, results); failed > 0 {
+		fmt.Fprintf(stdout, "selftest: %d of %d checks failed\n", failed, len(results))
+		return false
+	}
+	fmt.Fprintf(stdout, "selftest: all %d checks passed\n", len(results))
+	return true
+}
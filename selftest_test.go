@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ci_education/storage"
+)
+
+func TestSelftestPasses(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{"/pokemon": `{"count":1,"results":[]}`})
+	s := newTestServer(ts.URL)
+	s.store = storage.NewMemory()
+	opts := selftestOptions{port: "8080", driver: storage.DriverMemory, cacheDirs: []string{filepath.Join(t.TempDir(), "sprites")}, maxClockSkew: time.Minute}
+
+	results := s.selftest(context.Background(), opts)
+	var out bytes.Buffer
+	if failed := writeSelftestReport(&out, results); failed != 0 {
+		t.Fatalf("expected every check to pass:\n%s", out.String())
+	}
+	for _, name := range []string{"config", "upstream", "cache", "migrations", "clock"} {
+		if !strings.Contains(out.String(), "ok   "+name) {
+			t.Fatalf("missing %s in report:\n%s", name, out.String())
+		}
+	}
+}
+
+func TestSelftestFailures(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(ts.URL)
+	opts := selftestOptions{port: "http", driver: storage.DriverMemory, cacheDirs: []string{notADir}, maxClockSkew: time.Minute}
+
+	results := s.selftest(context.Background(), opts)
+	failed := map[string]bool{}
+	for _, r := range results {
+		failed[r.Name] = r.Err != nil
+	}
+	want := map[string]bool{"config": true, "upstream": true, "cache": true, "migrations": false, "clock": false}
+	for name, f := range want {
+		if failed[name] != f {
+			t.Errorf("%s: expected failed=%v, got %v", name, f, failed[name])
+		}
+	}
+	if n := writeSelftestReport(io.Discard, results); n != 3 {
+		t.Fatalf("expected 3 failed checks, got %d", n)
+	}
+}
+
+func TestSelftestClock(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := selftestClock(now, now.Add(-10*time.Second), 30*time.Second); err != nil {
+		t.Fatalf("expected 10s skew to pass: %v", err)
+	}
+	if _, err := selftestClock(now, now.Add(time.Minute), 30*time.Second); err == nil {
+		t.Fatal("expected a minute of skew to fail")
+	}
+	if _, err := selftestClock(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}, time.Minute); err == nil {
+		t.Fatal("expected a clock before the epoch to fail")
+	}
+	if _, err := selftestClock(now, time.Time{}, time.Minute); err != nil {
+		t.Fatalf("expected a missing upstream Date to pass: %v", err)
+	}
+}
+
+func TestParseServeArgs(t *testing.T) {
+	if on, err := parseServeArgs([]string{"--selftest"}, io.Discard); err != nil || !on {
+		t.Fatalf("expected --selftest to be parsed, got %v %v", on, err)
+	}
+	if on, err := parseServeArgs(nil, io.Discard); err != nil || on {
+		t.Fatalf("expected the self-test off by default, got %v %v", on, err)
+	}
+	if _, err := parseServeArgs([]string{"--bogus"}, io.Discard); err == nil {
+		t.Fatal("expected an unknown flag to fail")
+	}
+}
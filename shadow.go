@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// shadowMirror asynchronously replays a sample of upstream requests against a
+// secondary base URL so a new mirror can be validated before cutover. Shadow
+// responses are discarded; only the outcome is compared, in metrics.
+type shadowMirror struct {
+	client  *http.Client
+	baseURL string
+	percent int
+	timeout time.Duration
+	metrics *metrics
+	differ  *shadowDiffer // optional body comparison
+}
+
+// newShadowMirror returns nil (mirroring disabled) when baseURL is empty or
+// percent is not positive.
+func newShadowMirror(client *http.Client, baseURL string, percent int, timeout time.Duration, m *metrics) *shadowMirror {
+	if baseURL == "" || percent <= 0 {
+		return nil
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return &shadowMirror{client: client, baseURL: baseURL, percent: percent, timeout: timeout, metrics: m}
+}
+
+// maxShadowBodyBytes caps how much of a shadow response is read for diffing.
+const maxShadowBodyBytes = 1 << 20
+
+// mirror sends path to the shadow upstream in the background, if sampled, and
+// records whether its status matched the primary's. When a differ is set and
+// both sides returned 200, primaryBody is compared against the shadow body.
+func (m *shadowMirror) mirror(path string, primaryStatus int, primaryBody []byte) {
+	if m == nil || rand.IntN(100) >= m.percent {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.baseURL+path, nil)
+		if err != nil {
+			m.metrics.shadowRequestsTotal.WithLabelValues("error").Inc()
+			return
+		}
+		resp, err := m.client.Do(req)
+		if err != nil {
+			m.metrics.shadowRequestsTotal.WithLabelValues("error").Inc()
+			return
+		}
+		var shadowBody []byte
+		if m.differ != nil && primaryStatus == http.StatusOK && resp.StatusCode == http.StatusOK {
+			shadowBody, _ = io.ReadAll(io.LimitReader(resp.Body, maxShadowBodyBytes))
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if shadowBody != nil {
+			m.differ.compare(path, primaryBody, shadowBody)
+		}
+
+		result := "mismatch"
+		if normalizeUpstreamStatus(resp.StatusCode) == primaryStatus {
+			result = "match"
+		}
+		m.metrics.shadowRequestsTotal.WithLabelValues(result).Inc()
+		m.metrics.shadowStatusTotal.WithLabelValues(strconv.Itoa(primaryStatus), strconv.Itoa(resp.StatusCode)).Inc()
+	}()
+}
+
+// normalizeUpstreamStatus maps a raw upstream status to the status
+// fetchUpstream reports for it, so primary and shadow outcomes are comparable:
+// 200, 404 and 429 pass through and anything else, 503 included, is a 502.
+// fetchUpstream's own 503s (open breaker, quota, bulkhead) never reach the
+// upstream and are not mirrored.
+func normalizeUpstreamStatus(code int) int {
+	switch code {
+	case http.StatusOK, http.StatusNotFound, http.StatusTooManyRequests:
+		return code
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestShadowMirror(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"pikachu","height":4,"weight":60,"base_experience":112}`)
+	}))
+	defer primary.Close()
+
+	hits := make(chan string, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits <- r.URL.Path
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer shadow.Close()
+
+	reg := prometheus.NewRegistry()
+	m := newMetrics(reg)
+	s := &Server{
+		httpClient: primary.Client(),
+		cache:      newPokemonCache(0),
+		metrics:    m,
+		baseURL:    primary.URL,
+		shadow:     newShadowMirror(shadow.Client(), shadow.URL, 100, time.Second, m),
+	}
+	r := setupRouter(s)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	select {
+	case path := <-hits:
+		if path != "/pokemon/pikachu" {
+			t.Fatalf("unexpected shadow path: %s", path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shadow upstream was not called")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(m.shadowRequestsTotal.WithLabelValues("mismatch")) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected one shadow mismatch to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShadowMirrorMatchesUpstream503(t *testing.T) {
+	unavailable := func() *httptest.Server {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	primary, shadow := unavailable(), unavailable()
+
+	m := newMetrics(prometheus.NewRegistry())
+	s := &Server{
+		httpClient: primary.Client(),
+		metrics:    m,
+		baseURL:    primary.URL,
+		retry:      retryPolicy{MaxAttempts: 1},
+		shadow:     newShadowMirror(shadow.Client(), shadow.URL, 100, time.Second, m),
+	}
+	var out pokemonResponse
+	if status, _ := s.fetchUpstream(context.Background(), "/pokemon/pikachu", &out); status != http.StatusBadGateway {
+		t.Fatalf("expected fetchUpstream to report an upstream 503 as 502, got %d", status)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(m.shadowRequestsTotal.WithLabelValues("match")) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the shadow's 503 to match, got %v mismatches",
+				testutil.ToFloat64(m.shadowRequestsTotal.WithLabelValues("mismatch")))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShadowMirrorDisabled(t *testing.T) {
+	if m := newShadowMirror(http.DefaultClient, "", 100, time.Second, nil); m != nil {
+		t.Fatal("expected nil mirror without base URL")
+	}
+	if m := newShadowMirror(http.DefaultClient, "http://shadow", 0, time.Second, nil); m != nil {
+		t.Fatal("expected nil mirror with zero percent")
+	}
+}
+
+func TestNormalizeUpstreamStatus(t *testing.T) {
+	for code, want := range map[int]int{
+		http.StatusOK:                  http.StatusOK,
+		http.StatusNotFound:            http.StatusNotFound,
+		http.StatusTooManyRequests:     http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:  http.StatusBadGateway,
+		http.StatusInternalServerError: http.StatusBadGateway,
+		http.StatusBadRequest:          http.StatusBadGateway,
+	} {
+		if got := normalizeUpstreamStatus(code); got != want {
+			t.Errorf("normalizeUpstreamStatus(%d) = %d, want %d", code, got, want)
+		}
+	}
+}
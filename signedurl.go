@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+	"ci_education/storage"
+)
+
+// maxSignedURLTTL bounds how long a signed URL stays valid.
+const maxSignedURLTTL = 7 * 24 * time.Hour
+
+// urlSigner issues and verifies time-limited links to API-key routes. A
+// signed URL carries the owner it acts as ("as"), its expiry ("expires") and
+// an HMAC-SHA256 ("sig") over the method, path and every other query
+// parameter, so none of them can be changed.
+type urlSigner struct {
+	secret []byte
+}
+
+// newURLSigner signs with secret. It returns nil (signed URLs disabled) when
+// secret is empty: a made-up secret would make links break on restart and
+// fail on every other replica.
+func newURLSigner(secret string) *urlSigner {
+	if secret == "" {
+		log.Printf("WARNING: signed urls: SIGNED_URL_SECRET is not set, signed URLs are disabled")
+		return nil
+	}
+	return &urlSigner{secret: []byte(secret)}
+}
+
+// signature returns the hex HMAC of a GET of path with query q, which must
+// not contain "sig".
+func (u *urlSigner) signature(path string, q url.Values) string {
+	mac := hmac.New(sha256.New, u.secret)
+	mac.Write([]byte(http.MethodGet + "\n" + path + "\n" + q.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign returns target (a path with an optional query) signed for owner until
+// expires.
+func (u *urlSigner) sign(target, owner string, expires time.Time) (string, error) {
+	t, err := url.Parse(target)
+	if err != nil || !strings.HasPrefix(t.Path, "/") || t.Host != "" || t.Scheme != "" {
+		return "", errors.New("path must be an absolute path on this server")
+	}
+	q := t.Query()
+	for _, reserved := range []string{"as", "expires", "sig"} {
+		if q.Has(reserved) {
+			return "", errors.New("path must not set " + reserved)
+		}
+	}
+	q.Set("as", owner)
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("sig", u.signature(t.Path, q))
+	return t.Path + "?" + q.Encode(), nil
+}
+
+// verify returns the owner a signed request acts as.
+func (u *urlSigner) verify(r *http.Request) (string, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", errors.New("signed URLs are only valid for GET")
+	}
+	q := r.URL.Query()
+	sig := q.Get("sig")
+	q.Del("sig")
+	if !hmac.Equal([]byte(sig), []byte(u.signature(r.URL.Path, q))) {
+		return "", errors.New("invalid signature")
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", errors.New("signed URL expired")
+	}
+	return q.Get("as"), nil
+}
+
+// signedPrincipal authenticates a request without X-API-Key by its URL
+// signature, acting as a non-admin key of the signed owner. ok is false when
+// the request carries no signature.
+func (s *Server) signedPrincipal(c *gin.Context) (p storage.APIKey, ok bool, err error) {
+	if s.signer == nil || !c.Request.URL.Query().Has("sig") {
+		return storage.APIKey{}, false, nil
+	}
+	owner, err := s.signer.verify(c.Request)
+	if err != nil {
+		return storage.APIKey{}, true, err
+	}
+	return storage.APIKey{Owner: owner}, true, nil
+}
+
+// signedURLRequest is the body of POST /admin/signed-url.
+type signedURLRequest struct {
+	Path   string `json:"path"`
+	Owner  string `json:"owner"`
+	TTLSec int    `json:"ttl_sec"`
+}
+
+// adminSignedURLHandler serves POST /admin/signed-url: a link to GET path as
+// owner, valid for ttl_sec seconds (at most a week), usable without an API
+// key. Only admin keys may sign.
+func (s *Server) adminSignedURLHandler(c *gin.Context) {
+	var req signedURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Path == "" || req.Owner == "" {
+		writeError(c, apierror.BadRequest("body must be a JSON object with path, owner and ttl_sec"))
+		return
+	}
+	ttl := time.Duration(req.TTLSec) * time.Second
+	if ttl <= 0 || ttl > maxSignedURLTTL {
+		writeError(c, apierror.BadRequest("ttl_sec must be between 1 and 604800"))
+		return
+	}
+	if s.signer == nil {
+		writeError(c, apierror.NotFound("signed URLs are disabled: SIGNED_URL_SECRET is not set"))
+		return
+	}
+	expires := time.Now().Add(ttl)
+	signed, err := s.signer.sign(req.Path, req.Owner, expires)
+	if err != nil {
+		writeError(c, apierror.BadRequest(err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"url": signed, "expires_at": expires.UTC().Truncate(time.Second)})
+}
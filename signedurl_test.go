@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedURL(t *testing.T) {
+	s, r := newTeamsTestServer(t)
+	s.signer = newURLSigner("secret")
+	doTeams(r, http.MethodPost, "/teams", "ash-key", `{"name":"kanto","members":["pikachu"]}`)
+
+	for key, want := range map[string]int{"": http.StatusUnauthorized, "gary-key": http.StatusForbidden} {
+		if w := doTeams(r, http.MethodPost, "/admin/signed-url", key, `{"path":"/me/export","owner":"ash","ttl_sec":60}`); w.Code != want {
+			t.Fatalf("key %q: expected %d, got %d", key, want, w.Code)
+		}
+	}
+	w := doTeams(r, http.MethodPost, "/admin/signed-url", "admin-key", `{"path":"/teams?x=1","owner":"ash","ttl_sec":60}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	w = doTeams(r, http.MethodGet, body.URL, "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"kanto"`) {
+		t.Fatalf("expected the signed URL to list ash's teams, got %d: %s", w.Code, w.Body)
+	}
+	for _, tampered := range []string{
+		strings.Replace(body.URL, "as=ash", "as=gary", 1),
+		strings.Replace(body.URL, "x=1", "x=2", 1),
+		strings.Replace(body.URL, "/teams", "/me/export", 1),
+	} {
+		if w := doTeams(r, http.MethodGet, tampered, "", ""); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %s, got %d", tampered, w.Code)
+		}
+	}
+	if w := doTeams(r, http.MethodPost, body.URL, "", `{"name":"x","members":["mew"]}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected signed URLs to be read-only, got %d", w.Code)
+	}
+
+	expired, _ := s.signer.sign("/teams", "ash", time.Now().Add(-time.Second))
+	if w := doTeams(r, http.MethodGet, expired, "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an expired URL, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodPost, "/admin/signed-url", "admin-key", `{"path":"https://evil.example/","owner":"ash","ttl_sec":60}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an absolute URL, got %d", w.Code)
+	}
+}
+
+func TestSignedURLNeedsSecret(t *testing.T) {
+	if newURLSigner("") != nil {
+		t.Fatal("expected signed URLs to be disabled without a secret")
+	}
+	s, r := newTeamsTestServer(t)
+	s.signer = nil
+	if w := doTeams(r, http.MethodPost, "/admin/signed-url", "admin-key", `{"path":"/teams","owner":"ash","ttl_sec":60}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with signed URLs disabled, got %d", w.Code)
+	}
+}
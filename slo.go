@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// sloSpec declares an objective for one route: the fraction of requests that
+// must be good. A request is bad if it returns 5xx or, when latency is set,
+// takes at least that long.
+type sloSpec struct {
+	Route     string
+	Objective float64 // e.g. 0.995
+	Latency   time.Duration
+}
+
+// parseSLOs parses a comma-separated list of route=percent[@latency] items,
+// e.g. "/pokemon/:name=99.5@300ms,/health=99.9". Invalid items are logged
+// and skipped.
+func parseSLOs(v string) []sloSpec {
+	var specs []sloSpec
+	for _, item := range splitList(v) {
+		route, target, ok := strings.Cut(item, "=")
+		if !ok || route == "" {
+			log.Printf("slo: ignoring invalid entry %q", item)
+			continue
+		}
+		pct, lat, hasLat := strings.Cut(target, "@")
+		objective, err := strconv.ParseFloat(pct, 64)
+		if err != nil || objective <= 0 || objective >= 100 {
+			log.Printf("slo: ignoring invalid objective in %q", item)
+			continue
+		}
+		spec := sloSpec{Route: route, Objective: objective / 100}
+		if hasLat {
+			d, err := time.ParseDuration(lat)
+			if err != nil || d <= 0 {
+				log.Printf("slo: ignoring invalid latency in %q", item)
+				continue
+			}
+			spec.Latency = d
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// parseDurations parses a comma-separated list of durations, skipping invalid ones.
+func parseDurations(v string) []time.Duration {
+	var out []time.Duration
+	for _, item := range splitList(v) {
+		if d, err := time.ParseDuration(item); err == nil && d > 0 {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// sloBucket counts requests observed during one minute.
+type sloBucket struct {
+	minute int64
+	total  int64
+	bad    int64
+}
+
+type sloState struct {
+	spec    sloSpec
+	buckets []sloBucket // ring indexed by unix minute
+}
+
+// sloTracker keeps per-minute good/bad counts for each declared SLO and
+// derives burn rates over sliding windows. The longest window is used for
+// the remaining error budget.
+type sloTracker struct {
+	windows []time.Duration
+	metrics *metrics
+	now     func() time.Time
+
+	mu      sync.Mutex
+	byRoute map[string]*sloState
+	order   []*sloState
+}
+
+// newSLOTracker returns nil (tracking disabled) when no SLOs are declared.
+func newSLOTracker(specs []sloSpec, windows []time.Duration, m *metrics) *sloTracker {
+	if len(specs) == 0 {
+		return nil
+	}
+	if len(windows) == 0 {
+		windows = []time.Duration{time.Hour}
+	}
+	longest := windows[0]
+	for _, w := range windows {
+		if w > longest {
+			longest = w
+		}
+	}
+	size := int(longest/time.Minute) + 1
+	t := &sloTracker{windows: windows, metrics: m, now: time.Now, byRoute: make(map[string]*sloState)}
+	for _, spec := range specs {
+		st := &sloState{spec: spec, buckets: make([]sloBucket, size)}
+		t.byRoute[spec.Route] = st
+		t.order = append(t.order, st)
+		log.Printf("slo: tracking %s", spec)
+	}
+	return t
+}
+
+func (t *sloTracker) observe(route string, status int, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.byRoute[route]
+	if !ok {
+		return
+	}
+	minute := t.now().Unix() / 60
+	b := &st.buckets[minute%int64(len(st.buckets))]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+	b.total++
+	if status >= http.StatusInternalServerError || (st.spec.Latency > 0 && d >= st.spec.Latency) {
+		b.bad++
+	}
+}
+
+// sloWindowStatus is the state of one SLO over one window.
+type sloWindowStatus struct {
+	Total     int64   `json:"total"`
+	Bad       int64   `json:"bad"`
+	ErrorRate float64 `json:"error_rate"`
+	BurnRate  float64 `json:"burn_rate"`
+}
+
+// sloStatus is the reported state of one SLO.
+type sloStatus struct {
+	Route                string                     `json:"route"`
+	Objective            float64                    `json:"objective"`
+	LatencyThresholdMS   int64                      `json:"latency_threshold_ms,omitempty"`
+	Windows              map[string]sloWindowStatus `json:"windows"`
+	ErrorBudgetRemaining float64                    `json:"error_budget_remaining"`
+}
+
+// snapshot computes every SLO's windows and refreshes the gauges.
+func (t *sloTracker) snapshot() []sloStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	nowMinute := t.now().Unix() / 60
+	out := make([]sloStatus, 0, len(t.order))
+	for _, st := range t.order {
+		budget := 1 - st.spec.Objective
+		status := sloStatus{
+			Route:                st.spec.Route,
+			Objective:            st.spec.Objective,
+			LatencyThresholdMS:   st.spec.Latency.Milliseconds(),
+			Windows:              make(map[string]sloWindowStatus, len(t.windows)),
+			ErrorBudgetRemaining: 1,
+		}
+		var longest time.Duration
+		for _, w := range t.windows {
+			minutes := int64(w / time.Minute)
+			if minutes < 1 {
+				minutes = 1
+			}
+			var ws sloWindowStatus
+			for _, b := range st.buckets {
+				if b.minute > nowMinute-minutes && b.minute <= nowMinute {
+					ws.Total += b.total
+					ws.Bad += b.bad
+				}
+			}
+			if ws.Total > 0 {
+				ws.ErrorRate = float64(ws.Bad) / float64(ws.Total)
+				ws.BurnRate = ws.ErrorRate / budget
+			}
+			name := w.String()
+			status.Windows[name] = ws
+			t.metrics.sloBurnRate.WithLabelValues(st.spec.Route, name).Set(ws.BurnRate)
+			if w >= longest {
+				longest = w
+				status.ErrorBudgetRemaining = 1 - ws.BurnRate
+			}
+		}
+		t.metrics.sloErrorBudgetRemaining.WithLabelValues(st.spec.Route).Set(status.ErrorBudgetRemaining)
+		out = append(out, status)
+	}
+	return out
+}
+
+// start refreshes the SLO gauges periodically so they stay current between
+// status endpoint calls.
+func (t *sloTracker) start(sv *supervisor, interval time.Duration) {
+	if t == nil {
+		return
+	}
+	sv.goWorker("slo", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			t.snapshot()
+		}
+	})
+}
+
+// adminSLOHandler reports the current state of all declared SLOs.
+func (s *Server) adminSLOHandler(c *gin.Context) {
+	if s.slo == nil {
+		writeError(c, apierror.NotFound("no SLOs are configured"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"slos": s.slo.snapshot()})
+}
+
+func (s sloSpec) String() string {
+	if s.Latency > 0 {
+		return fmt.Sprintf("%s=%g@%s", s.Route, s.Objective*100, s.Latency)
+	}
+	return fmt.Sprintf("%s=%g", s.Route, s.Objective*100)
+}
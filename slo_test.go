@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseSLOs(t *testing.T) {
+	specs := parseSLOs("/pokemon/:name=99.5@300ms, /health=99.9, bogus, /x=150")
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 valid specs, got %d: %+v", len(specs), specs)
+	}
+	if specs[0].Route != "/pokemon/:name" || specs[0].Objective != 0.995 || specs[0].Latency != 300*time.Millisecond {
+		t.Fatalf("unexpected first spec: %+v", specs[0])
+	}
+	if specs[1].Route != "/health" || specs[1].Latency != 0 {
+		t.Fatalf("unexpected second spec: %+v", specs[1])
+	}
+}
+
+func TestSLOTrackerBurnRate(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	tr := newSLOTracker([]sloSpec{{Route: "/pokemon/:name", Objective: 0.9, Latency: 100 * time.Millisecond}},
+		[]time.Duration{5 * time.Minute, time.Hour}, m)
+	now := time.Unix(1_700_000_000, 0)
+	tr.now = func() time.Time { return now }
+
+	// 10 requests: one 5xx, one too slow => 20% bad against a 10% budget.
+	for i := 0; i < 8; i++ {
+		tr.observe("/pokemon/:name", http.StatusOK, 10*time.Millisecond)
+	}
+	tr.observe("/pokemon/:name", http.StatusBadGateway, 10*time.Millisecond)
+	tr.observe("/pokemon/:name", http.StatusOK, time.Second)
+	tr.observe("/other", http.StatusBadGateway, 0)
+
+	st := tr.snapshot()[0]
+	w := st.Windows["5m0s"]
+	if w.Total != 10 || w.Bad != 2 {
+		t.Fatalf("unexpected window counts: %+v", w)
+	}
+	if got := testutil.ToFloat64(m.sloBurnRate.WithLabelValues("/pokemon/:name", "1h0m0s")); got < 1.99 || got > 2.01 {
+		t.Fatalf("expected burn rate 2, got %v", got)
+	}
+
+	// Ten minutes later the 5m window is empty but the 1h window still burns.
+	now = now.Add(10 * time.Minute)
+	st = tr.snapshot()[0]
+	if st.Windows["5m0s"].Total != 0 || st.Windows["1h0m0s"].Total != 10 {
+		t.Fatalf("unexpected windows after rollover: %+v", st.Windows)
+	}
+	if st.ErrorBudgetRemaining > -0.99 {
+		t.Fatalf("expected exhausted budget, got %v", st.ErrorBudgetRemaining)
+	}
+}
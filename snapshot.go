@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotRecord is the on-disk form of one pokemon cache entry.
+type snapshotRecord struct {
+	Key        string           `json:"key"`
+	Pokemon    *pokemonResponse `json:"pokemon,omitempty"`
+	Detail     *pokemonDetail   `json:"detail,omitempty"` // absent in snapshots from older versions
+	NotFound   bool             `json:"not_found,omitempty"`
+	StoredAt   time.Time        `json:"stored_at"`
+	FreshUntil time.Time        `json:"fresh_until"`
+	ExpiresAt  time.Time        `json:"expires_at"`
+}
+
+// cacheSnapshotter periodically writes the pokemon cache to path and reloads
+// it at startup so a restart doesn't begin with a cold cache. Entries keep
+// their original expiry; maxAge additionally drops entries cached longer ago
+// than that.
+type cacheSnapshotter struct {
+	cache    *pokemonCache
+	path     string
+	interval time.Duration
+	maxAge   time.Duration
+}
+
+// newCacheSnapshotter returns nil (no persistence) when path is empty.
+func newCacheSnapshotter(c *pokemonCache, path string, interval, maxAge time.Duration) *cacheSnapshotter {
+	if path == "" {
+		return nil
+	}
+	return &cacheSnapshotter{cache: c, path: path, interval: interval, maxAge: maxAge}
+}
+
+// snapshotRecords returns the entries of c in their on-disk form.
+func snapshotRecords(c *pokemonCache) []snapshotRecord {
+	items := c.items()
+	records := make([]snapshotRecord, 0, len(items))
+	for _, it := range items {
+		rec := snapshotRecord{Key: it.key, Detail: it.value.detail, NotFound: it.value.notFound, StoredAt: it.storedAt, FreshUntil: it.value.freshUntil, ExpiresAt: it.expiresAt}
+		if !it.value.notFound {
+			p := it.value.pokemon
+			rec.Pokemon = &p
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// restoreRecord puts rec back into c with its original expiry. Expired,
+// malformed and (with a positive maxAge) too-old records are skipped and
+// reported false.
+func restoreRecord(c *pokemonCache, rec snapshotRecord, maxAge time.Duration, now time.Time) bool {
+	if !now.Before(rec.ExpiresAt) || (maxAge > 0 && now.Sub(rec.StoredAt) > maxAge) {
+		return false
+	}
+	if rec.Pokemon == nil && !rec.NotFound {
+		return false
+	}
+	v := pokemonCacheEntry{detail: rec.Detail, notFound: rec.NotFound, freshUntil: rec.FreshUntil}
+	if rec.Pokemon != nil {
+		v.pokemon = *rec.Pokemon
+	}
+	c.restore(cacheItem[pokemonCacheEntry]{key: rec.Key, value: v, storedAt: rec.StoredAt, expiresAt: rec.ExpiresAt})
+	return true
+}
+
+// save writes the snapshot atomically via a temporary file and rename.
+func (sn *cacheSnapshotter) save() (int, error) {
+	records := snapshotRecords(sn.cache)
+	data, err := json.Marshal(records)
+	if err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(sn.path), filepath.Base(sn.path)+".tmp*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return len(records), os.Rename(tmp.Name(), sn.path)
+}
+
+// readSnapshotFile returns the records of the snapshot at path; a missing
+// file has none.
+func readSnapshotFile(path string) ([]snapshotRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []snapshotRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// load restores entries from the snapshot file. A missing file is not an
+// error; expired and too-old entries are skipped.
+func (sn *cacheSnapshotter) load() (int, error) {
+	records, err := readSnapshotFile(sn.path)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	restored := 0
+	for _, rec := range records {
+		if restoreRecord(sn.cache, rec, sn.maxAge, now) {
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+// start saves the cache every interval; a non-positive interval disables
+// periodic saving.
+func (sn *cacheSnapshotter) start(sv *supervisor) {
+	if sn == nil || sn.interval <= 0 {
+		return
+	}
+	sv.goWorker("cache_snapshot", func() {
+		ticker := time.NewTicker(sn.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := sn.save(); err != nil {
+				log.Printf("cache snapshot: save failed: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	src := newLRUCache[pokemonCacheEntry](time.Hour, 10)
+	src.set("pikachu", pokemonCacheEntry{pokemon: pokemonResponse{Name: "pikachu", Height: 4}})
+	src.setTTL("missingno", pokemonCacheEntry{notFound: true}, time.Hour)
+	src.restore(cacheItem[pokemonCacheEntry]{key: "old", value: pokemonCacheEntry{pokemon: pokemonResponse{Name: "old"}},
+		storedAt: time.Now().Add(-2 * time.Hour), expiresAt: time.Now().Add(time.Hour)})
+	if n, err := newCacheSnapshotter(src, path, 0, 0).save(); err != nil || n != 3 {
+		t.Fatalf("expected 3 saved entries, got %d %v", n, err)
+	}
+
+	dst := newPokemonCache(time.Hour)
+	n, err := newCacheSnapshotter(dst, path, 0, time.Hour).load()
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 restored entries, got %d %v", n, err)
+	}
+	if v, ok := dst.get("pikachu"); !ok || v.pokemon.Height != 4 {
+		t.Fatalf("expected pikachu restored, got %+v %v", v, ok)
+	}
+	if v, ok := dst.get("missingno"); !ok || !v.notFound {
+		t.Fatalf("expected negative entry restored, got %+v %v", v, ok)
+	}
+	if _, ok := dst.get("old"); ok {
+		t.Fatal("expected entry older than max age to be skipped")
+	}
+
+	if n, err := newCacheSnapshotter(dst, filepath.Join(t.TempDir(), "missing.json"), 0, 0).load(); err != nil || n != 0 {
+		t.Fatalf("expected missing snapshot to be ignored, got %d %v", n, err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// speciesResponse is the response of GET /pokemon/:name/species.
+type speciesResponse struct {
+	Name        string `json:"name"`
+	Genus       string `json:"genus,omitempty"`
+	Color       string `json:"color,omitempty"`
+	Habitat     string `json:"habitat,omitempty"`
+	FlavorText  string `json:"flavor_text,omitempty"`
+	IsLegendary bool   `json:"is_legendary"`
+	IsMythical  bool   `json:"is_mythical"`
+}
+
+// speciesHandler serves GET /pokemon/:name/species?lang=en from the
+// pokemon-species resource. name is a pokemon, resolved to its species
+// through the cached details so forms such as giratina-altered work. Texts
+// are in lang, English by default.
+func (s *Server) speciesHandler(c *gin.Context) {
+	lang := c.DefaultQuery("lang", "en")
+	ctx := c.Request.Context()
+	d, status, err := s.fetchPokemonDetail(ctx, c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+		return
+	}
+	sp, status, err := s.fetchSpecies(ctx, d.speciesName())
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "species not found"))
+		return
+	}
+	c.JSON(http.StatusOK, speciesResponse{
+		Name: sp.Name, Genus: sp.genus(lang), Color: sp.Color.Name, Habitat: sp.Habitat.Name,
+		FlavorText: sp.flavorText(lang), IsLegendary: sp.IsLegendary, IsMythical: sp.IsMythical,
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSpeciesEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu":          `{"name":"pikachu","species":{"name":"pikachu"}}`,
+		"/pokemon/giratina-altered": `{"name":"giratina-altered","species":{"name":"giratina"}}`,
+		"/pokemon-species/giratina": `{"name":"giratina","is_legendary":true}`,
+		"/pokemon-species/pikachu": `{"name":"pikachu","color":{"name":"yellow"},"habitat":{"name":"forest"},
+			"flavor_text_entries":[{"flavor_text":"Pika\u000cpika.","language":{"name":"ja"}},{"flavor_text":"It keeps\nits tail raised.","language":{"name":"en"}}],
+			"genera":[{"genus":"Mouse Pokémon","language":{"name":"en"}}]}`,
+	})
+	s := newTestServer(ts.URL)
+	s.species = newTTLCache[speciesDetail](time.Minute)
+	s.details = newTTLCache[pokemonDetail](time.Minute)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno/species", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for an unknown species, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/giratina-altered/species", nil))
+	var form speciesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &form); err != nil || w.Code != http.StatusOK || form.Name != "giratina" || !form.IsLegendary {
+		t.Fatalf("expected a form to resolve to its species, got %d %s", w.Code, w.Body)
+	}
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu/species", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+		}
+		var sp speciesResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &sp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		want := speciesResponse{Name: "pikachu", Genus: "Mouse Pokémon", Color: "yellow", Habitat: "forest", FlavorText: "It keeps its tail raised."}
+		if sp != want {
+			t.Fatalf("expected %+v, got %+v", want, sp)
+		}
+		if i == 0 {
+			ts.Close() // the second request must be served from the cache
+		}
+	}
+}
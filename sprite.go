@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+const (
+	// maxSpriteBytes bounds one sprite download.
+	maxSpriteBytes = 2 << 20
+	// spriteMaxAge is the Cache-Control max-age of sprite responses; artwork
+	// rarely changes.
+	spriteMaxAge = 24 * time.Hour
+)
+
+// spriteNamePattern restricts sprite cache file names; pokemon names are
+// lower-case letters, digits and dashes.
+var spriteNamePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// spriteImage is a downloaded sprite, with its gzip variant when the format
+// compresses.
+type spriteImage = encodedBody
+
+// spriteCache keeps downloaded sprites in memory and, when dir is set, on
+// disk so they survive restarts.
+type spriteCache struct {
+	mem *ttlCache[spriteImage]
+	dir string
+}
+
+func newSpriteCache(mem *ttlCache[spriteImage], dir string) *spriteCache {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("sprite cache: %v; caching in memory only", err)
+			dir = ""
+		}
+	}
+	return &spriteCache{mem: mem, dir: dir}
+}
+
+func (sc *spriteCache) path(name string) string {
+	return filepath.Join(sc.dir, name+".png")
+}
+
+// get returns a cached sprite, promoting disk hits into memory.
+func (sc *spriteCache) get(name string) (spriteImage, bool) {
+	if sc == nil {
+		return spriteImage{}, false
+	}
+	if img, ok := sc.mem.get(name); ok {
+		return img, true
+	}
+	if sc.dir == "" {
+		return spriteImage{}, false
+	}
+	body, err := os.ReadFile(sc.path(name))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("sprite cache: %v", err)
+		}
+		return spriteImage{}, false
+	}
+	img := newEncodedBody("image/png", body)
+	sc.mem.set(name, img)
+	return img, true
+}
+
+// set caches img in memory and, for PNGs, on disk (written atomically).
+func (sc *spriteCache) set(name string, img spriteImage) {
+	if sc == nil {
+		return
+	}
+	sc.mem.set(name, img)
+	if sc.dir == "" || img.ContentType != "image/png" {
+		return
+	}
+	tmp, err := os.CreateTemp(sc.dir, name+".tmp*")
+	if err == nil {
+		_, err = tmp.Write(img.Body)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), sc.path(name))
+		}
+		os.Remove(tmp.Name())
+	}
+	if err != nil {
+		log.Printf("sprite cache: writing %s: %v", name, err)
+	}
+}
+
+// delete drops a sprite from memory and disk and reports whether it was
+// cached in either.
+func (sc *spriteCache) delete(name string) bool {
+	if sc == nil {
+		return false
+	}
+	ok := sc.mem.delete(name)
+	if sc.dir != "" && spriteNamePattern.MatchString(name) {
+		if err := os.Remove(sc.path(name)); err == nil {
+			ok = true
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("sprite cache: %v", err)
+		}
+	}
+	return ok
+}
+
+// flush empties the cache, in memory and on disk, and returns how many
+// sprites were dropped; one held in both counts once.
+func (sc *spriteCache) flush() int {
+	if sc == nil {
+		return 0
+	}
+	if sc.dir == "" {
+		return sc.mem.flush()
+	}
+	dropped := map[string]bool{}
+	sc.mem.mu.RLock()
+	for k := range sc.mem.data {
+		dropped[k] = true
+	}
+	sc.mem.mu.RUnlock()
+	sc.mem.flush()
+	files, err := filepath.Glob(filepath.Join(sc.dir, "*.png"))
+	if err != nil {
+		log.Printf("sprite cache: %v", err)
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			log.Printf("sprite cache: %v", err)
+			continue
+		}
+		dropped[strings.TrimSuffix(filepath.Base(f), ".png")] = true
+	}
+	return len(dropped)
+}
+
+// spriteURL returns the pokemon's official artwork, or its default sprite
+// when it has none.
+func (d pokemonDetail) spriteURL() string {
+	if u := d.Sprites.Other.OfficialArtwork.FrontDefault; u != "" {
+		return u
+	}
+	return d.Sprites.FrontDefault
+}
+
+// fetchSprite downloads an image from the sprite CDN.
+func (s *Server) fetchSprite(ctx context.Context, url string) (spriteImage, error) {
+	const target = "sprite_cdn"
+	start := time.Now()
+	defer func() { s.metrics.extCallDurationSec.WithLabelValues(target).Observe(time.Since(start).Seconds()) }()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return spriteImage{}, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.metrics.extCallsTotal.WithLabelValues(target, "error").Inc()
+		return spriteImage{}, err
+	}
+	defer resp.Body.Close()
+	s.metrics.extCallsTotal.WithLabelValues(target, strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.StatusCode != http.StatusOK {
+		return spriteImage{}, fmt.Errorf("sprite CDN returned status %d", resp.StatusCode)
+	}
+	ct := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "image/") {
+		return spriteImage{}, fmt.Errorf("sprite CDN returned %q", ct)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSpriteBytes+1))
+	if err != nil {
+		return spriteImage{}, err
+	}
+	if len(body) > maxSpriteBytes {
+		return spriteImage{}, errors.New("sprite is too large")
+	}
+	return newEncodedBody(ct, body), nil
+}
+
+// spriteHandler serves GET /pokemon/:name/sprite: the pokemon's official
+// artwork, downloaded once from the sprite CDN and then served from the
+// sprite cache.
+func (s *Server) spriteHandler(c *gin.Context) {
+	name := strings.ToLower(c.Param("name"))
+	if !spriteNamePattern.MatchString(name) {
+		writeError(c, apierror.BadRequest("invalid pokemon name"))
+		return
+	}
+	img, ok := s.sprites.get(name)
+	if ok {
+		traceFrom(c.Request.Context()).cacheResult(cacheHit)
+	} else {
+		ctx := c.Request.Context()
+		traceFrom(ctx).cacheResult(cacheMiss)
+		p, status, err := s.fetchPokemonDetail(ctx, name)
+		if err != nil {
+			writeError(c, apierror.FromUpstream(status, err, "pokemon not found"))
+			return
+		}
+		url := p.spriteURL()
+		if url == "" {
+			writeError(c, apierror.NotFound("pokemon has no sprite"))
+			return
+		}
+		if img, err = s.fetchSprite(ctx, url); err != nil {
+			writeError(c, apierror.UpstreamError("failed to fetch sprite: "+err.Error()))
+			return
+		}
+		s.sprites.set(name, img)
+	}
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(spriteMaxAge.Seconds())))
+	img.write(c, http.StatusOK)
+}
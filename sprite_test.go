@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fakePNG = []byte("\x89PNG\r\n\x1a\nfake")
+
+func TestSpriteEndpoint(t *testing.T) {
+	var cdnCalls atomic.Int32
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pokemon/pikachu":
+			w.Write([]byte(`{"name":"pikachu","sprites":{"front_default":"` + api.URL + `/small/25.png",
+				"other":{"official-artwork":{"front_default":"` + api.URL + `/artwork/25.png"}}}}`))
+		case "/pokemon/missingno":
+			w.Write([]byte(`{"name":"missingno","sprites":{"front_default":null}}`))
+		case "/artwork/25.png":
+			cdnCalls.Add(1)
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(fakePNG)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	s := newTestServer(api.URL)
+	s.sprites = newSpriteCache(newTTLCache[spriteImage](time.Minute), dir)
+	r := setupRouter(s)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/Pikachu/sprite", nil))
+		if w.Code != http.StatusOK || w.Body.String() != string(fakePNG) {
+			t.Fatalf("expected the artwork, got %d %q", w.Code, w.Body)
+		}
+		if ct, cc := w.Header().Get("Content-Type"), w.Header().Get("Cache-Control"); ct != "image/png" || cc != "public, max-age=86400" {
+			t.Fatalf("unexpected headers Content-Type=%q Cache-Control=%q", ct, cc)
+		}
+	}
+	if n := cdnCalls.Load(); n != 1 {
+		t.Fatalf("expected one CDN download, got %d", n)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "pikachu.png")); err != nil || string(b) != string(fakePNG) {
+		t.Fatalf("expected the sprite on disk, got %q %v", b, err)
+	}
+
+	// a restarted instance serves the sprite from disk
+	restarted := newSpriteCache(newTTLCache[spriteImage](time.Minute), dir)
+	if img, ok := restarted.get("pikachu"); !ok || img.ContentType != "image/png" {
+		t.Fatal("expected a disk hit after restart")
+	}
+	if n := restarted.flush(); n != 1 {
+		t.Fatalf("expected the flush to count the sprite in memory and on disk once, got %d", n)
+	}
+	if _, ok := restarted.get("pikachu"); ok {
+		t.Fatal("expected no sprite after a flush")
+	}
+
+	for path, want := range map[string]int{
+		"/pokemon/missingno/sprite": http.StatusNotFound,
+		"/pokemon/mewtwo/sprite":    http.StatusNotFound,
+		"/pokemon/pika_chu/sprite":  http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// Standby states. A standby instance starts warming, becomes ready once the
+// projected hit ratio reaches the target (or cold when a warm-up ends below
+// it) and serves traffic only after being promoted to active.
+const (
+	standbyWarming = "warming"
+	standbyReady   = "ready"
+	standbyCold    = "cold"
+	standbyActive  = "active"
+)
+
+// standbyController tracks a blue/green standby instance: where its cache
+// was warmed from and the working set it is expected to serve. The
+// projected hit ratio is the share of that working set currently cached.
+type standbyController struct {
+	target   float64  // projected hit ratio required to become ready
+	workers  int      // concurrent upstream fetches while warming
+	snapshot string   // CACHE_SNAPSHOT_PATH, the snapshot source
+	warmup   []string // names always part of the working set
+
+	mu         sync.Mutex
+	state      string
+	running    bool // a warm-up is in progress
+	source     string
+	expected   []string
+	loaded     int
+	fetched    int
+	err        string
+	startedAt  time.Time
+	readyAt    time.Time
+	promotedAt time.Time
+}
+
+func newStandbyController(target float64, workers int, snapshot string, warmup []string) *standbyController {
+	return &standbyController{
+		target: target, workers: workers, snapshot: snapshot, warmup: warmup,
+		state: standbyWarming, startedAt: time.Now(),
+	}
+}
+
+// standbyStatus is the JSON view of the standby controller.
+type standbyStatus struct {
+	State             string     `json:"state"`
+	Source            string     `json:"source,omitempty"`
+	Expected          int        `json:"expected"`
+	Loaded            int        `json:"loaded"`
+	Fetched           int        `json:"fetched"`
+	ProjectedHitRatio float64    `json:"projected_hit_ratio"`
+	TargetHitRatio    float64    `json:"target_hit_ratio"`
+	Error             string     `json:"error,omitempty"`
+	StartedAt         time.Time  `json:"started_at"`
+	ReadyAt           *time.Time `json:"ready_at,omitempty"`
+	PromotedAt        *time.Time `json:"promoted_at,omitempty"`
+}
+
+// projectedHitRatio returns the share of expected names with an unexpired
+// entry in the pokemon cache; an empty working set projects 1.
+func (s *Server) projectedHitRatio(expected []string) float64 {
+	if len(expected) == 0 {
+		return 1
+	}
+	cached := map[string]bool{}
+	for _, it := range s.cache.items() {
+		cached[it.key] = true
+	}
+	hits := 0
+	for _, name := range expected {
+		if cached[name] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(expected))
+}
+
+func (s *Server) standbyStatus() standbyStatus {
+	sb := s.standby
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	st := standbyStatus{
+		State: sb.state, Source: sb.source, Expected: len(sb.expected), Loaded: sb.loaded, Fetched: sb.fetched,
+		ProjectedHitRatio: s.projectedHitRatio(sb.expected), TargetHitRatio: sb.target,
+		Error: sb.err, StartedAt: sb.startedAt,
+	}
+	if !sb.readyAt.IsZero() {
+		t := sb.readyAt
+		st.ReadyAt = &t
+	}
+	if !sb.promotedAt.IsZero() {
+		t := sb.promotedAt
+		st.PromotedAt = &t
+	}
+	return st
+}
+
+// standbyRecords loads the records of a warm-up source: "snapshot" reads
+// CACHE_SNAPSHOT_PATH, "peer" the live instance's /admin/cache/export.
+func (s *Server) standbyRecords(ctx context.Context, source, peerURL string) ([]snapshotRecord, error) {
+	switch source {
+	case "snapshot":
+		if s.standby.snapshot == "" {
+			return nil, errors.New("CACHE_SNAPSHOT_PATH is not set")
+		}
+		return readSnapshotFile(s.standby.snapshot)
+	case "peer":
+		if peerURL == "" {
+			return nil, errors.New("peer_url is required")
+		}
+		return fetchPeerRecords(ctx, s.peerClient, peerURL)
+	}
+	return nil, fmt.Errorf("unknown source %q", source)
+}
+
+// runStandbyWarm restores the cache from source, fetches whatever of the
+// working set (the source's keys plus the warm-up list) is still missing,
+// and marks the standby ready or cold by the projected hit ratio. A failing
+// source leaves the warm-up list as the working set.
+func (s *Server) runStandbyWarm(ctx context.Context, source, peerURL string) {
+	sb := s.standby
+	records, err := s.standbyRecords(ctx, source, peerURL)
+	if err != nil {
+		log.Printf("standby: warming from %s: %v", source, err)
+	}
+
+	now := time.Now()
+	loaded := 0
+	seen := map[string]bool{}
+	var expected []string
+	for _, rec := range records {
+		if restoreRecord(s.cache, rec, 0, now) {
+			loaded++
+		}
+		if !seen[rec.Key] {
+			seen[rec.Key] = true
+			expected = append(expected, rec.Key)
+		}
+	}
+	for _, name := range sb.warmup {
+		if name = strings.ToLower(name); !seen[name] {
+			seen[name] = true
+			expected = append(expected, name)
+		}
+	}
+
+	cached := map[string]bool{}
+	for _, it := range s.cache.items() {
+		cached[it.key] = true
+	}
+	var missing []string
+	for _, name := range expected {
+		if !cached[name] {
+			missing = append(missing, name)
+		}
+	}
+	fetched := s.warmCache(ctx, missing, sb.workers)
+
+	ratio := s.projectedHitRatio(expected)
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.running = false
+	sb.source, sb.expected, sb.loaded, sb.fetched, sb.err = source, expected, loaded, fetched, ""
+	if err != nil {
+		sb.err = err.Error()
+	}
+	if sb.state == standbyActive {
+		return // promoted by force while warming
+	}
+	if ratio >= sb.target {
+		sb.state, sb.readyAt = standbyReady, time.Now()
+	} else {
+		sb.state = standbyCold
+	}
+	log.Printf("standby: warmed from %s: %d loaded, %d fetched, projected hit ratio %.2f (target %.2f), %s",
+		source, loaded, fetched, ratio, sb.target, sb.state)
+}
+
+// startWarm moves the standby (back) to warming before runStandbyWarm, unless
+// a warm-up is already running or it is active.
+func (sb *standbyController) startWarm() error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.running {
+		return errors.New("a warm-up is already running")
+	}
+	if sb.state == standbyActive {
+		return errors.New("instance is already active")
+	}
+	sb.state, sb.running, sb.startedAt, sb.readyAt = standbyWarming, true, time.Now(), time.Time{}
+	return nil
+}
+
+// middleware: while in standby, only health, readiness, metrics and admin
+// routes are served; everything else answers 503 until promotion.
+func standbyMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.standby == nil || s.standbyState() == standbyActive || standbyExempt(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		writeError(c, apierror.Overloaded("instance is in standby"))
+		c.Abort()
+	}
+}
+
+func standbyExempt(path string) bool {
+	switch path {
+	case "/health", "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	return strings.HasPrefix(path, "/admin/")
+}
+
+func (s *Server) standbyState() string {
+	s.standby.mu.Lock()
+	defer s.standby.mu.Unlock()
+	return s.standby.state
+}
+
+// readyzHandler serves GET /readyz for load balancers: 200 once the instance
+// takes traffic (always, outside standby mode), 503 while on standby.
+func (s *Server) readyzHandler(c *gin.Context) {
+	if s.standby == nil {
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+		return
+	}
+	st := s.standbyStatus()
+	code := http.StatusServiceUnavailable
+	if st.State == standbyActive {
+		code = http.StatusOK
+	}
+	c.JSON(code, gin.H{"status": st.State, "projected_hit_ratio": st.ProjectedHitRatio})
+}
+
+// adminStandbyHandler serves GET /admin/standby.
+func (s *Server) adminStandbyHandler(c *gin.Context) {
+	if s.standby == nil {
+		writeError(c, apierror.NotFound("standby mode is not enabled"))
+		return
+	}
+	c.JSON(http.StatusOK, s.standbyStatus())
+}
+
+// standbyWarmRequest is the body of POST /admin/standby/warm.
+type standbyWarmRequest struct {
+	Source  string `json:"source"`
+	PeerURL string `json:"peer_url"`
+}
+
+// adminStandbyWarmHandler serves POST /admin/standby/warm: warm again from
+// {"source": "snapshot"} or {"source": "peer", "peer_url": ...}, in the
+// background. Poll GET /admin/standby for the outcome.
+func (s *Server) adminStandbyWarmHandler(c *gin.Context) {
+	if s.standby == nil {
+		writeError(c, apierror.NotFound("standby mode is not enabled"))
+		return
+	}
+	var req standbyWarmRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Source != "snapshot" && req.Source != "peer") {
+		writeError(c, apierror.BadRequest(`body must be a JSON object with source "snapshot" or "peer"`))
+		return
+	}
+	if req.Source == "peer" && req.PeerURL == "" {
+		writeError(c, apierror.BadRequest("peer_url is required for the peer source"))
+		return
+	}
+	if err := s.standby.startWarm(); err != nil {
+		writeError(c, apierror.BadRequest(err.Error()))
+		return
+	}
+	go s.runStandbyWarm(context.Background(), req.Source, req.PeerURL)
+	c.JSON(http.StatusAccepted, s.standbyStatus())
+}
+
+// adminStandbyPromoteHandler serves POST /admin/standby/promote: the instance
+// starts taking traffic. Promoting before the standby is ready requires
+// ?force=true.
+func (s *Server) adminStandbyPromoteHandler(c *gin.Context) {
+	if s.standby == nil {
+		writeError(c, apierror.NotFound("standby mode is not enabled"))
+		return
+	}
+	sb := s.standby
+	sb.mu.Lock()
+	if sb.state != standbyReady && sb.state != standbyActive && c.Query("force") != "true" {
+		state := sb.state
+		sb.mu.Unlock()
+		writeError(c, apierror.BadRequest("standby is "+state+", not ready; use force=true to promote anyway"))
+		return
+	}
+	if sb.state != standbyActive {
+		sb.state, sb.promotedAt = standbyActive, time.Now()
+		log.Printf("standby: promoted to active")
+	}
+	sb.mu.Unlock()
+	c.JSON(http.StatusOK, s.standbyStatus())
+}
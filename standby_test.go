@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStandbyWarmFromPeerAndPromote(t *testing.T) {
+	live := withAdminKey(t, newTestServer(""))
+	live.cache.set("pikachu", pokemonCacheEntry{pokemon: pokemonResponse{Name: "pikachu", Height: 4}})
+	live.cache.set("missingno", pokemonCacheEntry{notFound: true})
+	peer := httptest.NewServer(setupRouter(live))
+	defer peer.Close()
+
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/raichu": `{"name":"raichu","height":8,"weight":300,"base_experience":218}`,
+	})
+	s := withAdminKey(t, newTestServer(ts.URL))
+	s.peerClient = newPeerClient(testAdminKey)
+	s.standby = newStandbyController(0.9, 2, "", []string{"Raichu"})
+	r := setupRouter(s)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	post := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodPost, path, nil)))
+		return w
+	}
+
+	if w := get("/pokemon/pikachu"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 on standby, got %d", w.Code)
+	}
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected /readyz 503 on standby, got %d", w.Code)
+	}
+	if w := post("/admin/standby/promote"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected promotion before warm-up to be refused, got %d", w.Code)
+	}
+
+	if err := s.standby.startWarm(); err != nil {
+		t.Fatal(err)
+	}
+	s.runStandbyWarm(context.Background(), "peer", peer.URL)
+	st := s.standbyStatus()
+	if st.State != standbyReady || st.Loaded != 2 || st.Fetched != 1 || st.Expected != 3 || st.ProjectedHitRatio != 1 {
+		t.Fatalf("unexpected status after warm-up %+v", st)
+	}
+	if v, ok := s.cache.get("pikachu"); !ok || v.pokemon.Height != 4 {
+		t.Fatal("expected the peer's entry to be restored")
+	}
+
+	if w := post("/admin/standby/promote"); w.Code != http.StatusOK {
+		t.Fatalf("expected promotion to succeed, got %d: %s", w.Code, w.Body)
+	}
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Fatalf("expected /readyz 200 once active, got %d", w.Code)
+	}
+	if w := get("/pokemon/pikachu"); w.Code != http.StatusOK {
+		t.Fatalf("expected traffic to be served once active, got %d", w.Code)
+	}
+}
+
+func TestStandbyColdBelowTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	exp := time.Now().Add(time.Hour)
+	data, _ := json.Marshal([]snapshotRecord{
+		{Key: "pikachu", Pokemon: &pokemonResponse{Name: "pikachu"}, StoredAt: time.Now(), ExpiresAt: exp},
+		{Key: "raichu", Pokemon: &pokemonResponse{Name: "raichu"}, StoredAt: time.Now(), ExpiresAt: time.Now().Add(-time.Minute)},
+	})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer("http://127.0.0.1:1") // raichu cannot be refetched
+	s.retry = retryPolicy{MaxAttempts: 1}
+	s.standby = newStandbyController(0.9, 1, path, nil)
+	if err := s.standby.startWarm(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.standby.startWarm(); err == nil {
+		t.Fatal("expected a second warm-up to be refused while one runs")
+	}
+	s.runStandbyWarm(context.Background(), "snapshot", "")
+	st := s.standbyStatus()
+	if st.State != standbyCold || st.Loaded != 1 || st.ProjectedHitRatio != 0.5 {
+		t.Fatalf("expected a cold standby at 0.5, got %+v", st)
+	}
+
+	r := setupRouter(withAdminKey(t, s))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodPost, "/admin/standby/promote?force=true", nil)))
+	if w.Code != http.StatusOK || s.standbyState() != standbyActive {
+		t.Fatalf("expected a forced promotion, got %d", w.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+const (
+	maxAggregateNames = 100
+	aggregateWorkers  = 8
+)
+
+// statSummary summarizes one base stat across a set of pokemon.
+type statSummary struct {
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+}
+
+// summarize computes min/max/mean/median; values must be non-empty.
+func summarize(values []int) statSummary {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	sum := 0
+	for _, v := range sorted {
+		sum += v
+	}
+	n := len(sorted)
+	median := float64(sorted[n/2])
+	if n%2 == 0 {
+		median = float64(sorted[n/2-1]+sorted[n/2]) / 2
+	}
+	return statSummary{
+		Min:    sorted[0],
+		Max:    sorted[n-1],
+		Mean:   math.Round(float64(sum)/float64(n)*100) / 100,
+		Median: median,
+	}
+}
+
+// normalizeNames lowercases, trims and de-duplicates names, keeping order.
+func normalizeNames(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, n := range in {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	return out
+}
+
+// statsAggregateHandler serves POST /stats/aggregate with body
+// {"names": [...]}. Names that fail to resolve are reported in errors and
+// excluded from the aggregate.
+func (s *Server) statsAggregateHandler(c *gin.Context) {
+	var req struct {
+		Names []string `json:"names"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, apierror.BadRequest("body must be a JSON object with a names array"))
+		return
+	}
+	names := normalizeNames(req.Names)
+	if len(names) == 0 {
+		writeError(c, apierror.BadRequest("names must not be empty"))
+		return
+	}
+	if len(names) > maxAggregateNames {
+		writeError(c, apierror.BadRequest("too many names"))
+		return
+	}
+
+	values := make(map[string][]int, len(statNames))
+	errs := map[string]partError{}
+	var included []string
+	var firstErr *apierror.Error
+	for i, res := range s.fetchPokemonDetails(c.Request.Context(), names, aggregateWorkers) {
+		if res.err != nil {
+			errs[names[i]] = newPartError(res.status, res.err)
+			if firstErr == nil {
+				firstErr = apierror.FromUpstream(res.status, res.err, "pokemon not found: "+names[i])
+			}
+			continue
+		}
+		included = append(included, names[i])
+		for _, st := range statNames {
+			values[st] = append(values[st], res.detail.baseStat(st))
+		}
+	}
+	if len(included) == 0 {
+		writeError(c, firstErr)
+		return
+	}
+
+	stats := make(map[string]statSummary, len(statNames))
+	for _, st := range statNames {
+		stats[st] = summarize(values[st])
+	}
+	resp := gin.H{"count": len(included), "names": included, "stats": stats}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	got := summarize([]int{50, 10, 40, 20})
+	want := statSummary{Min: 10, Max: 50, Mean: 30, Median: 30}
+	if got != want {
+		t.Fatalf("summarize = %+v, want %+v", got, want)
+	}
+	if got := summarize([]int{3, 1, 2}); got.Median != 2 {
+		t.Fatalf("odd median = %v, want 2", got.Median)
+	}
+}
+
+func TestStatsAggregate(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu": `{"name":"pikachu","stats":[{"base_stat":35,"stat":{"name":"hp"}},{"base_stat":90,"stat":{"name":"speed"}}]}`,
+		"/pokemon/raichu":  `{"name":"raichu","stats":[{"base_stat":60,"stat":{"name":"hp"}},{"base_stat":110,"stat":{"name":"speed"}}]}`,
+	})
+	r := setupRouter(newTestServer(ts.URL))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/stats/aggregate", strings.NewReader(`{"names":["Pikachu","raichu","pikachu","missingno"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Count  int                    `json:"count"`
+		Stats  map[string]statSummary `json:"stats"`
+		Errors map[string]partError   `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if body.Count != 2 {
+		t.Fatalf("expected 2 aggregated pokemon, got %d", body.Count)
+	}
+	if hp := body.Stats["hp"]; hp.Min != 35 || hp.Max != 60 || hp.Mean != 47.5 {
+		t.Fatalf("unexpected hp summary: %+v", hp)
+	}
+	if body.Errors["missingno"].Code != "not_found" {
+		t.Fatalf("expected missingno error, got %+v", body.Errors)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/stats/aggregate", strings.NewReader(`{"names":[]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty names, got %d", w.Code)
+	}
+}
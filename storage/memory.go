@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a Store kept in process memory; its data is lost on exit.
+type Memory struct {
+	mu        sync.Mutex
+	nextTeam  int64
+	teams     map[int64]Team
+	favorites map[string]map[string]time.Time // owner -> pokemon -> deleted at (zero = live)
+	keys      map[string]APIKey
+	usage     map[string]map[string]int64 // key -> day -> requests
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		teams:     map[int64]Team{},
+		favorites: map[string]map[string]time.Time{},
+		keys:      map[string]APIKey{},
+		usage:     map[string]map[string]int64{},
+	}
+}
+
+func (m *Memory) CreateTeam(_ context.Context, t Team) (Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextTeam++
+	t.ID = m.nextTeam
+	t.CreatedAt = time.Now().UTC()
+	t.DeletedAt = nil
+	t = copyTeam(t)
+	m.teams[t.ID] = t
+	return copyTeam(t), nil
+}
+
+// copyTeam returns t with its own members slice and deletion time.
+func copyTeam(t Team) Team {
+	t.Members = slices.Clone(t.Members)
+	if t.DeletedAt != nil {
+		at := *t.DeletedAt
+		t.DeletedAt = &at
+	}
+	return t
+}
+
+func (m *Memory) GetTeam(_ context.Context, id int64) (Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.teams[id]
+	if !ok || t.DeletedAt != nil {
+		return Team{}, ErrNotFound
+	}
+	return copyTeam(t), nil
+}
+
+func (m *Memory) ListTeams(_ context.Context, owner string, includeDeleted bool) ([]Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []Team{}
+	for _, t := range m.teams {
+		if t.Owner == owner && (includeDeleted || t.DeletedAt == nil) {
+			out = append(out, copyTeam(t))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (m *Memory) DeleteTeam(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.teams[id]
+	if !ok || t.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now().UTC()
+	t.DeletedAt = &now
+	m.teams[id] = t
+	return nil
+}
+
+func (m *Memory) RestoreTeam(_ context.Context, id int64) (Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.teams[id]
+	if !ok || t.DeletedAt == nil {
+		return Team{}, ErrNotFound
+	}
+	t.DeletedAt = nil
+	m.teams[id] = t
+	return copyTeam(t), nil
+}
+
+func (m *Memory) AddFavorite(_ context.Context, owner, pokemon string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.favorites[owner] == nil {
+		m.favorites[owner] = map[string]time.Time{}
+	}
+	m.favorites[owner][pokemon] = time.Time{}
+	return nil
+}
+
+func (m *Memory) RemoveFavorite(_ context.Context, owner, pokemon string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deletedAt, ok := m.favorites[owner][pokemon]
+	if !ok || !deletedAt.IsZero() {
+		return ErrNotFound
+	}
+	m.favorites[owner][pokemon] = time.Now().UTC()
+	return nil
+}
+
+func (m *Memory) RestoreFavorite(_ context.Context, owner, pokemon string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deletedAt, ok := m.favorites[owner][pokemon]
+	if !ok || deletedAt.IsZero() {
+		return ErrNotFound
+	}
+	m.favorites[owner][pokemon] = time.Time{}
+	return nil
+}
+
+func (m *Memory) ListFavorites(_ context.Context, owner string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []string{}
+	for name, deletedAt := range m.favorites[owner] {
+		if deletedAt.IsZero() {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (m *Memory) PurgeDeleted(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, t := range m.teams {
+		if t.DeletedAt != nil && t.DeletedAt.Before(before) {
+			delete(m.teams, id)
+			n++
+		}
+	}
+	for _, favs := range m.favorites {
+		for name, deletedAt := range favs {
+			if !deletedAt.IsZero() && deletedAt.Before(before) {
+				delete(favs, name)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) EraseOwner(_ context.Context, owner string) (Erasure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var e Erasure
+	for id, t := range m.teams {
+		if t.Owner == owner {
+			delete(m.teams, id)
+			e.Teams++
+		}
+	}
+	e.Favorites = int64(len(m.favorites[owner]))
+	delete(m.favorites, owner)
+	for key, k := range m.keys {
+		if k.Owner == owner {
+			e.UsageDays += int64(len(m.usage[key]))
+			delete(m.usage, key)
+		}
+	}
+	return e, nil
+}
+
+func (m *Memory) CreateAPIKey(_ context.Context, k APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now().UTC()
+	}
+	m.keys[k.Key] = k
+	return nil
+}
+
+func (m *Memory) GetAPIKey(_ context.Context, key string) (APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[key]
+	if !ok {
+		return APIKey{}, ErrNotFound
+	}
+	return k, nil
+}
+
+func (m *Memory) RevokeAPIKey(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[key]
+	if !ok {
+		return ErrNotFound
+	}
+	k.Revoked = true
+	m.keys[key] = k
+	return nil
+}
+
+func (m *Memory) RecordUsage(_ context.Context, key string, at time.Time, n int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.usage[key] == nil {
+		m.usage[key] = map[string]int64{}
+	}
+	m.usage[key][day(at)] += n
+	return nil
+}
+
+func (m *Memory) Usage(_ context.Context, key string, from, to time.Time) ([]Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lo, hi := day(from), day(to)
+	out := []Usage{}
+	for d, n := range m.usage[key] {
+		if d >= lo && d <= hi {
+			out = append(out, Usage{Key: key, Day: d, Requests: n})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Day < out[j].Day })
+	return out, nil
+}
+
+func (m *Memory) PurgeUsage(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cutoff := day(before)
+	var n int64
+	for _, days := range m.usage {
+		for d := range days {
+			if d < cutoff {
+				delete(days, d)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) SchemaVersion(context.Context) (int, error) { return 0, nil }
+
+func (m *Memory) Close() error { return nil }
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations holds one directory per driver of NNNN_description.up.sql files
+// and their NNNN_description.down.sql reversals. Versions are applied in
+// order, each in its own transaction, and never edited once released: schema
+// changes go in a new version.
+//
+//go:embed migrations
+var migrations embed.FS
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// loadMigrations returns driver's migrations sorted by version.
+func loadMigrations(driver string) ([]migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*migration{}
+	for _, e := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), ".")
+		prefix, name, ok2 := strings.Cut(base, "_")
+		v, err := strconv.Atoi(prefix)
+		if !ok || !ok2 || err != nil || !strings.HasSuffix(e.Name(), ".sql") || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("storage: malformed migration file name %q", e.Name())
+		}
+		b, err := fs.ReadFile(migrations, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[v]
+		if m == nil {
+			m = &migration{version: v, name: name}
+			byVersion[v] = m
+		}
+		if direction == "up" {
+			m.up = string(b)
+		} else {
+			m.down = string(b)
+		}
+	}
+	out := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("storage: migration %d has no up file", m.version)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].version < out[j].version })
+	return out, nil
+}
+
+// MigrationStatus reports one known migration.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrator manages the schema of a SQL store independently of Open, for the
+// migrate subcommand.
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []migration
+}
+
+// NewMigrator connects to a SQL store without touching its schema. The
+// memory driver has no schema and is rejected.
+func NewMigrator(ctx context.Context, driver, dsn string) (*Migrator, error) {
+	if driver == DriverMemory {
+		return nil, errors.New("storage: the memory driver has no schema to migrate")
+	}
+	db, err := connect(ctx, driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(ctx, db, driver)
+}
+
+func newMigrator(ctx context.Context, db *sql.DB, driver string) (*Migrator, error) {
+	ms, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return nil, fmt.Errorf("storage: creating schema_migrations: %w", err)
+	}
+	return &Migrator{db: db, driver: driver, migrations: ms}, nil
+}
+
+// Latest returns the newest known schema version.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].version
+}
+
+// LatestVersion returns the newest schema version known for driver; the
+// memory driver has no schema and reports 0.
+func LatestVersion(driver string) (int, error) {
+	if driver == DriverMemory {
+		return 0, nil
+	}
+	ms, err := loadMigrations(driver)
+	if err != nil {
+		return 0, err
+	}
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	return ms[len(ms)-1].version, nil
+}
+
+// Version returns the newest applied schema version, 0 for an empty database.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	return schemaVersion(ctx, m.db)
+}
+
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var v int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("storage: reading schema version: %w", err)
+	}
+	return v, nil
+}
+
+// Up applies every pending migration and returns the versions applied.
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var applied []int
+	for _, mg := range m.migrations {
+		if mg.version <= current {
+			continue
+		}
+		if err := m.apply(ctx, mg.up, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			mg.version, time.Now().UTC()); err != nil {
+			return applied, fmt.Errorf("storage: migration %d_%s up: %w", mg.version, mg.name, err)
+		}
+		applied = append(applied, mg.version)
+	}
+	return applied, nil
+}
+
+// Down reverts the newest steps applied migrations and returns the versions
+// reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var reverted []int
+	for ; steps > 0; steps-- {
+		current, err := m.Version(ctx)
+		if err != nil || current == 0 {
+			return reverted, err
+		}
+		i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].version >= current })
+		if i == len(m.migrations) || m.migrations[i].version != current {
+			return reverted, fmt.Errorf("storage: applied version %d is unknown to this build", current)
+		}
+		mg := m.migrations[i]
+		if mg.down == "" {
+			return reverted, fmt.Errorf("storage: migration %d_%s cannot be reverted", mg.version, mg.name)
+		}
+		if err := m.apply(ctx, mg.down, `DELETE FROM schema_migrations WHERE version = ?`, mg.version); err != nil {
+			return reverted, fmt.Errorf("storage: migration %d_%s down: %w", mg.version, mg.name, err)
+		}
+		reverted = append(reverted, mg.version)
+	}
+	return reverted, nil
+}
+
+// Status lists every known migration and whether it is applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[v] = at.UTC()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, len(m.migrations))
+	for i, mg := range m.migrations {
+		out[i] = MigrationStatus{Version: mg.version, Name: mg.name}
+		if at, ok := appliedAt[mg.version]; ok {
+			out[i].Applied, out[i].AppliedAt = true, &at
+		}
+	}
+	return out, nil
+}
+
+// apply runs script and the bookkeeping statement in one transaction.
+func (m *Migrator) apply(ctx context.Context, script, bookkeeping string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, rebind(m.driver, bookkeeping), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the database connection.
+func (m *Migrator) Close() error { return m.db.Close() }
@@ -0,0 +1,4 @@
+DROP TABLE usage;
+DROP TABLE api_keys;
+DROP TABLE favorites;
+DROP TABLE teams;
@@ -0,0 +1,28 @@
+CREATE TABLE teams (
+    id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    members    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX teams_owner ON teams (owner);
+
+CREATE TABLE favorites (
+    owner   TEXT NOT NULL,
+    pokemon TEXT NOT NULL,
+    PRIMARY KEY (owner, pokemon)
+);
+
+CREATE TABLE api_keys (
+    key        TEXT PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked    BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE usage (
+    key      TEXT NOT NULL,
+    day      TEXT NOT NULL,
+    requests BIGINT NOT NULL,
+    PRIMARY KEY (key, day)
+);
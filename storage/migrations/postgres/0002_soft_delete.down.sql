@@ -0,0 +1,5 @@
+DELETE FROM teams WHERE deleted_at IS NOT NULL;
+DELETE FROM favorites WHERE deleted_at IS NOT NULL;
+ALTER TABLE api_keys DROP COLUMN admin;
+ALTER TABLE favorites DROP COLUMN deleted_at;
+ALTER TABLE teams DROP COLUMN deleted_at;
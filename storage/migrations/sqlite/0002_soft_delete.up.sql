@@ -0,0 +1,3 @@
+ALTER TABLE teams ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE favorites ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE api_keys ADD COLUMN admin BOOLEAN NOT NULL DEFAULT FALSE;
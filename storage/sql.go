@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+	_ "modernc.org/sqlite"             // registers the "sqlite" database/sql driver
+)
+
+// sqlDrivers maps Open's driver names to database/sql driver names.
+var sqlDrivers = map[string]string{DriverSQLite: "sqlite", DriverPostgres: "pgx"}
+
+// SQL is a Store backed by SQLite or Postgres. Queries are written with "?"
+// placeholders and rebound for the driver.
+type SQL struct {
+	db     *sql.DB
+	driver string
+}
+
+// connect opens and pings a database for driver.
+func connect(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(sqlDrivers[driver], dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverSQLite {
+		// SQLite serializes writers; one connection avoids SQLITE_BUSY and
+		// keeps ":memory:" databases from splitting per connection.
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("storage: connecting to %s: %w", driver, err)
+	}
+	return db, nil
+}
+
+// openSQL connects and either applies pending migrations (autoMigrate) or
+// refuses a schema that is not at the latest version.
+func openSQL(ctx context.Context, driver, dsn string, autoMigrate bool) (*SQL, error) {
+	db, err := connect(ctx, driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	mg, err := newMigrator(ctx, db, driver)
+	if err == nil {
+		if autoMigrate {
+			_, err = mg.Up(ctx)
+		} else {
+			err = checkSchema(ctx, mg)
+		}
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQL{db: db, driver: driver}, nil
+}
+
+func checkSchema(ctx context.Context, mg *Migrator) error {
+	v, err := mg.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if v != mg.Latest() {
+		return fmt.Errorf("storage: schema is at version %d, this build needs %d; run \"migrate up\"", v, mg.Latest())
+	}
+	return nil
+}
+
+// rebind rewrites "?" placeholders to "$1", "$2", ... for Postgres.
+func rebind(driver, query string) string {
+	if driver != DriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *SQL) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, rebind(s.driver, query), args...)
+}
+
+func (s *SQL) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, rebind(s.driver, query), args...)
+}
+
+func (s *SQL) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.db.QueryRowContext(ctx, rebind(s.driver, query), args...)
+}
+
+// affected maps a write that touched no rows to ErrNotFound.
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQL) CreateTeam(ctx context.Context, t Team) (Team, error) {
+	members, err := json.Marshal(t.Members)
+	if err != nil {
+		return Team{}, err
+	}
+	t.CreatedAt = time.Now().UTC()
+	err = s.queryRow(ctx, `INSERT INTO teams (owner, name, members, created_at) VALUES (?, ?, ?, ?) RETURNING id`,
+		t.Owner, t.Name, string(members), t.CreatedAt).Scan(&t.ID)
+	return t, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+const teamColumns = `id, owner, name, members, created_at, deleted_at`
+
+func scanTeam(row scanner) (Team, error) {
+	var t Team
+	var members string
+	var deletedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.Owner, &t.Name, &members, &t.CreatedAt, &deletedAt); err != nil {
+		return Team{}, err
+	}
+	t.CreatedAt = t.CreatedAt.UTC()
+	if deletedAt.Valid {
+		at := deletedAt.Time.UTC()
+		t.DeletedAt = &at
+	}
+	return t, json.Unmarshal([]byte(members), &t.Members)
+}
+
+func (s *SQL) GetTeam(ctx context.Context, id int64) (Team, error) {
+	t, err := scanTeam(s.queryRow(ctx, `SELECT `+teamColumns+` FROM teams WHERE id = ? AND deleted_at IS NULL`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Team{}, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *SQL) ListTeams(ctx context.Context, owner string, includeDeleted bool) ([]Team, error) {
+	q := `SELECT ` + teamColumns + ` FROM teams WHERE owner = ?`
+	if !includeDeleted {
+		q += ` AND deleted_at IS NULL`
+	}
+	rows, err := s.query(ctx, q+` ORDER BY id`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Team{}
+	for rows.Next() {
+		t, err := scanTeam(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQL) DeleteTeam(ctx context.Context, id int64) error {
+	return affected(s.exec(ctx, `UPDATE teams SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, time.Now().UTC(), id))
+}
+
+func (s *SQL) RestoreTeam(ctx context.Context, id int64) (Team, error) {
+	if err := affected(s.exec(ctx, `UPDATE teams SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)); err != nil {
+		return Team{}, err
+	}
+	return s.GetTeam(ctx, id)
+}
+
+func (s *SQL) AddFavorite(ctx context.Context, owner, pokemon string) error {
+	_, err := s.exec(ctx, `INSERT INTO favorites (owner, pokemon) VALUES (?, ?)
+		ON CONFLICT (owner, pokemon) DO UPDATE SET deleted_at = NULL`, owner, pokemon)
+	return err
+}
+
+func (s *SQL) RemoveFavorite(ctx context.Context, owner, pokemon string) error {
+	return affected(s.exec(ctx, `UPDATE favorites SET deleted_at = ? WHERE owner = ? AND pokemon = ? AND deleted_at IS NULL`,
+		time.Now().UTC(), owner, pokemon))
+}
+
+func (s *SQL) RestoreFavorite(ctx context.Context, owner, pokemon string) error {
+	return affected(s.exec(ctx, `UPDATE favorites SET deleted_at = NULL WHERE owner = ? AND pokemon = ? AND deleted_at IS NOT NULL`,
+		owner, pokemon))
+}
+
+func (s *SQL) ListFavorites(ctx context.Context, owner string) ([]string, error) {
+	rows, err := s.query(ctx, `SELECT pokemon FROM favorites WHERE owner = ? AND deleted_at IS NULL ORDER BY pokemon`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQL) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, table := range []string{"teams", "favorites"} {
+		res, err := s.exec(ctx, `DELETE FROM `+table+` WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before.UTC())
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (s *SQL) EraseOwner(ctx context.Context, owner string) (Erasure, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Erasure{}, err
+	}
+	defer tx.Rollback()
+	var e Erasure
+	for _, d := range []struct {
+		n     *int64
+		query string
+	}{
+		{&e.Teams, `DELETE FROM teams WHERE owner = ?`},
+		{&e.Favorites, `DELETE FROM favorites WHERE owner = ?`},
+		{&e.UsageDays, `DELETE FROM usage WHERE key IN (SELECT key FROM api_keys WHERE owner = ?)`},
+	} {
+		res, err := tx.ExecContext(ctx, rebind(s.driver, d.query), owner)
+		if err != nil {
+			return Erasure{}, err
+		}
+		if *d.n, err = res.RowsAffected(); err != nil {
+			return Erasure{}, err
+		}
+	}
+	return e, tx.Commit()
+}
+
+func (s *SQL) CreateAPIKey(ctx context.Context, k APIKey) error {
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now().UTC()
+	}
+	_, err := s.exec(ctx, `INSERT INTO api_keys (key, owner, admin, created_at, revoked) VALUES (?, ?, ?, ?, ?)`,
+		k.Key, k.Owner, k.Admin, k.CreatedAt, k.Revoked)
+	return err
+}
+
+func (s *SQL) GetAPIKey(ctx context.Context, key string) (APIKey, error) {
+	var k APIKey
+	err := s.queryRow(ctx, `SELECT key, owner, admin, created_at, revoked FROM api_keys WHERE key = ?`, key).
+		Scan(&k.Key, &k.Owner, &k.Admin, &k.CreatedAt, &k.Revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrNotFound
+	}
+	k.CreatedAt = k.CreatedAt.UTC()
+	return k, err
+}
+
+func (s *SQL) RevokeAPIKey(ctx context.Context, key string) error {
+	return affected(s.exec(ctx, `UPDATE api_keys SET revoked = TRUE WHERE key = ?`, key))
+}
+
+func (s *SQL) RecordUsage(ctx context.Context, key string, at time.Time, n int64) error {
+	_, err := s.exec(ctx, `INSERT INTO usage (key, day, requests) VALUES (?, ?, ?)
+		ON CONFLICT (key, day) DO UPDATE SET requests = usage.requests + excluded.requests`, key, day(at), n)
+	return err
+}
+
+func (s *SQL) Usage(ctx context.Context, key string, from, to time.Time) ([]Usage, error) {
+	rows, err := s.query(ctx, `SELECT key, day, requests FROM usage WHERE key = ? AND day >= ? AND day <= ? ORDER BY day`,
+		key, day(from), day(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Usage{}
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Key, &u.Day, &u.Requests); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQL) PurgeUsage(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.exec(ctx, `DELETE FROM usage WHERE day < ?`, day(before))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *SQL) SchemaVersion(ctx context.Context) (int, error) {
+	return schemaVersion(ctx, s.db)
+}
+
+func (s *SQL) Close() error { return s.db.Close() }
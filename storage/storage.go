@@ -0,0 +1,130 @@
+// Package storage persists the service's own data (teams, favorites, API
+// keys and their usage) behind the Store interface. Open picks an
+// implementation by driver name:
+//
+//	store, err := storage.Open(ctx, "postgres", "postgres://user:pass@db/app", true)
+//
+// SQL-backed stores keep their schema in embedded migrations, applied by Open
+// or, when operators manage schema changes themselves, by a Migrator.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("storage: not found")
+
+// Team is a named set of pokemon owned by a user.
+type Team struct {
+	ID        int64      `json:"id"`
+	Owner     string     `json:"owner"`
+	Name      string     `json:"name"`
+	Members   []string   `json:"members"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // set while soft-deleted
+}
+
+// APIKey is a credential issued to an owner. Admin keys may act across
+// owners.
+type APIKey struct {
+	Key       string    `json:"key"`
+	Owner     string    `json:"owner"`
+	Admin     bool      `json:"admin"`
+	CreatedAt time.Time `json:"created_at"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// Usage is the request count of one API key on one UTC day.
+type Usage struct {
+	Key      string `json:"key"`
+	Day      string `json:"day"` // YYYY-MM-DD
+	Requests int64  `json:"requests"`
+}
+
+// Erasure counts the records removed by EraseOwner.
+type Erasure struct {
+	Teams     int64 `json:"teams"`
+	Favorites int64 `json:"favorites"`
+	UsageDays int64 `json:"usage_days"`
+}
+
+// Store is the persistence interface. Implementations are safe for
+// concurrent use.
+//
+// Teams and favorites are soft-deleted: deletion hides them from reads until
+// they are restored or purged by PurgeDeleted.
+type Store interface {
+	// CreateTeam stores t, assigning its ID and CreatedAt.
+	CreateTeam(ctx context.Context, t Team) (Team, error)
+	// GetTeam returns a team that is not deleted.
+	GetTeam(ctx context.Context, id int64) (Team, error)
+	// ListTeams returns owner's teams, oldest first, including soft-deleted
+	// ones when includeDeleted is set.
+	ListTeams(ctx context.Context, owner string, includeDeleted bool) ([]Team, error)
+	DeleteTeam(ctx context.Context, id int64) error
+	// RestoreTeam undeletes a soft-deleted team.
+	RestoreTeam(ctx context.Context, id int64) (Team, error)
+
+	// AddFavorite is idempotent and revives a deleted favorite.
+	AddFavorite(ctx context.Context, owner, pokemon string) error
+	RemoveFavorite(ctx context.Context, owner, pokemon string) error
+	// RestoreFavorite undeletes a soft-deleted favorite.
+	RestoreFavorite(ctx context.Context, owner, pokemon string) error
+	// ListFavorites returns owner's favorites sorted by name.
+	ListFavorites(ctx context.Context, owner string) ([]string, error)
+
+	// PurgeDeleted permanently removes teams and favorites soft-deleted
+	// before the given time and returns how many were removed.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	// EraseOwner permanently removes owner's teams and favorites, deleted
+	// or not, and the usage of owner's API keys. The keys are kept.
+	EraseOwner(ctx context.Context, owner string) (Erasure, error)
+
+	// CreateAPIKey stores k, setting CreatedAt when it is zero.
+	CreateAPIKey(ctx context.Context, k APIKey) error
+	GetAPIKey(ctx context.Context, key string) (APIKey, error)
+	RevokeAPIKey(ctx context.Context, key string) error
+
+	// RecordUsage adds n requests to key's count for the UTC day of at.
+	RecordUsage(ctx context.Context, key string, at time.Time, n int64) error
+	// Usage returns key's daily counts between the UTC days of from and to,
+	// inclusive, oldest first.
+	Usage(ctx context.Context, key string, from, to time.Time) ([]Usage, error)
+	// PurgeUsage removes the daily counts of UTC days before the day of
+	// before and returns how many were removed.
+	PurgeUsage(ctx context.Context, before time.Time) (int64, error)
+
+	// SchemaVersion returns the applied schema version; 0 for stores
+	// without a schema.
+	SchemaVersion(ctx context.Context) (int, error)
+	Close() error
+}
+
+// Drivers accepted by Open.
+const (
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Open returns the store for driver. dsn is ignored by the memory driver; for
+// sqlite it is a file path (or ":memory:"), for postgres a connection URL.
+// With autoMigrate, pending migrations are applied; otherwise a schema that
+// is not at the latest version is an error.
+func Open(ctx context.Context, driver, dsn string, autoMigrate bool) (Store, error) {
+	switch driver {
+	case DriverMemory:
+		return NewMemory(), nil
+	case DriverSQLite, DriverPostgres:
+		return openSQL(ctx, driver, dsn, autoMigrate)
+	}
+	return nil, fmt.Errorf("storage: unknown driver %q", driver)
+}
+
+func day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testStore runs the behaviour every Store implementation must share.
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	team, err := s.CreateTeam(ctx, Team{Owner: "ash", Name: "kanto", Members: []string{"pikachu", "charizard"}})
+	if err != nil || team.ID == 0 || team.CreatedAt.IsZero() {
+		t.Fatalf("CreateTeam: %+v %v", team, err)
+	}
+	if _, err := s.CreateTeam(ctx, Team{Owner: "misty", Name: "water", Members: []string{"starmie"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetTeam(ctx, team.ID)
+	if err != nil || got.Name != "kanto" || !slices.Equal(got.Members, team.Members) {
+		t.Fatalf("GetTeam: %+v %v", got, err)
+	}
+	if teams, err := s.ListTeams(ctx, "ash", false); err != nil || len(teams) != 1 || teams[0].ID != team.ID {
+		t.Fatalf("ListTeams: %+v %v", teams, err)
+	}
+	if err := s.DeleteTeam(ctx, team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetTeam(ctx, team.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := s.DeleteTeam(ctx, team.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+	if teams, err := s.ListTeams(ctx, "ash", false); err != nil || len(teams) != 0 {
+		t.Fatalf("expected deleted teams to be hidden, got %+v %v", teams, err)
+	}
+	if teams, err := s.ListTeams(ctx, "ash", true); err != nil || len(teams) != 1 || teams[0].DeletedAt == nil {
+		t.Fatalf("expected the deleted team with include_deleted, got %+v %v", teams, err)
+	}
+	if restored, err := s.RestoreTeam(ctx, team.ID); err != nil || restored.DeletedAt != nil || restored.Name != "kanto" {
+		t.Fatalf("RestoreTeam: %+v %v", restored, err)
+	}
+	if _, err := s.RestoreTeam(ctx, team.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound restoring a live team, got %v", err)
+	}
+	if err := s.DeleteTeam(ctx, team.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"mew", "eevee", "mew"} {
+		if err := s.AddFavorite(ctx, "ash", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if favs, err := s.ListFavorites(ctx, "ash"); err != nil || !slices.Equal(favs, []string{"eevee", "mew"}) {
+		t.Fatalf("ListFavorites: %v %v", favs, err)
+	}
+	if err := s.RemoveFavorite(ctx, "ash", "eevee"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveFavorite(ctx, "ash", "eevee"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.RestoreFavorite(ctx, "ash", "eevee"); err != nil {
+		t.Fatal(err)
+	}
+	if favs, err := s.ListFavorites(ctx, "ash"); err != nil || !slices.Equal(favs, []string{"eevee", "mew"}) {
+		t.Fatalf("expected the restored favorite, got %v %v", favs, err)
+	}
+	if err := s.RemoveFavorite(ctx, "ash", "eevee"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only records deleted before the cutoff are purged.
+	if n, err := s.PurgeDeleted(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("expected nothing old enough to purge, got %d %v", n, err)
+	}
+	if n, err := s.PurgeDeleted(ctx, time.Now().Add(time.Second)); err != nil || n != 2 {
+		t.Fatalf("expected the deleted team and favorite to be purged, got %d %v", n, err)
+	}
+	if teams, err := s.ListTeams(ctx, "ash", true); err != nil || len(teams) != 0 {
+		t.Fatalf("expected purged teams to be gone, got %+v %v", teams, err)
+	}
+	if err := s.RestoreFavorite(ctx, "ash", "eevee"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected purged favorites to be gone, got %v", err)
+	}
+
+	if err := s.CreateAPIKey(ctx, APIKey{Key: "k1", Owner: "ash"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateAPIKey(ctx, APIKey{Key: "root", Owner: "oak", Admin: true}); err != nil {
+		t.Fatal(err)
+	}
+	if k, err := s.GetAPIKey(ctx, "root"); err != nil || !k.Admin {
+		t.Fatalf("expected an admin key, got %+v %v", k, err)
+	}
+	if err := s.RevokeAPIKey(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if k, err := s.GetAPIKey(ctx, "k1"); err != nil || k.Owner != "ash" || !k.Revoked || k.CreatedAt.IsZero() {
+		t.Fatalf("GetAPIKey: %+v %v", k, err)
+	}
+	if _, err := s.GetAPIKey(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	d1 := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	d2 := d1.Add(2 * time.Hour)
+	for _, at := range []time.Time{d1, d1, d2} {
+		if err := s.RecordUsage(ctx, "k1", at, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []Usage{{Key: "k1", Day: "2024-05-01", Requests: 10}, {Key: "k1", Day: "2024-05-02", Requests: 5}}
+	if u, err := s.Usage(ctx, "k1", d1, d2); err != nil || !slices.Equal(u, want) {
+		t.Fatalf("Usage: %+v %v", u, err)
+	}
+	if u, err := s.Usage(ctx, "k1", d2, d2); err != nil || len(u) != 1 {
+		t.Fatalf("Usage for one day: %+v %v", u, err)
+	}
+	if n, err := s.PurgeUsage(ctx, d1); err != nil || n != 0 {
+		t.Fatalf("expected no usage before the first day, got %d %v", n, err)
+	}
+	if err := s.RecordUsage(ctx, "k1", d1.AddDate(0, -2, 0), 1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.PurgeUsage(ctx, d2); err != nil || n != 2 {
+		t.Fatalf("expected the two oldest days to be purged, got %d %v", n, err)
+	}
+	if err := s.RecordUsage(ctx, "k1", d1, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CreateAPIKey(ctx, APIKey{Key: "k2", Owner: "misty"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordUsage(ctx, "k2", d1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"psyduck", "goldeen"} {
+		if err := s.AddFavorite(ctx, "misty", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.RemoveFavorite(ctx, "misty", "goldeen"); err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.EraseOwner(ctx, "misty")
+	if err != nil || e != (Erasure{Teams: 1, Favorites: 2, UsageDays: 1}) {
+		t.Fatalf("EraseOwner: %+v %v", e, err)
+	}
+	if teams, err := s.ListTeams(ctx, "misty", true); err != nil || len(teams) != 0 {
+		t.Fatalf("expected erased teams to be gone, got %+v %v", teams, err)
+	}
+	if u, err := s.Usage(ctx, "k2", d1, d2); err != nil || len(u) != 0 {
+		t.Fatalf("expected erased usage to be gone, got %+v %v", u, err)
+	}
+	if _, err := s.GetAPIKey(ctx, "k2"); err != nil {
+		t.Fatalf("expected the owner's key to be kept, got %v", err)
+	}
+	if u, err := s.Usage(ctx, "k1", d1, d2); err != nil || len(u) != 2 {
+		t.Fatalf("expected other owners' usage to be kept, got %+v %v", u, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s, err := Open(context.Background(), DriverMemory, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	s, err := Open(context.Background(), DriverSQLite, path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	s.Close()
+
+	// Reopening finds the schema current and the data intact.
+	s, err = Open(context.Background(), DriverSQLite, path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if favs, err := s.ListFavorites(context.Background(), "ash"); err != nil || !slices.Equal(favs, []string{"mew"}) {
+		t.Fatalf("expected persisted favorites, got %v %v", favs, err)
+	}
+}
+
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("STORAGE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("STORAGE_TEST_POSTGRES_DSN not set")
+	}
+	s, err := Open(context.Background(), DriverPostgres, dsn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testStore(t, s)
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	if _, err := Open(context.Background(), "mysql", "", true); err == nil {
+		t.Fatal("expected an error for an unknown driver")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	if got := rebind(DriverPostgres, "a = ? AND b = ?"); got != "a = $1 AND b = $2" {
+		t.Fatalf("unexpected postgres query %q", got)
+	}
+	if got := rebind(DriverSQLite, "a = ?"); got != "a = ?" {
+		t.Fatalf("unexpected sqlite query %q", got)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "app.db")
+	if _, err := Open(ctx, DriverSQLite, path, false); err == nil {
+		t.Fatal("expected an unmigrated schema to be refused without auto-migration")
+	}
+
+	mg, err := NewMigrator(ctx, DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mg.Close()
+	if v, err := LatestVersion(DriverSQLite); err != nil || v != mg.Latest() || v == 0 {
+		t.Fatalf("LatestVersion: %d %v", v, err)
+	}
+	if applied, err := mg.Up(ctx); err != nil || len(applied) != mg.Latest() {
+		t.Fatalf("Up: %v %v", applied, err)
+	}
+	if applied, err := mg.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("expected a second Up to be a no-op, got %v %v", applied, err)
+	}
+	st, err := mg.Status(ctx)
+	if err != nil || len(st) == 0 || !st[0].Applied || st[0].AppliedAt == nil {
+		t.Fatalf("Status: %+v %v", st, err)
+	}
+
+	s, err := Open(ctx, DriverSQLite, path, false)
+	if err != nil {
+		t.Fatalf("expected a current schema to open: %v", err)
+	}
+	if v, err := s.SchemaVersion(ctx); err != nil || v != mg.Latest() {
+		t.Fatalf("SchemaVersion: %d %v", v, err)
+	}
+	s.Close()
+
+	if reverted, err := mg.Down(ctx, 100); err != nil || len(reverted) != mg.Latest() {
+		t.Fatalf("Down: %v %v", reverted, err)
+	}
+	if v, err := mg.Version(ctx); err != nil || v != 0 {
+		t.Fatalf("expected version 0 after reverting everything, got %d %v", v, err)
+	}
+	if _, err := NewMigrator(ctx, DriverMemory, ""); err == nil {
+		t.Fatal("expected the memory driver to be rejected")
+	}
+}
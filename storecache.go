@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"ci_education/storage"
+)
+
+// cachedStore is a read-through cache in front of a SQL store: team and
+// favorite reads are served from memory and every write through it
+// invalidates the entries it affects. Only live records are cached, so
+// purging soft-deleted ones needs no invalidation. Other methods pass
+// straight through.
+// Writes made by other replicas become visible once entries expire.
+type cachedStore struct {
+	storage.Store
+	teams     *ttlCache[storage.Team]   // by id
+	owned     *ttlCache[[]storage.Team] // by owner
+	favorites *ttlCache[[]string]       // by owner
+}
+
+// newCachedStore wraps store; it returns store unchanged when ttl is not
+// positive.
+func newCachedStore(store storage.Store, ttl time.Duration, maxEntries int, m *metrics) storage.Store {
+	if ttl <= 0 {
+		return store
+	}
+	return &cachedStore{
+		Store:     store,
+		teams:     newLRUCache[storage.Team](ttl, maxEntries).instrument("store_teams", m),
+		owned:     newLRUCache[[]storage.Team](ttl, maxEntries).instrument("store_team_lists", m),
+		favorites: newLRUCache[[]string](ttl, maxEntries).instrument("store_favorites", m),
+	}
+}
+
+func teamKey(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// cloneTeam copies t so callers cannot modify a cached team's members.
+func cloneTeam(t storage.Team) storage.Team {
+	t.Members = slices.Clone(t.Members)
+	return t
+}
+
+func (s *cachedStore) CreateTeam(ctx context.Context, t storage.Team) (storage.Team, error) {
+	created, err := s.Store.CreateTeam(ctx, t)
+	if err == nil {
+		s.owned.invalidate(created.Owner)
+	}
+	return created, err
+}
+
+func (s *cachedStore) GetTeam(ctx context.Context, id int64) (storage.Team, error) {
+	if t, ok := s.teams.get(teamKey(id)); ok {
+		return cloneTeam(t), nil
+	}
+	t, err := s.Store.GetTeam(ctx, id)
+	if err != nil {
+		return t, err
+	}
+	s.teams.set(teamKey(id), cloneTeam(t))
+	return t, nil
+}
+
+// ListTeams caches only the live teams; listings that include deleted
+// teams are rare admin reads and go to the database.
+func (s *cachedStore) ListTeams(ctx context.Context, owner string, includeDeleted bool) ([]storage.Team, error) {
+	if includeDeleted {
+		return s.Store.ListTeams(ctx, owner, true)
+	}
+	if ts, ok := s.owned.get(owner); ok {
+		out := make([]storage.Team, len(ts))
+		for i, t := range ts {
+			out[i] = cloneTeam(t)
+		}
+		return out, nil
+	}
+	ts, err := s.Store.ListTeams(ctx, owner, false)
+	if err != nil {
+		return ts, err
+	}
+	cached := make([]storage.Team, len(ts))
+	for i, t := range ts {
+		cached[i] = cloneTeam(t)
+	}
+	s.owned.set(owner, cached)
+	return ts, nil
+}
+
+func (s *cachedStore) DeleteTeam(ctx context.Context, id int64) error {
+	// the owner's list must be invalidated too, so learn the owner first
+	t, err := s.GetTeam(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.Store.DeleteTeam(ctx, id)
+	s.teams.invalidate(teamKey(id))
+	s.owned.invalidate(t.Owner)
+	return err
+}
+
+func (s *cachedStore) RestoreTeam(ctx context.Context, id int64) (storage.Team, error) {
+	t, err := s.Store.RestoreTeam(ctx, id)
+	if err == nil {
+		s.owned.invalidate(t.Owner)
+	}
+	return t, err
+}
+
+// EraseOwner drops the owner's cached lists and every cached team, since the
+// ids of the erased teams are not known.
+func (s *cachedStore) EraseOwner(ctx context.Context, owner string) (storage.Erasure, error) {
+	e, err := s.Store.EraseOwner(ctx, owner)
+	s.owned.invalidate(owner)
+	s.favorites.invalidate(owner)
+	s.teams.flush()
+	return e, err
+}
+
+func (s *cachedStore) AddFavorite(ctx context.Context, owner, pokemon string) error {
+	err := s.Store.AddFavorite(ctx, owner, pokemon)
+	s.favorites.invalidate(owner)
+	return err
+}
+
+func (s *cachedStore) RemoveFavorite(ctx context.Context, owner, pokemon string) error {
+	err := s.Store.RemoveFavorite(ctx, owner, pokemon)
+	s.favorites.invalidate(owner)
+	return err
+}
+
+func (s *cachedStore) RestoreFavorite(ctx context.Context, owner, pokemon string) error {
+	err := s.Store.RestoreFavorite(ctx, owner, pokemon)
+	s.favorites.invalidate(owner)
+	return err
+}
+
+func (s *cachedStore) ListFavorites(ctx context.Context, owner string) ([]string, error) {
+	if names, ok := s.favorites.get(owner); ok {
+		return slices.Clone(names), nil
+	}
+	names, err := s.Store.ListFavorites(ctx, owner)
+	if err != nil {
+		return names, err
+	}
+	s.favorites.set(owner, slices.Clone(names))
+	return names, nil
+}
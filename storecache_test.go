@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"ci_education/storage"
+)
+
+// countingStore counts reads that reach the underlying store.
+type countingStore struct {
+	*storage.Memory
+	reads int
+}
+
+func (s *countingStore) GetTeam(ctx context.Context, id int64) (storage.Team, error) {
+	s.reads++
+	return s.Memory.GetTeam(ctx, id)
+}
+
+func (s *countingStore) ListFavorites(ctx context.Context, owner string) ([]string, error) {
+	s.reads++
+	return s.Memory.ListFavorites(ctx, owner)
+}
+
+func TestCachedStore(t *testing.T) {
+	ctx := context.Background()
+	m := newMetrics(prometheus.NewRegistry())
+	inner := &countingStore{Memory: storage.NewMemory()}
+	store := newCachedStore(inner, time.Minute, 100, m)
+
+	team, err := store.CreateTeam(ctx, storage.Team{Owner: "ash", Name: "kanto", Members: []string{"pikachu"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		got, err := store.GetTeam(ctx, team.ID)
+		if err != nil || got.Name != "kanto" {
+			t.Fatalf("GetTeam: %+v %v", got, err)
+		}
+		got.Members[0] = "mutated"
+	}
+	if inner.reads != 1 {
+		t.Fatalf("expected one database read, got %d", inner.reads)
+	}
+	if got, _ := store.GetTeam(ctx, team.ID); got.Members[0] != "pikachu" {
+		t.Fatal("callers must not be able to modify cached teams")
+	}
+	if got := testutil.ToFloat64(m.cacheLookupsTotal.WithLabelValues("store_teams", "hit")); got != 3 {
+		t.Fatalf("expected 3 hits, got %v", got)
+	}
+
+	store.AddFavorite(ctx, "ash", "mew")
+	if favs, _ := store.ListFavorites(ctx, "ash"); !slices.Equal(favs, []string{"mew"}) {
+		t.Fatalf("unexpected favorites %v", favs)
+	}
+	store.AddFavorite(ctx, "ash", "eevee")
+	if favs, _ := store.ListFavorites(ctx, "ash"); !slices.Equal(favs, []string{"eevee", "mew"}) {
+		t.Fatalf("expected the write to invalidate the cached favorites, got %v", favs)
+	}
+
+	if teams, _ := store.ListTeams(ctx, "ash", false); len(teams) != 1 {
+		t.Fatalf("unexpected teams %+v", teams)
+	}
+	if err := store.DeleteTeam(ctx, team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if teams, _ := store.ListTeams(ctx, "ash", false); len(teams) != 0 {
+		t.Fatalf("expected the delete to invalidate the team list, got %+v", teams)
+	}
+	if _, err := store.GetTeam(ctx, team.ID); err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if got := testutil.ToFloat64(m.cacheEvictionsTotal.WithLabelValues("store_teams", "invalidated")); got != 1 {
+		t.Fatalf("expected one invalidation, got %v", got)
+	}
+
+	if _, err := store.RestoreTeam(ctx, team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetTeam(ctx, team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.EraseOwner(ctx, "ash"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetTeam(ctx, team.ID); err != storage.ErrNotFound {
+		t.Fatalf("expected the erasure to drop the cached team, got %v", err)
+	}
+	if favs, _ := store.ListFavorites(ctx, "ash"); len(favs) != 0 {
+		t.Fatalf("expected the erasure to drop the cached favorites, got %v", favs)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Worker states.
+const (
+	workerRunning    = "running"
+	workerRestarting = "restarting" // crashed, waiting out its backoff
+	workerStopped    = "stopped"    // returned normally
+)
+
+const (
+	// workerMinBackoff is the wait before restarting a crashed worker; it
+	// doubles with each consecutive crash up to workerMaxBackoff.
+	workerMinBackoff = time.Second
+	workerMaxBackoff = time.Minute
+)
+
+// workerStatus is the health of one supervised worker.
+type workerStatus struct {
+	State       string     `json:"state"`
+	Restarts    int        `json:"restarts"`
+	StartedAt   time.Time  `json:"started_at"`
+	LastPanic   string     `json:"last_panic,omitempty"`
+	LastPanicAt *time.Time `json:"last_panic_at,omitempty"`
+}
+
+// supervisor runs background workers (janitors, snapshotter, purger, ...)
+// so that a panicking worker is logged, counted and restarted with backoff
+// instead of silently stopping.
+type supervisor struct {
+	metrics    *metrics
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	workers map[string]*workerStatus
+}
+
+func newSupervisor(m *metrics) *supervisor {
+	return &supervisor{metrics: m, minBackoff: workerMinBackoff, maxBackoff: workerMaxBackoff, workers: map[string]*workerStatus{}}
+}
+
+// goWorker runs fn in its own goroutine under name, restarting it whenever it
+// panics; when fn returns, the worker is stopped. Consecutive crashes back
+// off exponentially; a run that lasted longer than the maximum backoff
+// resets it. A nil supervisor runs fn unsupervised.
+func (sv *supervisor) goWorker(name string, fn func()) {
+	if sv == nil {
+		go fn()
+		return
+	}
+	sv.mu.Lock()
+	st := &workerStatus{}
+	sv.workers[name] = st
+	sv.mu.Unlock()
+
+	go func() {
+		backoff := sv.minBackoff
+		for {
+			start := time.Now()
+			sv.setState(name, st, workerRunning, start)
+			p, stack := runRecovered(fn)
+			if p == nil {
+				sv.setState(name, st, workerStopped, time.Time{})
+				return
+			}
+			if time.Since(start) > sv.maxBackoff {
+				backoff = sv.minBackoff
+			}
+			log.Printf("worker %s: panic: %v; restarting in %s\n%s", name, p, backoff, stack)
+			sv.metrics.workerRestartsTotal.WithLabelValues(name).Inc()
+			now := time.Now()
+			sv.mu.Lock()
+			st.Restarts++
+			st.LastPanic, st.LastPanicAt = fmt.Sprint(p), &now
+			sv.mu.Unlock()
+			sv.setState(name, st, workerRestarting, time.Time{})
+			time.Sleep(backoff)
+			backoff = min(2*backoff, sv.maxBackoff)
+		}
+	}()
+}
+
+// runRecovered calls fn and returns what it panicked with, if anything.
+func runRecovered(fn func()) (p any, stack []byte) {
+	defer func() {
+		if p = recover(); p != nil {
+			stack = debug.Stack()
+		}
+	}()
+	fn()
+	return nil, nil
+}
+
+func (sv *supervisor) setState(name string, st *workerStatus, state string, startedAt time.Time) {
+	sv.mu.Lock()
+	st.State = state
+	if !startedAt.IsZero() {
+		st.StartedAt = startedAt
+	}
+	sv.mu.Unlock()
+	up := 0.0
+	if state == workerRunning {
+		up = 1
+	}
+	sv.metrics.workerUp.WithLabelValues(name).Set(up)
+}
+
+// status returns a copy of every worker's status and the names of those
+// not running because they crashed, sorted.
+func (sv *supervisor) status() (map[string]workerStatus, []string) {
+	if sv == nil {
+		return map[string]workerStatus{}, nil
+	}
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	out := make(map[string]workerStatus, len(sv.workers))
+	var crashed []string
+	for name, st := range sv.workers {
+		out[name] = *st
+		if st.State == workerRestarting {
+			crashed = append(crashed, name)
+		}
+	}
+	sort.Strings(crashed)
+	return out, crashed
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSupervisorRestartsPanickingWorker(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	sv := newSupervisor(m)
+	sv.minBackoff, sv.maxBackoff = 10*time.Millisecond, 50*time.Millisecond
+
+	runs := make(chan int, 4)
+	block := make(chan struct{})
+	defer close(block)
+	n := 0
+	sv.goWorker("flaky", func() {
+		n++
+		runs <- n
+		if n == 1 {
+			panic("boom")
+		}
+		<-block
+	})
+	<-runs
+	<-runs
+
+	waitFor(t, func() bool {
+		st, _ := sv.status()
+		return st["flaky"].State == workerRunning
+	})
+	st, crashed := sv.status()
+	if got := st["flaky"]; got.Restarts != 1 || got.LastPanic != "boom" || got.LastPanicAt == nil {
+		t.Fatalf("unexpected status %+v", got)
+	}
+	if len(crashed) != 0 {
+		t.Fatalf("expected no crashed workers, got %v", crashed)
+	}
+	if v := testutil.ToFloat64(m.workerRestartsTotal.WithLabelValues("flaky")); v != 1 {
+		t.Fatalf("expected 1 restart, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.workerUp.WithLabelValues("flaky")); v != 1 {
+		t.Fatalf("expected worker_up 1, got %v", v)
+	}
+}
+
+func TestSupervisorReportsCrashedWorker(t *testing.T) {
+	sv := newSupervisor(newMetrics(prometheus.NewRegistry()))
+	sv.minBackoff, sv.maxBackoff = time.Hour, time.Hour
+
+	sv.goWorker("broken", func() { panic("boom") })
+	waitFor(t, func() bool {
+		_, crashed := sv.status()
+		return len(crashed) == 1 && crashed[0] == "broken"
+	})
+}
+
+func TestSupervisorStoppedWorker(t *testing.T) {
+	m := newMetrics(prometheus.NewRegistry())
+	sv := newSupervisor(m)
+	sv.goWorker("once", func() {})
+	waitFor(t, func() bool {
+		st, _ := sv.status()
+		return st["once"].State == workerStopped
+	})
+	if v := testutil.ToFloat64(m.workerUp.WithLabelValues("once")); v != 0 {
+		t.Fatalf("expected worker_up 0, got %v", v)
+	}
+
+	var nilSV *supervisor
+	done := make(chan struct{})
+	nilSV.goWorker("plain", func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("nil supervisor did not run the worker")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+	"ci_education/storage"
+)
+
+const (
+	// takeoutSyncLimit is the most teams plus favorites exported inline;
+	// larger accounts are exported by a background job.
+	takeoutSyncLimit = 1000
+	// takeoutUsageDays is how far back the usage summary reaches.
+	takeoutUsageDays = 365
+	// maxTakeoutJobs is how many export jobs are kept for status queries.
+	maxTakeoutJobs = 100
+	// takeoutTimeout bounds one background export.
+	takeoutTimeout = 5 * time.Minute
+)
+
+// takeoutUsageDay is one day of the caller's request counts.
+type takeoutUsageDay struct {
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"`
+}
+
+// takeoutUsage summarizes the calling key's usage over takeoutUsageDays.
+type takeoutUsage struct {
+	From  string            `json:"from"`
+	To    string            `json:"to"`
+	Total int64             `json:"total"`
+	Days  []takeoutUsageDay `json:"days"`
+}
+
+// takeout is everything stored about an owner: GET /me/export's document.
+type takeout struct {
+	Owner       string         `json:"owner"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Teams       []storage.Team `json:"teams"`
+	Favorites   []string       `json:"favorites"`
+	Usage       takeoutUsage   `json:"usage"`
+}
+
+// buildTakeout collects p's data. Soft-deleted teams are included: they are
+// still stored until purged.
+func (s *Server) buildTakeout(ctx context.Context, p storage.APIKey) (takeout, error) {
+	teams, err := s.store.ListTeams(ctx, p.Owner, true)
+	if err != nil {
+		return takeout{}, err
+	}
+	favorites, err := s.store.ListFavorites(ctx, p.Owner)
+	if err != nil {
+		return takeout{}, err
+	}
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -takeoutUsageDays+1)
+	usage, err := s.store.Usage(ctx, p.Key, from, now)
+	if err != nil {
+		return takeout{}, err
+	}
+	t := takeout{
+		Owner:       p.Owner,
+		GeneratedAt: now,
+		Teams:       teams,
+		Favorites:   favorites,
+		Usage:       takeoutUsage{From: from.Format(time.DateOnly), To: now.Format(time.DateOnly), Days: []takeoutUsageDay{}},
+	}
+	if t.Teams == nil {
+		t.Teams = []storage.Team{}
+	}
+	if t.Favorites == nil {
+		t.Favorites = []string{}
+	}
+	for _, u := range usage {
+		t.Usage.Total += u.Requests
+		t.Usage.Days = append(t.Usage.Days, takeoutUsageDay{Day: u.Day, Requests: u.Requests})
+	}
+	return t, nil
+}
+
+// takeoutJob is one background export, shared between the worker and status
+// readers.
+type takeoutJob struct {
+	mu         sync.Mutex
+	id         string
+	owner      string
+	startedAt  time.Time
+	finishedAt time.Time
+	err        string
+	result     *takeout
+}
+
+// takeoutJobStatus is the JSON view of an export job. Export is set once the
+// job is done.
+type takeoutJobStatus struct {
+	ID         string     `json:"id"`
+	State      string     `json:"state"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Export     *takeout   `json:"export,omitempty"`
+}
+
+func newTakeoutJob(owner string) *takeoutJob {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &takeoutJob{id: hex.EncodeToString(b), owner: owner, startedAt: time.Now()}
+}
+
+func (j *takeoutJob) finish(t takeout, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finishedAt = time.Now()
+	if err != nil {
+		j.err = "export failed"
+		return
+	}
+	j.result = &t
+}
+
+func (j *takeoutJob) status() takeoutJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := takeoutJobStatus{ID: j.id, State: "running", StartedAt: j.startedAt, Error: j.err, Export: j.result}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		st.FinishedAt = &finished
+		st.State = "done"
+		if j.err != "" {
+			st.State = "failed"
+		}
+	}
+	return st
+}
+
+// runTakeout builds p's export in the background, recording it in job.
+func (s *Server) runTakeout(job *takeoutJob, p storage.APIKey) {
+	ctx, cancel := context.WithTimeout(context.Background(), takeoutTimeout)
+	defer cancel()
+	t, err := s.buildTakeout(ctx, p)
+	if err != nil {
+		log.Printf("takeout: job %s for %s: %v", job.id, p.Owner, err)
+	}
+	job.finish(t, err)
+}
+
+// meExportHandler serves GET /me/export: the caller's data as one JSON
+// document. Accounts with more than takeoutSyncLimit teams and favorites, or
+// requests with async=true, are exported by a background job instead: the
+// answer is 202 with the job, to poll at GET /me/export/:id.
+func (s *Server) meExportHandler(c *gin.Context) {
+	p := principal(c)
+	ctx := c.Request.Context()
+	async := c.Query("async") == "true"
+	if !async {
+		teams, err := s.store.ListTeams(ctx, p.Owner, true)
+		if err != nil {
+			writeStoreError(c, err, "")
+			return
+		}
+		favorites, err := s.store.ListFavorites(ctx, p.Owner)
+		if err != nil {
+			writeStoreError(c, err, "")
+			return
+		}
+		async = len(teams)+len(favorites) > takeoutSyncLimit
+	}
+	if async {
+		job := newTakeoutJob(p.Owner)
+		s.takeouts.add(job.id, job, maxTakeoutJobs)
+		go s.runTakeout(job, p)
+		c.Header("Location", "/me/export/"+job.id)
+		c.JSON(http.StatusAccepted, job.status())
+		return
+	}
+	t, err := s.buildTakeout(ctx, p)
+	if err != nil {
+		writeStoreError(c, err, "")
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="export.json"`)
+	c.JSON(http.StatusOK, t)
+}
+
+// meExportStatusHandler serves GET /me/export/:id for the caller's own jobs.
+func (s *Server) meExportStatusHandler(c *gin.Context) {
+	job, ok := s.takeouts.get(c.Param("id"))
+	if !ok || job.owner != principal(c).Owner {
+		writeError(c, apierror.NotFound("export job not found"))
+		return
+	}
+	c.JSON(http.StatusOK, job.status())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"ci_education/storage"
+)
+
+func TestMeExport(t *testing.T) {
+	s, r := newTeamsTestServer(t)
+	ctx := context.Background()
+	s.store.CreateTeam(ctx, storage.Team{Owner: "ash", Name: "kanto", Members: []string{"pikachu"}})
+	s.store.CreateTeam(ctx, storage.Team{Owner: "gary", Name: "rival", Members: []string{"eevee"}})
+	s.store.AddFavorite(ctx, "ash", "mew")
+	s.store.RecordUsage(ctx, "ash-key", time.Now(), 3)
+
+	w := doTeams(r, http.MethodGet, "/me/export", "ash-key", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var doc takeout
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if doc.Owner != "ash" || len(doc.Teams) != 1 || doc.Teams[0].Name != "kanto" || len(doc.Favorites) != 1 || doc.Usage.Total != 3 {
+		t.Fatalf("unexpected export %+v", doc)
+	}
+
+	w = doTeams(r, http.MethodGet, "/me/export?async=true", "ash-key", "")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	var st takeoutJobStatus
+	json.Unmarshal(w.Body.Bytes(), &st)
+	path := "/me/export/" + st.ID
+	if w := doTeams(r, http.MethodGet, path, "gary-key", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected other owners to get 404, got %d", w.Code)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for st.State == "running" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		w = doTeams(r, http.MethodGet, path, "ash-key", "")
+		json.Unmarshal(w.Body.Bytes(), &st)
+	}
+	if st.State != "done" || st.Export == nil || st.Export.Usage.Total != 3 {
+		t.Fatalf("unexpected job status %+v", st)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+type teamMember struct {
+	Name          string   `json:"name"`
+	Types         []string `json:"types"`
+	BaseStatTotal int      `json:"base_stat_total"`
+}
+
+// teamCoverage is a defending type the team hits super-effectively, with
+// the members whose own types do.
+type teamCoverage struct {
+	Type    string   `json:"type"`
+	Members []string `json:"members"`
+}
+
+// teamWeakness is an attacking type more than one member is weak to, with
+// the members that resist or are immune to it.
+type teamWeakness struct {
+	Type      string   `json:"type"`
+	Weak      []string `json:"weak"`
+	Resistant []string `json:"resistant"`
+}
+
+// teamAnalysis is the response of POST /team/analyze.
+type teamAnalysis struct {
+	Members          []teamMember           `json:"members"`
+	Stats            map[string]statSummary `json:"stats"`
+	AverageTotal     float64                `json:"average_base_stat_total"`
+	Coverage         []teamCoverage         `json:"coverage"`
+	SharedWeaknesses []teamWeakness         `json:"shared_weaknesses"`
+}
+
+// typeFetch is the outcome of fetching one type chart row.
+type typeFetch struct {
+	row    typeDetail
+	status int
+	err    error
+}
+
+// fetchTypes fetches the named type chart rows concurrently, one goroutine
+// per name; callers pass a handful. Results are returned in input order.
+func (s *Server) fetchTypes(ctx context.Context, names []string) []typeFetch {
+	results := make([]typeFetch, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			row, status, err := s.fetchType(ctx, name)
+			results[i] = typeFetch{row: row, status: status, err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// analyzeTeam computes the team report from its members' details and the
+// chart rows of every type they have.
+func analyzeTeam(members []pokemonDetail, chart map[string]typeDetail) teamAnalysis {
+	out := teamAnalysis{
+		Members:          make([]teamMember, len(members)),
+		Stats:            make(map[string]statSummary, len(statNames)),
+		Coverage:         []teamCoverage{},
+		SharedWeaknesses: []teamWeakness{},
+	}
+	values := make(map[string][]int, len(statNames))
+	total := 0
+	covered := map[string][]string{}
+	attackers := map[string]bool{}
+	for i, d := range members {
+		m := teamMember{Name: d.Name, Types: d.typeNames()}
+		for _, st := range statNames {
+			v := d.baseStat(st)
+			values[st] = append(values[st], v)
+			m.BaseStatTotal += v
+		}
+		total += m.BaseStatTotal
+		out.Members[i] = m
+
+		hits := map[string]bool{}
+		for _, t := range m.Types {
+			rel := chart[t].DamageRelations
+			for _, r := range rel.DoubleDamageTo {
+				hits[r.Name] = true
+			}
+			for _, rs := range [][]namedResource{rel.DoubleDamageFrom, rel.HalfDamageFrom, rel.NoDamageFrom} {
+				for _, r := range rs {
+					attackers[r.Name] = true
+				}
+			}
+		}
+		for t := range hits {
+			covered[t] = append(covered[t], m.Name)
+		}
+	}
+	for _, st := range statNames {
+		out.Stats[st] = summarize(values[st])
+	}
+	out.AverageTotal = math.Round(float64(total)/float64(len(members))*100) / 100
+
+	for t, ms := range covered {
+		out.Coverage = append(out.Coverage, teamCoverage{Type: t, Members: ms})
+	}
+	sort.Slice(out.Coverage, func(i, j int) bool {
+		if len(out.Coverage[i].Members) != len(out.Coverage[j].Members) {
+			return len(out.Coverage[i].Members) > len(out.Coverage[j].Members)
+		}
+		return out.Coverage[i].Type < out.Coverage[j].Type
+	})
+
+	for attacker := range attackers {
+		w := teamWeakness{Type: attacker, Weak: []string{}, Resistant: []string{}}
+		for _, m := range out.Members {
+			mult := 1.0
+			for _, t := range m.Types {
+				mult *= chart[t].damageFrom(attacker)
+			}
+			switch {
+			case mult > 1:
+				w.Weak = append(w.Weak, m.Name)
+			case mult < 1:
+				w.Resistant = append(w.Resistant, m.Name)
+			}
+		}
+		if len(w.Weak) > 1 {
+			out.SharedWeaknesses = append(out.SharedWeaknesses, w)
+		}
+	}
+	sort.Slice(out.SharedWeaknesses, func(i, j int) bool {
+		a, b := out.SharedWeaknesses[i], out.SharedWeaknesses[j]
+		if len(a.Weak) != len(b.Weak) {
+			return len(a.Weak) > len(b.Weak)
+		}
+		return a.Type < b.Type
+	})
+	return out
+}
+
+// teamAnalyzeHandler serves POST /team/analyze with body {"members": [...]}:
+// up to six pokemon, fetched concurrently, summarized as a team. Stats are
+// summarized per base stat; coverage lists the types some member's own
+// types hit super-effectively; shared weaknesses are the attacking types at
+// least two members are weak to. Every member must resolve.
+func (s *Server) teamAnalyzeHandler(c *gin.Context) {
+	var req struct {
+		Members []string `json:"members"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, apierror.BadRequest("body must be a JSON object with a members array"))
+		return
+	}
+	names := normalizeNames(req.Members)
+	if len(names) == 0 || len(names) > maxTeamSize {
+		writeError(c, apierror.BadRequest("a team has 1 to 6 distinct members"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	members := make([]pokemonDetail, len(names))
+	var typeNames []string
+	seen := map[string]bool{}
+	for i, res := range s.fetchPokemonDetails(ctx, names, maxTeamSize) {
+		if clientGone(ctx) {
+			return
+		}
+		if res.err != nil {
+			writeError(c, apierror.FromUpstream(res.status, res.err, "pokemon not found: "+names[i]))
+			return
+		}
+		members[i] = res.detail
+		for _, t := range res.detail.typeNames() {
+			if !seen[t] {
+				seen[t] = true
+				typeNames = append(typeNames, t)
+			}
+		}
+	}
+
+	chart := make(map[string]typeDetail, len(typeNames))
+	for i, res := range s.fetchTypes(ctx, typeNames) {
+		if clientGone(ctx) {
+			return
+		}
+		if res.err != nil {
+			writeError(c, apierror.FromUpstream(res.status, res.err, "type not found: "+typeNames[i]))
+			return
+		}
+		chart[typeNames[i]] = res.row
+	}
+	c.JSON(http.StatusOK, analyzeTeam(members, chart))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTeamAnalyze(t *testing.T) {
+	grassPoison := `"types":[{"slot":1,"type":{"name":"grass"}},{"slot":2,"type":{"name":"poison"}}]`
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/bulbasaur":  `{"name":"bulbasaur",` + grassPoison + `,"stats":[{"base_stat":45,"stat":{"name":"hp"}},{"base_stat":45,"stat":{"name":"speed"}}]}`,
+		"/pokemon/oddish":     `{"name":"oddish",` + grassPoison + `,"stats":[{"base_stat":45,"stat":{"name":"hp"}},{"base_stat":30,"stat":{"name":"speed"}}]}`,
+		"/pokemon/charmander": `{"name":"charmander","types":[{"slot":1,"type":{"name":"fire"}}],"stats":[{"base_stat":39,"stat":{"name":"hp"}},{"base_stat":65,"stat":{"name":"speed"}}]}`,
+		"/type/grass": `{"name":"grass","damage_relations":{"double_damage_from":[{"name":"fire"},{"name":"ice"}],
+			"half_damage_from":[{"name":"water"}],"double_damage_to":[{"name":"water"}]}}`,
+		"/type/poison": `{"name":"poison","damage_relations":{"double_damage_from":[{"name":"psychic"}],
+			"half_damage_from":[{"name":"grass"}],"double_damage_to":[{"name":"grass"}]}}`,
+		"/type/fire": `{"name":"fire","damage_relations":{"double_damage_from":[{"name":"water"}],
+			"half_damage_from":[{"name":"grass"},{"name":"fire"}],"double_damage_to":[{"name":"grass"}]}}`,
+	})
+	r := setupRouter(newTestServer(ts.URL))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/team/analyze", strings.NewReader(`{"members":["Bulbasaur","oddish","charmander","oddish"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	rs, err := loadResponseSchemas(responseSchemasJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := rs.validate(rs.schemaFor("/team/analyze", http.StatusOK), w.Body.Bytes()); len(errs) > 0 {
+		t.Fatalf("response violates the contract: %v", errs)
+	}
+	var got teamAnalysis
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(got.Members) != 3 || got.Members[0].Name != "bulbasaur" || got.Members[0].BaseStatTotal != 90 {
+		t.Fatalf("unexpected members: %+v", got.Members)
+	}
+	if got.AverageTotal != 89.67 {
+		t.Fatalf("expected an average total of 89.67, got %v", got.AverageTotal)
+	}
+	if sp := got.Stats["speed"]; sp.Min != 30 || sp.Max != 65 || sp.Median != 45 {
+		t.Fatalf("unexpected speed summary: %+v", sp)
+	}
+	wantCoverage := []teamCoverage{
+		{Type: "grass", Members: []string{"bulbasaur", "oddish", "charmander"}},
+		{Type: "water", Members: []string{"bulbasaur", "oddish"}},
+	}
+	if !reflect.DeepEqual(got.Coverage, wantCoverage) {
+		t.Fatalf("coverage = %+v, want %+v", got.Coverage, wantCoverage)
+	}
+	wantWeak := []teamWeakness{
+		{Type: "fire", Weak: []string{"bulbasaur", "oddish"}, Resistant: []string{"charmander"}},
+		{Type: "ice", Weak: []string{"bulbasaur", "oddish"}, Resistant: []string{}},
+		{Type: "psychic", Weak: []string{"bulbasaur", "oddish"}, Resistant: []string{}},
+	}
+	if !reflect.DeepEqual(got.SharedWeaknesses, wantWeak) {
+		t.Fatalf("shared weaknesses = %+v, want %+v", got.SharedWeaknesses, wantWeak)
+	}
+}
+
+func TestTeamAnalyzeErrors(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{})
+	r := setupRouter(newTestServer(ts.URL))
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{"members":[]}`, http.StatusBadRequest},
+		{`{"members":["a","b","c","d","e","f","g"]}`, http.StatusBadRequest},
+		{`["pikachu"]`, http.StatusBadRequest},
+		{`{"members":["missingno"]}`, http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/team/analyze", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.body, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+	"ci_education/storage"
+)
+
+const (
+	// maxImportTeams bounds how many teams one import may create.
+	maxImportTeams = 100
+	// maxImportBytes bounds the size of an import body.
+	maxImportBytes = 1 << 20
+)
+
+// importError reports why one team of an import was rejected. Index is the
+// team's position in the input, counted from 0 (CSV header rows excluded).
+type importError struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// parseImportJSON reads {"teams": [{"name": ..., "members": [...]}]} or a
+// bare array of teams.
+func parseImportJSON(body []byte) ([]teamRequest, error) {
+	var wrapped struct {
+		Teams []teamRequest `json:"teams"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil {
+		return wrapped.Teams, nil
+	}
+	var teams []teamRequest
+	if err := json.Unmarshal(body, &teams); err != nil {
+		return nil, errors.New("body must be a JSON object with a teams array, or an array of teams")
+	}
+	return teams, nil
+}
+
+// parseImportCSV reads one team per record: its name followed by its
+// members. A first record whose first field is "name" is a header.
+func parseImportCSV(body []byte) ([]teamRequest, error) {
+	r := csv.NewReader(strings.NewReader(string(body)))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var teams []teamRequest
+	for first := true; ; first = false {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return teams, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		if first && strings.EqualFold(strings.TrimSpace(rec[0]), "name") {
+			continue
+		}
+		teams = append(teams, teamRequest{Name: strings.TrimSpace(rec[0]), Members: rec[1:]})
+	}
+}
+
+// importTeamsHandler serves POST /teams/import. The body is JSON, or CSV when
+// the Content-Type is text/csv. Every team is validated on its own against
+// the pokedex; valid ones are created and the others are reported in errors.
+func (s *Server) importTeamsHandler(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes))
+	if err != nil {
+		writeError(c, apierror.BadRequest("body is too large"))
+		return
+	}
+	var teams []teamRequest
+	if c.ContentType() == "text/csv" {
+		teams, err = parseImportCSV(body)
+	} else {
+		teams, err = parseImportJSON(body)
+	}
+	if err != nil {
+		writeError(c, apierror.BadRequest(err.Error()))
+		return
+	}
+	if len(teams) == 0 {
+		writeError(c, apierror.BadRequest("no teams to import"))
+		return
+	}
+	if len(teams) > maxImportTeams {
+		writeError(c, apierror.BadRequest(fmt.Sprintf("at most %d teams can be imported at once", maxImportTeams)))
+		return
+	}
+
+	ctx := c.Request.Context()
+	pokedex, status, err := s.pokedexNames(ctx)
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "pokedex not found"))
+		return
+	}
+	known := make(map[string]bool, len(pokedex))
+	for _, p := range pokedex {
+		known[p.Name] = true
+	}
+
+	owner := principal(c).Owner
+	imported := []storage.Team{}
+	errs := []importError{}
+	for i, req := range teams {
+		if err := req.validate(); err != nil {
+			errs = append(errs, importError{Index: i, Name: req.Name, Message: err.Error()})
+			continue
+		}
+		var unknown []string
+		for _, m := range req.Members {
+			if !known[m] {
+				unknown = append(unknown, m)
+			}
+		}
+		if len(unknown) > 0 {
+			errs = append(errs, importError{Index: i, Name: req.Name, Message: "unknown pokemon: " + strings.Join(unknown, ", ")})
+			continue
+		}
+		t, err := s.store.CreateTeam(ctx, storage.Team{Owner: owner, Name: req.Name, Members: req.Members})
+		if err != nil {
+			rid, _ := c.Get("request_id")
+			log.Printf("rid=%v storage: importing team %q: %v", rid, req.Name, err)
+			errs = append(errs, importError{Index: i, Name: req.Name, Message: "storage failure"})
+			continue
+		}
+		imported = append(imported, t)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": imported, "errors": errs})
+}
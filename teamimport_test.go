@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportTeams(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon?limit=100000&offset=0": `{"count":3,"results":[{"name":"pikachu"},{"name":"charizard"},{"name":"mew"}]}`,
+	})
+	s, r := newTeamsTestServer(t)
+	s.baseURL = ts.URL
+
+	type report struct {
+		Imported []struct {
+			Name    string   `json:"name"`
+			Members []string `json:"members"`
+		} `json:"imported"`
+		Errors []importError `json:"errors"`
+	}
+	post := func(contentType, body string) (int, report) {
+		req := httptest.NewRequest(http.MethodPost, "/teams/import", strings.NewReader(body))
+		req.Header.Set("X-API-Key", "ash-key")
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var rep report
+		json.Unmarshal(w.Body.Bytes(), &rep)
+		return w.Code, rep
+	}
+
+	code, rep := post("application/json", `{"teams":[{"name":"a","members":["Pikachu","mew"]},{"name":"b","members":["missingno"]},{"name":"","members":["mew"]}]}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(rep.Imported) != 1 || rep.Imported[0].Name != "a" || rep.Imported[0].Members[0] != "pikachu" {
+		t.Fatalf("unexpected imported teams %+v", rep.Imported)
+	}
+	if len(rep.Errors) != 2 || rep.Errors[0].Index != 1 || !strings.Contains(rep.Errors[0].Message, "missingno") || rep.Errors[1].Index != 2 {
+		t.Fatalf("unexpected errors %+v", rep.Errors)
+	}
+
+	code, rep = post("text/csv", "name,member1,member2\nc,charizard,mew\nd,pikachu,agumon\n")
+	if code != http.StatusOK || len(rep.Imported) != 1 || rep.Imported[0].Name != "c" || len(rep.Errors) != 1 || rep.Errors[0].Index != 1 {
+		t.Fatalf("unexpected CSV import %d %+v", code, rep)
+	}
+
+	w := doTeams(r, http.MethodGet, "/teams", "ash-key", "")
+	if !strings.Contains(w.Body.String(), `"name":"a"`) || !strings.Contains(w.Body.String(), `"name":"c"`) {
+		t.Fatalf("expected the imported teams to be listed, got %s", w.Body)
+	}
+
+	if code, _ := post("application/json", `{"teams":[]}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty import, got %d", code)
+	}
+}
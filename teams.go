@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+	"ci_education/storage"
+)
+
+// maxTeamSize is the largest team a trainer can field.
+const maxTeamSize = 6
+
+// middleware: require a valid, unrevoked X-API-Key, or a valid URL signature,
+// and expose the key as the request's principal. Admin keys may send
+// X-Impersonate with another active key to act as it; such requests are
+// audited and flagged in the access log.
+func apiKeyMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if p, signed, err := s.signedPrincipal(c); key == "" && signed {
+			if err != nil {
+				writeError(c, apierror.Unauthorized(err.Error()))
+				c.Abort()
+				return
+			}
+			c.Set("principal", p)
+			c.Next()
+			return
+		}
+		if key == "" || s.store == nil {
+			writeError(c, apierror.Unauthorized("X-API-Key is required"))
+			c.Abort()
+			return
+		}
+		k, err := s.store.GetAPIKey(c.Request.Context(), key)
+		if err != nil || k.Revoked {
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("auth: looking up API key: %v", err)
+			}
+			writeError(c, apierror.Unauthorized("invalid or revoked API key"))
+			c.Abort()
+			return
+		}
+		if as := c.GetHeader("X-Impersonate"); as != "" {
+			if !k.Admin {
+				writeError(c, apierror.Forbidden("only admin keys can impersonate"))
+				c.Abort()
+				return
+			}
+			target, err := s.store.GetAPIKey(c.Request.Context(), as)
+			if err != nil || target.Revoked {
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					log.Printf("auth: looking up impersonated API key: %v", err)
+				}
+				writeError(c, apierror.BadRequest("X-Impersonate names no active API key"))
+				c.Abort()
+				return
+			}
+			rid, _ := c.Get("request_id")
+			log.Printf("audit: impersonation rid=%v admin=%s as=%s method=%s path=%s",
+				rid, k.Owner, target.Owner, c.Request.Method, c.Request.URL.Path)
+			c.Set("impersonator", k)
+			k = target
+		}
+		c.Set("principal", k)
+		c.Next()
+	}
+}
+
+// middleware: after apiKeyMiddleware, refuse principals that are not admin
+// keys. A signed URL never carries admin rights.
+func adminKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !principal(c).Admin {
+			writeError(c, apierror.Forbidden("an admin API key is required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// seedAdminKey stores key as an admin key owned by "admin" unless it exists,
+// so a fresh deployment has a key to issue the others with.
+func seedAdminKey(ctx context.Context, store storage.Store, key string) error {
+	if _, err := store.GetAPIKey(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return store.CreateAPIKey(ctx, storage.APIKey{Key: key, Owner: "admin", Admin: true})
+}
+
+// impersonator returns the owner of the admin key impersonating the
+// request's principal, or "".
+func impersonator(c *gin.Context) string {
+	if k, ok := c.Get("impersonator"); ok {
+		return k.(storage.APIKey).Owner
+	}
+	return ""
+}
+
+// principal returns the API key that authenticated the request.
+func principal(c *gin.Context) storage.APIKey {
+	k, _ := c.Get("principal")
+	p, _ := k.(storage.APIKey)
+	return p
+}
+
+// writeStoreError maps a storage error to the error envelope.
+func writeStoreError(c *gin.Context, err error, notFoundMsg string) {
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(c, apierror.NotFound(notFoundMsg))
+		return
+	}
+	rid, _ := c.Get("request_id")
+	log.Printf("rid=%v storage: %v", rid, err)
+	writeError(c, apierror.Internal("storage failure"))
+}
+
+// teamRequest is the body of POST /teams.
+type teamRequest struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// validate normalizes the member names and checks the team's shape.
+func (r *teamRequest) validate() error {
+	r.Members = normalizeNames(r.Members)
+	switch {
+	case r.Name == "":
+		return errors.New("name is required")
+	case len(r.Members) == 0 || len(r.Members) > maxTeamSize:
+		return fmt.Errorf("a team has 1 to %d distinct members", maxTeamSize)
+	}
+	return nil
+}
+
+// teamID parses the :id parameter, answering 404 for malformed ids.
+func teamID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, apierror.NotFound("team not found"))
+		return 0, false
+	}
+	return id, true
+}
+
+// listTeamsHandler serves GET /teams: the caller's teams, or with admin keys
+// any ?owner='s. include_deleted=true, admin only, adds soft-deleted teams.
+func (s *Server) listTeamsHandler(c *gin.Context) {
+	p := principal(c)
+	owner := p.Owner
+	if o := c.Query("owner"); o != "" && o != owner {
+		if !p.Admin {
+			writeError(c, apierror.Forbidden("only admins can list other owners' teams"))
+			return
+		}
+		owner = o
+	}
+	includeDeleted := c.Query("include_deleted") == "true"
+	if includeDeleted && !p.Admin {
+		writeError(c, apierror.Forbidden("only admins can list deleted teams"))
+		return
+	}
+	teams, err := s.store.ListTeams(c.Request.Context(), owner, includeDeleted)
+	if err != nil {
+		writeStoreError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+// createTeamHandler serves POST /teams.
+func (s *Server) createTeamHandler(c *gin.Context) {
+	var req teamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, apierror.BadRequest("body must be a JSON object with name and members"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(c, apierror.BadRequest(err.Error()))
+		return
+	}
+	t, err := s.store.CreateTeam(c.Request.Context(), storage.Team{Owner: principal(c).Owner, Name: req.Name, Members: req.Members})
+	if err != nil {
+		writeStoreError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// ownedTeam loads a live team the caller may act on. Other owners' teams
+// answer 404 so their ids are not disclosed.
+func (s *Server) ownedTeam(c *gin.Context) (storage.Team, bool) {
+	id, ok := teamID(c)
+	if !ok {
+		return storage.Team{}, false
+	}
+	t, err := s.store.GetTeam(c.Request.Context(), id)
+	if err == nil && t.Owner != principal(c).Owner && !principal(c).Admin {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		writeStoreError(c, err, "team not found")
+		return storage.Team{}, false
+	}
+	return t, true
+}
+
+// getTeamHandler serves GET /teams/:id.
+func (s *Server) getTeamHandler(c *gin.Context) {
+	if t, ok := s.ownedTeam(c); ok {
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// deleteTeamHandler serves DELETE /teams/:id. The team is soft-deleted: it
+// can be restored until the purge job removes it.
+func (s *Server) deleteTeamHandler(c *gin.Context) {
+	t, ok := s.ownedTeam(c)
+	if !ok {
+		return
+	}
+	if err := s.store.DeleteTeam(c.Request.Context(), t.ID); err != nil {
+		writeStoreError(c, err, "team not found")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// restoreTeamHandler serves POST /teams/:id/restore for a soft-deleted team.
+func (s *Server) restoreTeamHandler(c *gin.Context) {
+	id, ok := teamID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	p := principal(c)
+	if !p.Admin {
+		// deleted teams are invisible to GetTeam; look among the caller's own
+		teams, err := s.store.ListTeams(ctx, p.Owner, true)
+		if err != nil {
+			writeStoreError(c, err, "")
+			return
+		}
+		owned := false
+		for _, t := range teams {
+			owned = owned || t.ID == id
+		}
+		if !owned {
+			writeError(c, apierror.NotFound("deleted team not found"))
+			return
+		}
+	}
+	t, err := s.store.RestoreTeam(ctx, id)
+	if err != nil {
+		writeStoreError(c, err, "deleted team not found")
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// apiKeyRequest is the body of POST /admin/api-keys.
+type apiKeyRequest struct {
+	Owner string `json:"owner"`
+	Admin bool   `json:"admin"`
+}
+
+// adminCreateAPIKeyHandler issues a new API key for an owner. Only admin
+// keys may call it.
+func (s *Server) adminCreateAPIKeyHandler(c *gin.Context) {
+	var req apiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Owner == "" {
+		writeError(c, apierror.BadRequest("body must be a JSON object with an owner"))
+		return
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		writeError(c, apierror.Internal("could not generate a key"))
+		return
+	}
+	k := storage.APIKey{Key: hex.EncodeToString(b), Owner: req.Owner, Admin: req.Admin}
+	if err := s.store.CreateAPIKey(c.Request.Context(), k); err != nil {
+		writeStoreError(c, err, "")
+		return
+	}
+	k, err := s.store.GetAPIKey(c.Request.Context(), k.Key)
+	if err != nil {
+		writeStoreError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusCreated, k)
+}
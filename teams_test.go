@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"ci_education/storage"
+)
+
+// newTeamsTestServer returns a router over an in-memory store with a user key
+// for "ash", one for "gary" and an admin key.
+func newTeamsTestServer(t *testing.T) (*Server, *gin.Engine) {
+	t.Helper()
+	s := newTestServer("")
+	s.store = storage.NewMemory()
+	ctx := context.Background()
+	for _, k := range []storage.APIKey{{Key: "ash-key", Owner: "ash"}, {Key: "gary-key", Owner: "gary"}, {Key: testAdminKey, Owner: "oak", Admin: true}} {
+		if err := s.store.CreateAPIKey(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, setupRouter(s)
+}
+
+// testAdminKey is the admin key of newTeamsTestServer and withAdminKey.
+const testAdminKey = "admin-key"
+
+// withAdminKey gives s an in-memory store, unless it has one, holding
+// testAdminKey, and returns s.
+func withAdminKey(t *testing.T, s *Server) *Server {
+	t.Helper()
+	if s.store == nil {
+		s.store = storage.NewMemory()
+	}
+	if err := seedAdminKey(context.Background(), s.store, testAdminKey); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// asAdmin authenticates req with testAdminKey.
+func asAdmin(req *http.Request) *http.Request {
+	req.Header.Set("X-API-Key", testAdminKey)
+	return req
+}
+
+func doTeams(r *gin.Engine, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTeamsSoftDeleteAndRestore(t *testing.T) {
+	_, r := newTeamsTestServer(t)
+
+	if w := doTeams(r, http.MethodGet, "/teams", "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodPost, "/teams", "ash-key", `{"name":"kanto","members":[]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty team, got %d", w.Code)
+	}
+	w := doTeams(r, http.MethodPost, "/teams", "ash-key", `{"name":"kanto","members":["Pikachu","charizard"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var team storage.Team
+	json.Unmarshal(w.Body.Bytes(), &team)
+	path := "/teams/" + strconv.FormatInt(team.ID, 10)
+
+	if w := doTeams(r, http.MethodGet, path, "gary-key", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected other owners to get 404, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodDelete, path, "ash-key", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodGet, path, "ash-key", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected a deleted team to be hidden, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodGet, "/teams?include_deleted=true", "ash-key", ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected include_deleted to be admin only, got %d", w.Code)
+	}
+	w = doTeams(r, http.MethodGet, "/teams?owner=ash&include_deleted=true", "admin-key", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"deleted_at"`) {
+		t.Fatalf("expected the admin to see the deleted team, got %d: %s", w.Code, w.Body)
+	}
+
+	if w := doTeams(r, http.MethodPost, path+"/restore", "gary-key", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected other owners not to restore the team, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodPost, path+"/restore", "ash-key", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the owner to restore the team, got %d: %s", w.Code, w.Body)
+	}
+	w = doTeams(r, http.MethodGet, "/teams", "ash-key", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"members":["pikachu","charizard"]`) {
+		t.Fatalf("expected the restored team to be listed, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestStorePurger(t *testing.T) {
+	s, _ := newTeamsTestServer(t)
+	ctx := context.Background()
+	team, _ := s.store.CreateTeam(ctx, storage.Team{Owner: "ash", Name: "old", Members: []string{"mew"}})
+	s.store.DeleteTeam(ctx, team.ID)
+
+	s.store.RecordUsage(ctx, "ash-key", time.Now().AddDate(0, -3, 0), 1)
+	s.store.RecordUsage(ctx, "ash-key", time.Now(), 1)
+
+	p := newStorePurger(s.store, time.Hour, 0, time.Hour, s.metrics)
+	if n := p.purge(ctx); n != 0 {
+		t.Fatalf("expected a freshly deleted team to be kept, purged %d", n)
+	}
+	p.retention = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if n := p.purge(ctx); n != 1 {
+		t.Fatalf("expected one purged team, got %d", n)
+	}
+	if got := testutil.ToFloat64(s.metrics.storePurgedTotal.WithLabelValues("deleted")); got != 1 {
+		t.Fatalf("expected store_purged_total 1, got %v", got)
+	}
+
+	p.usageMonths = 2
+	if n := p.purge(ctx); n != 1 {
+		t.Fatalf("expected one purged usage day, got %d", n)
+	}
+	if got := testutil.ToFloat64(s.metrics.storePurgedTotal.WithLabelValues("usage")); got != 1 {
+		t.Fatalf("expected store_purged_total{kind=\"usage\"} 1, got %v", got)
+	}
+}
+
+func TestAdminCreateAPIKey(t *testing.T) {
+	_, r := newTeamsTestServer(t)
+	if w := doTeams(r, http.MethodPost, "/admin/api-keys", "", `{"owner":"brock","admin":true}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", w.Code)
+	}
+	if w := doTeams(r, http.MethodPost, "/admin/api-keys", "ash-key", `{"owner":"brock","admin":true}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-admin key, got %d", w.Code)
+	}
+	w := doTeams(r, http.MethodPost, "/admin/api-keys", "admin-key", `{"owner":"brock"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var k storage.APIKey
+	json.Unmarshal(w.Body.Bytes(), &k)
+	if k.Key == "" || k.Owner != "brock" || k.Admin {
+		t.Fatalf("unexpected key %+v", k)
+	}
+	if w := doTeams(r, http.MethodGet, "/teams", k.Key, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the new key to authenticate, got %d", w.Code)
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	s, r := newTeamsTestServer(t)
+	s.store.CreateTeam(context.Background(), storage.Team{Owner: "ash", Name: "kanto", Members: []string{"pikachu"}})
+
+	var logs strings.Builder
+	orig := log.Writer()
+	log.SetOutput(&logs)
+	defer log.SetOutput(orig)
+
+	as := func(key, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/teams", nil)
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("X-Impersonate", target)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := as("gary-key", "ash-key"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected non-admins to get 403, got %d", w.Code)
+	}
+	if w := as("admin-key", "nope"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown target to get 400, got %d", w.Code)
+	}
+	w := as("admin-key", "ash-key")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"kanto"`) {
+		t.Fatalf("expected ash's teams, got %d: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(logs.String(), "audit: impersonation") || !strings.Contains(logs.String(), "admin=oak as=ash") {
+		t.Fatalf("expected an audit record, got %q", logs.String())
+	}
+	if !strings.Contains(logs.String(), "impersonator=oak") {
+		t.Fatalf("expected the access log to flag the request, got %q", logs.String())
+	}
+}
+
+func TestSeedAdminKey(t *testing.T) {
+	store := storage.NewMemory()
+	ctx := context.Background()
+	for range 2 {
+		if err := seedAdminKey(ctx, store, "root-key"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if k, err := store.GetAPIKey(ctx, "root-key"); err != nil || !k.Admin {
+		t.Fatalf("expected an admin key, got %+v %v", k, err)
+	}
+}
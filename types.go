@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// damageRelations lists, by type name, how a type deals and takes damage.
+type damageRelations struct {
+	DoubleDamageFrom []string `json:"double_damage_from"`
+	DoubleDamageTo   []string `json:"double_damage_to"`
+	HalfDamageFrom   []string `json:"half_damage_from"`
+	HalfDamageTo     []string `json:"half_damage_to"`
+	NoDamageFrom     []string `json:"no_damage_from"`
+	NoDamageTo       []string `json:"no_damage_to"`
+}
+
+// typeResponse is the response of GET /type/:name.
+type typeResponse struct {
+	Name            string          `json:"name"`
+	DamageRelations damageRelations `json:"damage_relations"`
+}
+
+// resourceNames returns the names of rs, never nil.
+func resourceNames(rs []namedResource) []string {
+	out := make([]string, len(rs))
+	for i, r := range rs {
+		out[i] = r.Name
+	}
+	return out
+}
+
+// typeHandler serves GET /type/:name from the type chart cache.
+func (s *Server) typeHandler(c *gin.Context) {
+	t, status, err := s.fetchType(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "type not found"))
+		return
+	}
+	dr := t.DamageRelations
+	c.JSON(http.StatusOK, typeResponse{Name: t.Name, DamageRelations: damageRelations{
+		DoubleDamageFrom: resourceNames(dr.DoubleDamageFrom),
+		DoubleDamageTo:   resourceNames(dr.DoubleDamageTo),
+		HalfDamageFrom:   resourceNames(dr.HalfDamageFrom),
+		HalfDamageTo:     resourceNames(dr.HalfDamageTo),
+		NoDamageFrom:     resourceNames(dr.NoDamageFrom),
+		NoDamageTo:       resourceNames(dr.NoDamageTo),
+	}})
+}
+
+// typePokemon is one pokemon of a type; Slot 1 is its primary type, 2 its
+// secondary.
+type typePokemon struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Slot int    `json:"slot"`
+}
+
+// typePokemonHandler serves GET /type/:name/pokemon?limit=&offset=: one page
+// of the pokemon having the type, in upstream order. Pages are cut locally
+// from the cached type, so paging never refetches it.
+func (s *Server) typePokemonHandler(c *gin.Context) {
+	offset, limit, ok := listPage(c)
+	if !ok {
+		return
+	}
+	t, status, err := s.fetchType(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeError(c, apierror.FromUpstream(status, err, "type not found"))
+		return
+	}
+	page := t.Pokemon[min(offset, len(t.Pokemon)):min(offset+limit, len(t.Pokemon))]
+	results := make([]typePokemon, len(page))
+	for i, p := range page {
+		results[i] = typePokemon{Name: p.Pokemon.Name, URL: p.Pokemon.URL, Slot: p.Slot}
+	}
+	c.JSON(http.StatusOK, gin.H{"type": t.Name, "count": len(t.Pokemon), "offset": offset, "limit": limit, "results": results})
+}
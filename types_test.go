@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTypeEndpoint(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/type/fire": `{"name":"fire","damage_relations":{"double_damage_from":[{"name":"water"},{"name":"ground"}],
+			"double_damage_to":[{"name":"grass"}],"half_damage_from":[],"half_damage_to":[{"name":"water"}],"no_damage_from":[],"no_damage_to":[]}}`,
+	})
+	s := newTestServer(ts.URL)
+	s.types = newTTLCache[typeDetail](time.Minute)
+	r := setupRouter(s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/type/fire", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var got typeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := typeResponse{Name: "fire", DamageRelations: damageRelations{
+		DoubleDamageFrom: []string{"water", "ground"}, DoubleDamageTo: []string{"grass"},
+		HalfDamageFrom: []string{}, HalfDamageTo: []string{"water"}, NoDamageFrom: []string{}, NoDamageTo: []string{},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if _, ok := s.types.get("fire"); !ok {
+		t.Fatal("expected the type to be cached")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/type/shadowy", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestTypePokemonEndpoint(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/type/electric" {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"electric","pokemon":[{"slot":1,"pokemon":{"name":"pikachu","url":"u/25"}},
+			{"slot":1,"pokemon":{"name":"raichu","url":"u/26"}},{"slot":2,"pokemon":{"name":"chinchou","url":"u/170"}}]}`))
+	}))
+	defer ts.Close()
+	s := newTestServer(ts.URL)
+	s.types = newTTLCache[typeDetail](time.Minute)
+	r := setupRouter(s)
+
+	type page struct {
+		Type    string        `json:"type"`
+		Count   int           `json:"count"`
+		Offset  int           `json:"offset"`
+		Limit   int           `json:"limit"`
+		Results []typePokemon `json:"results"`
+	}
+	for target, want := range map[string]page{
+		"/type/electric/pokemon?limit=2": {Type: "electric", Count: 3, Limit: 2, Results: []typePokemon{
+			{Name: "pikachu", URL: "u/25", Slot: 1}, {Name: "raichu", URL: "u/26", Slot: 1}}},
+		"/type/electric/pokemon?limit=2&offset=2": {Type: "electric", Count: 3, Offset: 2, Limit: 2, Results: []typePokemon{
+			{Name: "chinchou", URL: "u/170", Slot: 2}}},
+		"/type/electric/pokemon?offset=10": {Type: "electric", Count: 3, Offset: 10, Limit: defaultListLimit, Results: []typePokemon{}},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", target, w.Code, w.Body)
+		}
+		var got page
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expected %+v, got %+v", target, want, got)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected pages to share one upstream fetch, got %d", n)
+	}
+
+	for target, want := range map[string]int{
+		"/type/electric/pokemon?limit=0": http.StatusBadRequest,
+		"/type/shadow/pokemon":           http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", target, want, w.Code)
+		}
+	}
+}
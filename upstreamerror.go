@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"ci_education/apierror"
+)
+
+const (
+	// maxUpstreamErrorBodyBytes caps how much of an error body is read.
+	maxUpstreamErrorBodyBytes = 4096
+	// maxUpstreamMessageRunes caps the message passed through to clients.
+	maxUpstreamMessageRunes = 200
+)
+
+// upstreamMessageKeys are the JSON fields checked, in order, for a message.
+var upstreamMessageKeys = []string{"detail", "message", "error", "error_description"}
+
+// upstreamErrorDetail extracts a sanitized message from a non-200 upstream
+// JSON body. It returns nil when passthrough is disabled or the body carries
+// no usable message.
+func (s *Server) upstreamErrorDetail(status int, body []byte) *apierror.UpstreamDetail {
+	if !s.upstreamErrorDetails {
+		return nil
+	}
+	body = body[:min(len(body), maxUpstreamErrorBodyBytes)]
+	var fields map[string]any
+	if json.Unmarshal(body, &fields) != nil {
+		return nil
+	}
+	for _, key := range upstreamMessageKeys {
+		if v, ok := fields[key].(string); ok {
+			if msg := sanitizeUpstreamMessage(v); msg != "" {
+				return &apierror.UpstreamDetail{Status: status, Message: msg}
+			}
+		}
+	}
+	return nil
+}
+
+// sanitizeUpstreamMessage drops control characters, collapses whitespace and
+// truncates the message.
+func sanitizeUpstreamMessage(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, v)
+	v = strings.Join(strings.Fields(v), " ")
+	if r := []rune(v); len(r) > maxUpstreamMessageRunes {
+		v = string(r[:maxUpstreamMessageRunes]) + "…"
+	}
+	return v
+}
+
+// withUpstreamDetail wraps d into err so apierror.FromUpstream can find it.
+func withUpstreamDetail(err error, d *apierror.UpstreamDetail) error {
+	if d == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", err, d)
+}
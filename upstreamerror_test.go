@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestUpstreamErrorPassthrough(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"detail":"invalid\n\tname"}`)
+	}))
+	defer ts.Close()
+
+	for _, enabled := range []bool{false, true} {
+		s := &Server{httpClient: ts.Client(), cache: newPokemonCache(0), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL, upstreamErrorDetails: enabled}
+		w := httptest.NewRecorder()
+		setupRouter(s).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/x", nil))
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("expected 502, got %d", w.Code)
+		}
+		var body struct {
+			Error struct {
+				Code            string `json:"code"`
+				UpstreamStatus  int    `json:"upstream_status"`
+				UpstreamMessage string `json:"upstream_message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if !enabled {
+			if body.Error.UpstreamStatus != 0 || body.Error.UpstreamMessage != "" {
+				t.Fatalf("expected no upstream details when disabled: %s", w.Body)
+			}
+			continue
+		}
+		if body.Error.Code != "upstream_error" || body.Error.UpstreamStatus != 400 || body.Error.UpstreamMessage != "invalid name" {
+			t.Fatalf("unexpected error body: %s", w.Body)
+		}
+	}
+}
+
+func TestUpstreamErrorPassthroughWithJournal(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"detail":"invalid name"}`)
+	}))
+	defer ts.Close()
+
+	s := newTestServer(ts.URL)
+	s.upstreamErrorDetails = true
+	s.journal = newUpstreamJournal(4, 4096)
+	w := httptest.NewRecorder()
+	setupRouter(s).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/x", nil))
+	if !strings.Contains(w.Body.String(), `"upstream_message":"invalid name"`) {
+		t.Fatalf("expected the upstream message with the journal enabled: %s", w.Body)
+	}
+	if e := s.journal.snapshot()[0]; e.Body != `{"detail":"invalid name"}` {
+		t.Fatalf("expected the journal to keep the error body, got %+v", e)
+	}
+}
+
+func TestSanitizeUpstreamMessage(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	if got := sanitizeUpstreamMessage(long); len([]rune(got)) != maxUpstreamMessageRunes+1 {
+		t.Fatalf("expected truncated message, got %d runes", len([]rune(got)))
+	}
+	if got := sanitizeUpstreamMessage("  a\x00b\r\nc "); got != "a b c" {
+		t.Fatalf("unexpected sanitized message %q", got)
+	}
+}
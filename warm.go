@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ci_education/apierror"
+)
+
+// warmResources are the resource families a warm job can prefetch.
+var warmResources = []string{"pokemon", "species", "types"}
+
+const (
+	// maxWarmFailures caps the failures kept per resource family.
+	maxWarmFailures = 50
+	// maxWarmJobs is how many warm jobs are kept for status queries.
+	maxWarmJobs = 20
+	// warmWorkers bounds concurrent upstream fetches of an admin warm job.
+	warmWorkers = 8
+)
+
+// warmRequest selects what to prefetch: whole resource families, limited to
+// one generation when Generation is positive.
+type warmRequest struct {
+	Resources  []string `json:"resources"`
+	Generation int      `json:"generation"`
+}
+
+func (r warmRequest) validate() error {
+	if len(r.Resources) == 0 {
+		return errors.New("resources must not be empty")
+	}
+	for _, res := range r.Resources {
+		if !slices.Contains(warmResources, res) {
+			return fmt.Errorf("unknown resource %q", res)
+		}
+	}
+	if r.Generation < 0 {
+		return errors.New("generation must not be negative")
+	}
+	return nil
+}
+
+type warmFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// warmProgress tracks one resource family of a warm job.
+type warmProgress struct {
+	Total    int           `json:"total"`
+	Done     int           `json:"done"`
+	Failed   int           `json:"failed"`
+	Failures []warmFailure `json:"failures,omitempty"`
+}
+
+// warmJob is one run of the warm command, shared between the worker and
+// status readers.
+type warmJob struct {
+	mu         sync.Mutex
+	id         string
+	request    warmRequest
+	startedAt  time.Time
+	finishedAt time.Time
+	err        string
+	progress   map[string]*warmProgress
+}
+
+// warmJobStatus is the JSON view of a warm job.
+type warmJobStatus struct {
+	ID         string                  `json:"id"`
+	Request    warmRequest             `json:"request"`
+	State      string                  `json:"state"`
+	StartedAt  time.Time               `json:"started_at"`
+	FinishedAt *time.Time              `json:"finished_at,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	Progress   map[string]warmProgress `json:"progress"`
+}
+
+func newWarmJob(req warmRequest) *warmJob {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	j := &warmJob{id: hex.EncodeToString(b), request: req, startedAt: time.Now(), progress: map[string]*warmProgress{}}
+	for _, res := range req.Resources {
+		j.progress[res] = &warmProgress{}
+	}
+	return j
+}
+
+func (j *warmJob) setTotal(resource string, n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress[resource].Total = n
+}
+
+func (j *warmJob) record(resource, name string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := j.progress[resource]
+	p.Done++
+	if err != nil {
+		p.Failed++
+		if len(p.Failures) < maxWarmFailures {
+			p.Failures = append(p.Failures, warmFailure{Name: name, Error: err.Error()})
+		}
+	}
+}
+
+func (j *warmJob) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finishedAt = time.Now()
+	if err != nil {
+		j.err = err.Error()
+	}
+}
+
+func (j *warmJob) status() warmJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := warmJobStatus{ID: j.id, Request: j.request, State: "running", StartedAt: j.startedAt, Error: j.err,
+		Progress: make(map[string]warmProgress, len(j.progress))}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		st.FinishedAt = &finished
+		st.State = "done"
+		if j.err != "" {
+			st.State = "failed"
+		}
+	}
+	for res, p := range j.progress {
+		cp := *p
+		cp.Failures = slices.Clone(p.Failures)
+		st.Progress[res] = cp
+	}
+	return st
+}
+
+// warmNames lists the names to prefetch for each requested resource. A
+// generation lists species, so its pokemon are species names that warmOne
+// resolves to their default varieties.
+func (s *Server) warmNames(ctx context.Context, req warmRequest) (map[string][]string, error) {
+	out := map[string][]string{}
+	if req.Generation > 0 {
+		gen, _, err := s.fetchGeneration(ctx, strconv.Itoa(req.Generation))
+		if err != nil {
+			return nil, fmt.Errorf("generation %d: %w", req.Generation, err)
+		}
+		for _, res := range req.Resources {
+			if res == "types" {
+				out[res] = resourceNames(gen.Types)
+			} else {
+				out[res] = resourceNames(gen.PokemonSpecies)
+			}
+		}
+		return out, nil
+	}
+	lists := map[string]string{"pokemon": "/pokemon", "species": "/pokemon-species", "types": "/type"}
+	for _, res := range req.Resources {
+		var list resourceList
+		if _, err := s.fetchUpstream(ctx, lists[res]+"?limit="+strconv.Itoa(fullListLimit)+"&offset=0", &list); err != nil {
+			return nil, fmt.Errorf("%s list: %w", res, err)
+		}
+		out[res] = resourceNames(list.Results)
+	}
+	return out, nil
+}
+
+// warmOne fetches one resource through its cache. With bySpecies, a pokemon
+// name is a species, warmed with the pokemon of its default variety.
+func (s *Server) warmOne(ctx context.Context, resource, name string, bySpecies bool) error {
+	var err error
+	switch resource {
+	case "pokemon":
+		if bySpecies {
+			sp, _, err := s.fetchSpecies(ctx, name)
+			if err != nil {
+				return err
+			}
+			name = sp.defaultVariety()
+		}
+		if _, _, err = s.getPokemon(ctx, name); err == nil {
+			_, _, err = s.fetchPokemonDetail(ctx, name)
+		}
+	case "species":
+		_, _, err = s.fetchSpecies(ctx, name)
+	case "types":
+		_, _, err = s.fetchType(ctx, name)
+	}
+	return err
+}
+
+// runWarm prefetches every requested resource family concurrently, with at
+// most workers upstream fetches in flight overall, recording progress in job.
+func (s *Server) runWarm(ctx context.Context, job *warmJob, workers int) {
+	all, err := s.warmNames(ctx, job.request)
+	if err != nil {
+		job.finish(err)
+		return
+	}
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for _, res := range job.request.Resources {
+		job.setTotal(res, len(all[res]))
+	}
+	for _, res := range job.request.Resources {
+		for _, name := range all[res] {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				job.record(res, name, s.warmOne(ctx, res, name, job.request.Generation > 0))
+			}()
+		}
+	}
+	wg.Wait()
+	job.finish(nil)
+}
+
+// adminStartWarmHandler starts a warm job in the background and answers 202
+// with its initial status; poll GET /admin/warm/:id for progress.
+func (s *Server) adminStartWarmHandler(c *gin.Context) {
+	var req warmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, apierror.BadRequest("body must be a JSON object with resources and generation"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(c, apierror.BadRequest(err.Error()))
+		return
+	}
+	job := newWarmJob(req)
+	s.warmJobs.add(job.id, job, maxWarmJobs)
+	go s.runWarm(context.Background(), job, warmWorkers)
+	c.JSON(http.StatusAccepted, job.status())
+}
+
+// adminWarmStatusHandler reports a warm job's progress and failures.
+func (s *Server) adminWarmStatusHandler(c *gin.Context) {
+	job, ok := s.warmJobs.get(c.Param("id"))
+	if !ok {
+		writeError(c, apierror.NotFound("warm job not found"))
+		return
+	}
+	c.JSON(http.StatusOK, job.status())
+}
+
+// warmCommand implements "warm --resources pokemon,species,types
+// --generation 1": it prefetches in-process, printing progress, and saves
+// the pokemon cache snapshot when CACHE_SNAPSHOT_PATH is set so a server
+// starting afterwards begins warm. It returns the process exit code.
+func warmCommand(args []string) int {
+	fs := flag.NewFlagSet("warm", flag.ContinueOnError)
+	resources := fs.String("resources", "pokemon", "comma-separated resources: pokemon, species, types")
+	generation := fs.Int("generation", 0, "limit to one generation (0 = all)")
+	workers := fs.Int("workers", warmWorkers, "concurrent upstream fetches")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	req := warmRequest{Resources: splitList(*resources), Generation: *generation}
+	if err := req.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "warm:", err)
+		return 2
+	}
+
+	s := newServerFromEnv()
+	snapshotter := newSnapshotterFromEnv(s)
+	job := newWarmJob(req)
+	done := make(chan struct{})
+	go func() {
+		s.runWarm(context.Background(), job, *workers)
+		close(done)
+	}()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-ticker.C:
+		case <-done:
+			running = false
+		}
+		st := job.status()
+		for _, res := range req.Resources {
+			p := st.Progress[res]
+			fmt.Fprintf(os.Stderr, "warm: %s %d/%d (%d failed)\n", res, p.Done, p.Total, p.Failed)
+		}
+	}
+
+	st := job.status()
+	if st.Error != "" {
+		fmt.Fprintln(os.Stderr, "warm:", st.Error)
+		return 1
+	}
+	failed := 0
+	for _, res := range req.Resources {
+		for _, f := range st.Progress[res].Failures {
+			fmt.Fprintf(os.Stderr, "warm: failed %s %s: %s\n", res, f.Name, f.Error)
+		}
+		failed += st.Progress[res].Failed
+	}
+	if snapshotter != nil {
+		n, err := snapshotter.save()
+		if err != nil {
+			log.Printf("warm: saving snapshot: %v", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "warm: saved %d pokemon to %s\n", n, snapshotter.path)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func warmUpstream() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/generation/1":
+			fmt.Fprint(w, `{"pokemon_species":[{"name":"bulbasaur"},{"name":"deoxys"},{"name":"missingno"}],"types":[{"name":"grass"}]}`)
+		case r.URL.Path == "/pokemon-species/deoxys":
+			fmt.Fprint(w, `{"name":"deoxys","varieties":[{"is_default":false,"pokemon":{"name":"deoxys-attack"}},
+				{"is_default":true,"pokemon":{"name":"deoxys-normal"}}]}`)
+		case strings.HasSuffix(r.URL.Path, "/missingno"), r.URL.Path == "/pokemon/deoxys":
+			http.NotFound(w, r)
+		default:
+			parts := strings.Split(r.URL.Path, "/")
+			fmt.Fprintf(w, `{"name":%q}`, parts[len(parts)-1])
+		}
+	}))
+}
+
+func newWarmTestServer(ts *httptest.Server) *Server {
+	return &Server{
+		httpClient: ts.Client(),
+		baseURL:    ts.URL,
+		metrics:    newMetrics(prometheus.NewRegistry()),
+		cache:      newPokemonCache(time.Minute),
+		details:    newTTLCache[pokemonDetail](time.Minute),
+		species:    newTTLCache[speciesDetail](time.Minute),
+		types:      newTTLCache[typeDetail](time.Minute),
+	}
+}
+
+func TestRunWarmGeneration(t *testing.T) {
+	ts := warmUpstream()
+	defer ts.Close()
+	s := newWarmTestServer(ts)
+
+	job := newWarmJob(warmRequest{Resources: []string{"pokemon", "species", "types"}, Generation: 1})
+	s.runWarm(context.Background(), job, 2)
+
+	st := job.status()
+	if st.State != "done" {
+		t.Fatalf("expected done, got %+v", st)
+	}
+	if p := st.Progress["pokemon"]; p.Total != 3 || p.Done != 3 || p.Failed != 1 || p.Failures[0].Name != "missingno" {
+		t.Fatalf("unexpected pokemon progress: %+v", p)
+	}
+	if p := st.Progress["types"]; p.Total != 1 || p.Failed != 0 {
+		t.Fatalf("unexpected types progress: %+v", p)
+	}
+	if _, ok := s.cache.get("bulbasaur"); !ok {
+		t.Fatal("expected bulbasaur to be cached")
+	}
+	if _, ok := s.cache.get("deoxys-normal"); !ok {
+		t.Fatal("expected deoxys to be warmed by its default variety")
+	}
+	if _, ok := s.species.get("bulbasaur"); !ok {
+		t.Fatal("expected bulbasaur species to be cached")
+	}
+	if _, ok := s.types.get("grass"); !ok {
+		t.Fatal("expected grass to be cached")
+	}
+}
+
+func TestWarmRequestValidate(t *testing.T) {
+	for _, req := range []warmRequest{
+		{},
+		{Resources: []string{"moves"}},
+		{Resources: []string{"pokemon"}, Generation: -1},
+	} {
+		if req.validate() == nil {
+			t.Errorf("expected %+v to be rejected", req)
+		}
+	}
+}
+
+func TestAdminWarmHandlers(t *testing.T) {
+	ts := warmUpstream()
+	defer ts.Close()
+	s := newWarmTestServer(ts)
+	r := gin.New()
+	r.POST("/admin/warm", s.adminStartWarmHandler)
+	r.GET("/admin/warm/:id", s.adminWarmStatusHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/warm", strings.NewReader(`{"resources":["bogus"]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/warm", strings.NewReader(`{"resources":["types"],"generation":1}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	id := s.warmJobs.order[0]
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/warm/"+id, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if strings.Contains(w.Body.String(), `"state":"done"`) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("warm job did not finish: %s", w.Body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/warm/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
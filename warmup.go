@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// readWarmupFile reads pokemon names, one per line; blank lines and lines
+// starting with # are ignored.
+func readWarmupFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, sc.Err()
+}
+
+// warmCache prefetches names into the pokemon cache with at most workers
+// concurrent fetches. Failures are logged and skipped; it returns the number
+// of names now cached.
+func (s *Server) warmCache(ctx context.Context, names []string, workers int) int {
+	sem := make(chan struct{}, max(workers, 1))
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		warmed int
+	)
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if _, _, err := s.getPokemon(ctx, strings.ToLower(name)); err != nil {
+				log.Printf("cache warm-up: %s: %v", name, err)
+				return
+			}
+			mu.Lock()
+			warmed++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return warmed
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWarmCache(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/pokemon/")
+		if name == "missingno" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"name":%q}`, name)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "warmup.txt")
+	if err := os.WriteFile(path, []byte("# starters\nbulbasaur\n\nCharmander\nmissingno\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names, err := readWarmupFile(path)
+	if err != nil || len(names) != 3 {
+		t.Fatalf("expected 3 names, got %v %v", names, err)
+	}
+
+	s := &Server{httpClient: ts.Client(), cache: newPokemonCache(time.Minute), metrics: newMetrics(prometheus.NewRegistry()), baseURL: ts.URL}
+	if n := s.warmCache(context.Background(), names, 2); n != 2 {
+		t.Fatalf("expected 2 warmed pokemon, got %d", n)
+	}
+	if _, ok := s.cache.get("charmander"); !ok {
+		t.Fatal("expected charmander to be cached")
+	}
+}
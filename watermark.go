@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cache states reported in X-Cache.
+const (
+	cacheHit   = "HIT"
+	cacheStale = "STALE"
+	cacheMiss  = "MISS"
+)
+
+// requestTrace collects what serving one request involved, for the debug
+// headers: the cache outcome and the time spent waiting for the upstream.
+// Methods are safe on a nil trace, which records nothing.
+type requestTrace struct {
+	mu       sync.Mutex
+	cache    string
+	upstream time.Duration
+	calls    int
+}
+
+type requestTraceKey struct{}
+
+// withRequestTrace returns ctx carrying t.
+func withRequestTrace(ctx context.Context, t *requestTrace) context.Context {
+	return context.WithValue(ctx, requestTraceKey{}, t)
+}
+
+// traceFrom returns the request's trace, nil when debug headers are off.
+func traceFrom(ctx context.Context) *requestTrace {
+	t, _ := ctx.Value(requestTraceKey{}).(*requestTrace)
+	return t
+}
+
+// cacheResult records one cache lookup. A request consulting several caches
+// reports the worst outcome: MISS over STALE over HIT.
+func (t *requestTrace) cacheResult(state string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cache == "" || t.cache == cacheHit || state == cacheMiss {
+		t.cache = state
+	}
+}
+
+// upstreamCall records one upstream fetch that took d.
+func (t *requestTrace) upstreamCall(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.upstream += d
+	t.calls++
+	t.mu.Unlock()
+}
+
+// defaultInstanceID names this instance in X-Served-By when INSTANCE_ID is
+// not set: the host name, which is the pod name on Kubernetes.
+func defaultInstanceID() string {
+	if h, err := os.Hostname(); err == nil {
+		return h
+	}
+	return "unknown"
+}
+
+// watermarkWriter adds the debug headers just before the response header is
+// sent, once the handler has done its lookups.
+type watermarkWriter struct {
+	gin.ResponseWriter
+	apply func()
+	once  sync.Once
+}
+
+func (w *watermarkWriter) Write(b []byte) (int, error) {
+	w.once.Do(w.apply)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *watermarkWriter) WriteString(s string) (int, error) {
+	w.once.Do(w.apply)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *watermarkWriter) WriteHeaderNow() {
+	w.once.Do(w.apply)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *watermarkWriter) Flush() {
+	w.once.Do(w.apply)
+	w.ResponseWriter.Flush()
+}
+
+// middleware: with DEBUG_HEADERS, mark responses with X-Served-By (the
+// instance ID), X-Cache (HIT, STALE or MISS, when the request consulted a
+// cache) and X-Upstream-Duration (total time spent on upstream calls, when
+// any were made), so a client trace shows which instance and cache state
+// produced a response.
+func watermarkMiddleware(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.debugHeaders {
+			c.Next()
+			return
+		}
+		t := &requestTrace{}
+		c.Request = c.Request.WithContext(withRequestTrace(c.Request.Context(), t))
+		w := &watermarkWriter{ResponseWriter: c.Writer}
+		w.apply = func() {
+			h := w.ResponseWriter.Header()
+			h.Set("X-Served-By", s.instanceID)
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.cache != "" {
+				h.Set("X-Cache", t.cache)
+			}
+			if t.calls > 0 {
+				h.Set("X-Upstream-Duration", t.upstream.Round(100*time.Microsecond).String())
+			}
+		}
+		c.Writer = w
+		c.Next()
+		if !w.Written() {
+			w.once.Do(w.apply)
+		}
+	}
+}
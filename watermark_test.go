@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDebugHeaders(t *testing.T) {
+	ts := fakePokeAPI(t, map[string]string{
+		"/pokemon/pikachu": `{"name":"pikachu","height":4,"weight":60,"base_experience":112}`,
+	})
+	s := newTestServer(ts.URL)
+	s.debugHeaders, s.instanceID = true, "pod-a"
+	s.defaultCachePolicy = cachePolicy{TTL: time.Minute, StaleWhileRevalidate: time.Minute}
+	r := setupRouter(s)
+
+	get := func(path string) http.Header {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Result().Header
+	}
+
+	h := get("/pokemon/pikachu")
+	if h.Get("X-Served-By") != "pod-a" || h.Get("X-Cache") != cacheMiss || h.Get("X-Upstream-Duration") == "" {
+		t.Fatalf("unexpected headers on a miss: %v", h)
+	}
+	h = get("/pokemon/pikachu")
+	if h.Get("X-Cache") != cacheHit || h.Get("X-Upstream-Duration") != "" {
+		t.Fatalf("unexpected headers on a hit: %v", h)
+	}
+
+	// age the entry past its TTL but within stale-while-revalidate
+	v, _ := s.cache.get("pikachu")
+	v.freshUntil = time.Now().Add(-time.Second)
+	s.cache.set("pikachu", v)
+	if h = get("/pokemon/pikachu"); h.Get("X-Cache") != cacheStale {
+		t.Fatalf("expected a stale hit, got %v", h)
+	}
+
+	h = get("/hello")
+	if h.Get("X-Served-By") != "pod-a" || h.Get("X-Cache") != "" {
+		t.Fatalf("expected only X-Served-By without a cache lookup, got %v", h)
+	}
+
+	s.debugHeaders = false
+	if h = get("/pokemon/pikachu"); h.Get("X-Served-By") != "" || h.Get("X-Cache") != "" {
+		t.Fatalf("expected no debug headers when disabled, got %v", h)
+	}
+}
+
+func TestRequestTraceCacheResult(t *testing.T) {
+	for _, tc := range []struct {
+		results []string
+		want    string
+	}{
+		{[]string{cacheHit, cacheHit}, cacheHit},
+		{[]string{cacheHit, cacheStale, cacheHit}, cacheStale},
+		{[]string{cacheStale, cacheMiss, cacheHit}, cacheMiss},
+	} {
+		tr := &requestTrace{}
+		for _, r := range tc.results {
+			tr.cacheResult(r)
+		}
+		if tr.cache != tc.want {
+			t.Errorf("%v: expected %s, got %s", tc.results, tc.want, tr.cache)
+		}
+	}
+	var nilTrace *requestTrace
+	nilTrace.cacheResult(cacheHit)
+	nilTrace.upstreamCall(time.Second)
+}